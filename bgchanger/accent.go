@@ -0,0 +1,172 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const modeAccent = "accent"
+
+// accentColors are the GNOME accent colors, as set in
+// org.gnome.desktop.interface accent-color.
+var accentColors = map[string]string{
+	"blue":   "#3584e4",
+	"teal":   "#2190a4",
+	"green":  "#3a944a",
+	"yellow": "#c88800",
+	"orange": "#ed5b00",
+	"red":    "#e62d42",
+	"pink":   "#d56199",
+	"purple": "#9141ac",
+	"slate":  "#6f8396",
+}
+
+// parseHexColor parses a color like #rgb or #rrggbb.
+func parseHexColor(s string) (color.RGBA, error) {
+	if !hexColorRegexp.MatchString(s) {
+		return color.RGBA{}, fmt.Errorf("invalid color '%s', want #rrggbb", s)
+	}
+	s = s[1:]
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	v, _ := strconv.ParseUint(s, 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// accentColor returns the color to match, which is target_color if set, or
+// else the accent color of the desktop theme.
+func accentColor(cfg *Config) (color.RGBA, error) {
+	if cfg.TargetColor != "" {
+		return parseHexColor(cfg.TargetColor)
+	}
+	out, err := gsettingsGet(interfaceSchema, "accent-color")
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("failed to get accent color: %w", err)
+	}
+	name := strings.Trim(out, "'")
+	hex, ok := accentColors[name]
+	if !ok {
+		return color.RGBA{}, fmt.Errorf("unknown accent color '%s'", name)
+	}
+	return parseHexColor(hex)
+}
+
+// colorDistance returns the squared euclidean distance between two colors.
+func colorDistance(a, b color.RGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// sortByColor sorts the pictures from the one whose average color is the
+// closest to target to the farthest. Pictures whose color is unknown go last.
+func sortByColor(pictures []string, target color.RGBA) {
+	distances := make(map[string]int, len(pictures))
+	for _, p := range pictures {
+		c, err := averageColor(p)
+		if err != nil {
+			log.Printf("Warning: cannot compute color of '%s': %v", p, err)
+			distances[p] = 1 << 30
+			continue
+		}
+		distances[p] = colorDistance(c, target)
+	}
+	sort.SliceStable(pictures, func(i, j int) bool { return distances[pictures[i]] < distances[pictures[j]] })
+}
+
+// minAccentSaturation is the saturation below which a picture is considered
+// gray, and matched to the slate accent.
+const minAccentSaturation = 0.15
+
+// hueSaturation returns the hue, in degrees, and the saturation of a color.
+func hueSaturation(c color.RGBA) (float64, float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	d := hi - lo
+	if d == 0 {
+		return 0, 0
+	}
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, d / hi
+}
+
+// nearestAccent returns the name of the GNOME accent color with the closest
+// hue to c, or slate if c is almost gray.
+func nearestAccent(c color.RGBA) string {
+	hue, sat := hueSaturation(c)
+	if sat < minAccentSaturation {
+		return "slate"
+	}
+	best, bestDist := "", math.Inf(1)
+	for name, hex := range accentColors {
+		if name == "slate" {
+			continue
+		}
+		ac, _ := parseHexColor(hex)
+		h, _ := hueSaturation(ac)
+		d := math.Abs(h - hue)
+		if d > 180 {
+			d = 360 - d
+		}
+		// break ties by name, map iteration order is random
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// accentSupport records whether the desktop has the accent-color setting,
+// which needs GNOME 47 or later. It is checked once.
+var accentSupport struct {
+	once      sync.Once
+	available bool
+}
+
+func accentColorAvailable() bool {
+	accentSupport.once.Do(func() {
+		_, err := commandOutput("gsettings", "range", interfaceSchema, "accent-color")
+		accentSupport.available = err == nil
+		if err != nil {
+			log.Printf("Warning: the desktop has no accent-color setting, not syncing it: %v", err)
+		}
+	})
+	return accentSupport.available
+}
+
+// syncAccentColor sets the accent color of the desktop to the one closest to
+// the average color of the picture.
+func syncAccentColor(filename string) {
+	if !accentColorAvailable() {
+		return
+	}
+	c, err := averageColor(filename)
+	if err != nil {
+		log.Printf("Warning: cannot compute color of '%s': %v", filename, err)
+		return
+	}
+	accent := nearestAccent(c)
+	if err := gsettingsSet(interfaceSchema, "accent-color", accent); err != nil {
+		log.Printf("Error: failed to set accent color: %v", err)
+		return
+	}
+	debugf("accent color set to %s", accent)
+}
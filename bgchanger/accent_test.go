@@ -0,0 +1,55 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccentModePicksNearestColor(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	red := writeTestPicture(t, filepath.Join(dir, "red.png"), 16, 9, color.RGBA{220, 30, 40, 255})
+	green := writeTestPicture(t, filepath.Join(dir, "green.png"), 16, 9, color.RGBA{40, 160, 60, 255})
+	blue := writeTestPicture(t, filepath.Join(dir, "blue.png"), 16, 9, color.RGBA{40, 120, 230, 255})
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "accent", "target_color": "#30a050"}`, dir)
+	for i := 0; i < 5; i++ {
+		if p, err := PickPicture(cfg); err != nil || p != green {
+			t.Fatalf("picked %s, %v for a green target, want %s", p, err, green)
+		}
+	}
+
+	cfg = testConfig(t, `{"pictures_dir": %q, "mode": "accent"}`, dir)
+	setter.preset(interfaceSchema, "accent-color", "'red'")
+	if p, err := PickPicture(cfg); err != nil || p != red {
+		t.Errorf("picked %s, %v with the red accent, want %s", p, err, red)
+	}
+
+	// without an accent color, any picture can be picked
+	setter.preset(interfaceSchema, "accent-color", "'no-such-color'")
+	picked := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked[p] = true
+	}
+	if !picked[blue] || !picked[green] {
+		t.Errorf("picked %v without an accent color, want random pictures", picked)
+	}
+}
+
+func TestNearestAccent(t *testing.T) {
+	for c, want := range map[color.RGBA]string{
+		{230, 45, 66, 255}:   "red",
+		{58, 148, 74, 255}:   "green",
+		{53, 132, 228, 255}:  "blue",
+		{120, 120, 125, 255}: "slate",
+	} {
+		if got := nearestAccent(c); got != want {
+			t.Errorf("nearestAccent(%v) = %s, want %s", c, got, want)
+		}
+	}
+}
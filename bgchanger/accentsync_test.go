@@ -0,0 +1,35 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncAccentColor(t *testing.T) {
+	setter := testEnv(t)
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		return []byte("enum\n'blue'\n'teal'\n'red'\n"), nil
+	}
+	dir := t.TempDir()
+	writeTestPicture(t, filepath.Join(dir, "teal.png"), 16, 9, color.RGBA{30, 140, 160, 255})
+	cfg := testConfig(t, `{"pictures_dir": %q, "sync_accent_color": true}`, dir)
+	ChangeBG(cfg)
+	if accent := setter.get("accent-color"); accent != "teal" {
+		t.Errorf("got accent-color %q, want teal", accent)
+	}
+}
+
+func TestSyncAccentColorUnavailable(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q, "sync_accent_color": true}`, dir)
+	ChangeBG(cfg)
+	if CurrentPicture() == "" {
+		t.Fatal("the background was not changed")
+	}
+	if accent := setter.values("accent-color"); len(accent) != 0 {
+		t.Errorf("set accent-color %v on a desktop without it", accent)
+	}
+}
@@ -0,0 +1,56 @@
+package bgchanger
+
+import (
+	"log"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// defaultMinInterval is the shortest interval allowed in accessibility mode.
+const defaultMinInterval = 15 * time.Minute
+
+// AccessibilityConfig configures the accessibility mode, which forces the
+// calmest behavior for users sensitive to motion.
+type AccessibilityConfig struct {
+	Enabled     bool           `json:"enabled"`
+	MinInterval xjson.Duration `json:"min_interval"`
+}
+
+// accessibleInterval returns interval, or the minimum interval of the
+// accessibility mode if enabled and longer.
+func accessibleInterval(cfg *Config, interval xjson.Duration) xjson.Duration {
+	if cfg.Accessibility.Enabled && interval < cfg.Accessibility.MinInterval {
+		return cfg.Accessibility.MinInterval
+	}
+	return interval
+}
+
+// applyAccessibility overrides the animation related settings when the
+// accessibility mode is enabled: transitions are disabled, and intervals are
+// no shorter than the minimum.
+func applyAccessibility(cfg *Config) {
+	ac := &cfg.Accessibility
+	if !ac.Enabled {
+		return
+	}
+	if ac.MinInterval <= 0 {
+		ac.MinInterval = xjson.Duration(defaultMinInterval)
+	}
+	log.Printf("Accessibility mode is active, disabling transitions and changing background at most every %s", ac.MinInterval)
+	cfg.Transition = ""
+	if cfg.Interval > 0 && cfg.Interval < ac.MinInterval {
+		cfg.Interval = ac.MinInterval
+	}
+	clamp := func(sources []SourceConfig) {
+		for i := range sources {
+			if s := &sources[i]; s.Interval > 0 && s.Interval < ac.MinInterval {
+				s.Interval = ac.MinInterval
+			}
+		}
+	}
+	clamp(cfg.Sources)
+	for i := range cfg.TimeSources {
+		clamp(cfg.TimeSources[i].Sources)
+	}
+}
@@ -0,0 +1,45 @@
+package bgchanger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessibilityOverridesAnimations(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1m", "transition": "fade",
+		"sources": [{"type": "dir", "path": %q, "interval": "30s"}],
+		"accessibility": {"enabled": true}}`, dir, dir)
+	if cfg.Transition != "" {
+		t.Errorf("got transition %q in accessibility mode, want none", cfg.Transition)
+	}
+	if cfg.Interval.String() != defaultMinInterval.String() || cfg.Sources[0].Interval.String() != defaultMinInterval.String() {
+		t.Errorf("got interval %s and source interval %s, want at least %s", cfg.Interval, cfg.Sources[0].Interval, defaultMinInterval)
+	}
+	// a picture is applied in one step, without fade frames
+	ChangeBG(cfg)
+	if uris := setter.schemaValues(backgroundSchema, "picture-uri"); len(uris) != 1 {
+		t.Errorf("got picture-uri %v, want a single setting", uris)
+	}
+	// boosting cannot go below the minimum interval
+	Boost(cfg)
+	defer CancelBoost(cfg)
+	if got := time.Duration(EffectiveInterval(cfg)); got != defaultMinInterval {
+		t.Errorf("got interval %s while boosted, want %s", got, defaultMinInterval)
+	}
+}
+
+func TestAccessibilityMinInterval(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "10m", "transition": "fade",
+		"accessibility": {"enabled": true, "min_interval": "1h"}}`)
+	if time.Duration(cfg.Interval) != time.Hour {
+		t.Errorf("got interval %s, want the 1h min_interval", cfg.Interval)
+	}
+	cfg = testConfig(t, `{"pictures_dir": "/p", "interval": "10m", "transition": "fade"}`)
+	if cfg.Transition != transitionFade || time.Duration(cfg.Interval) != 10*time.Minute {
+		t.Errorf("got transition %q and interval %s without accessibility, want them unchanged", cfg.Transition, cfg.Interval)
+	}
+}
@@ -0,0 +1,49 @@
+package bgchanger
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// filterByAge keeps the pictures modified between max_age and min_age ago.
+// If none is, it returns all of them, unless age_fallback is false.
+func filterByAge(cfg *Config, pictures []string, now time.Time) []string {
+	if cfg.MaxAge <= 0 && cfg.MinAge <= 0 {
+		return pictures
+	}
+	var kept []string
+	for _, p := range pictures {
+		mtime, err := pictureModTime(p)
+		if err != nil {
+			log.Printf("Warning: cannot stat '%s': %v", p, err)
+			continue
+		}
+		age := now.Sub(mtime)
+		if cfg.MaxAge > 0 && age > time.Duration(cfg.MaxAge) {
+			continue
+		}
+		if cfg.MinAge > 0 && age < time.Duration(cfg.MinAge) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if len(kept) == 0 && len(pictures) > 0 && (cfg.AgeFallback == nil || *cfg.AgeFallback) {
+		debugf("No pictures within max_age and min_age, using any picture")
+		return pictures
+	}
+	return kept
+}
+
+// pictureModTime returns the modification time of the picture, or of the
+// bucket object it is downloaded from if it isn't downloaded yet.
+func pictureModTime(p string) (time.Time, error) {
+	st, err := os.Stat(p)
+	if err == nil {
+		return st.ModTime(), nil
+	}
+	if mtime, ok := s3ModTime(p); ok {
+		return mtime, nil
+	}
+	return time.Time{}, err
+}
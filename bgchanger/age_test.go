@@ -0,0 +1,72 @@
+package bgchanger
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterByAge(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 4)
+	now := time.Now()
+	// 1 hour, 2 days, 10 days and 30 days old
+	for i, age := range []time.Duration{time.Hour, 48 * time.Hour, 240 * time.Hour, 720 * time.Hour} {
+		mtime := now.Add(-age)
+		if err := os.Chtimes(pictures[i], mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		settings string
+		want     []string
+	}{
+		{``, pictures},
+		{`"max_age": "168h"`, pictures[:2]},
+		{`"min_age": "24h"`, pictures[1:]},
+		{`"min_age": "24h", "max_age": "480h"`, pictures[1:3]},
+		// nothing matches, so any picture is used
+		{`"min_age": "2000h"`, pictures},
+		{`"min_age": "2000h", "age_fallback": false`, nil},
+	} {
+		sep := ""
+		if tc.settings != "" {
+			sep = ", "
+		}
+		cfg := testConfig(t, `{"pictures_dir": %q%s%s}`, dir, sep, tc.settings)
+		if got := filterByAge(cfg, pictures, now); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.settings, got, tc.want)
+		}
+	}
+}
+
+func TestAgeValidate(t *testing.T) {
+	for _, tc := range []string{
+		`{"pictures_dir": "/p", "max_age": "-1h"}`,
+		`{"pictures_dir": "/p", "max_age": "1h", "min_age": "2h"}`,
+	} {
+		if _, err := ParseConfig([]byte(tc)); err == nil {
+			t.Errorf("%s: got no error, want an invalid age error", tc)
+		}
+	}
+}
+
+func TestPickRecentPictures(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	old := time.Now().AddDate(0, -1, 0)
+	for _, p := range pictures[1:] {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "max_age": "168h"}`, dir)
+	for i := 0; i < 10; i++ {
+		if p, err := PickPicture(cfg); err != nil || p != pictures[0] {
+			t.Fatalf("got %s, %v, want the only recent picture %s", p, err, pictures[0])
+		}
+	}
+}
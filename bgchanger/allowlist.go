@@ -0,0 +1,107 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kirsle/configdir"
+)
+
+// writeAllowlist holds the directories that files may be written to, from
+// write_allowlist. If empty, any path may be written.
+var writeAllowlist = struct {
+	sync.Mutex
+	dirs []string
+}{}
+
+// setWriteAllowlist sets the directories that files may be written to.
+func setWriteAllowlist(dirs []string) {
+	resolved := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		resolved = append(resolved, realPath(d))
+	}
+	writeAllowlist.Lock()
+	writeAllowlist.dirs = resolved
+	writeAllowlist.Unlock()
+}
+
+// LoadWriteAllowlist sets write_allowlist from the config at location, like
+// LoadConfigFrom does, but without creating or fetching the config, so that
+// the command line actions run before loading the config are restricted too.
+// A remote config uses its cached copy, and without a config nothing is
+// restricted.
+func LoadWriteAllowlist(location string) error {
+	filename := location
+	switch {
+	case location == "":
+		filename = path.Join(configdir.LocalConfig(Progname), "config.json")
+	case isRemoteConfig(location):
+		filename = remoteConfigCache()
+	}
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	if !isRemoteConfig(location) {
+		cfg.resolvePaths(filepath.Dir(filename))
+	}
+	setWriteAllowlist(cfg.WriteAllowlist)
+	return nil
+}
+
+// realPath returns the absolute path of filename with the symlinks of its
+// directory resolved, so that a symlink cannot lead a write outside of the
+// allowlist. The file itself does not need to exist.
+func realPath(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.Clean(filename)
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs))
+	}
+	return abs
+}
+
+// makeDirs is configdir.MakePath, checking write_allowlist first, so that a
+// disallowed path doesn't get its directories created. Existing directories
+// are fine, as nothing is created then.
+func makeDirs(dir string) error {
+	if st, err := os.Stat(dir); err == nil && st.IsDir() {
+		return nil
+	}
+	if err := checkWrite(dir); err != nil {
+		return err
+	}
+	return configdir.MakePath(dir)
+}
+
+// checkWrite returns an error if filename is outside of the directories of
+// write_allowlist. It must be called before creating, changing or removing
+// any file, except the instance lock.
+func checkWrite(filename string) error {
+	writeAllowlist.Lock()
+	defer writeAllowlist.Unlock()
+	if len(writeAllowlist.dirs) == 0 {
+		return nil
+	}
+	p := realPath(filename)
+	for _, dir := range writeAllowlist.dirs {
+		rel, err := filepath.Rel(dir, p)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing to write '%s', it is outside of write_allowlist", filename)
+}
@@ -0,0 +1,152 @@
+package bgchanger
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kirsle/configdir"
+)
+
+func TestCheckWrite(t *testing.T) {
+	testEnv(t)
+	allowed, outside := t.TempDir(), t.TempDir()
+	// without an allowlist, anything goes
+	if err := checkWrite(filepath.Join(outside, "f")); err != nil {
+		t.Errorf("got %v without an allowlist", err)
+	}
+
+	setWriteAllowlist([]string{allowed})
+	for _, p := range []string{
+		filepath.Join(allowed, "f"),
+		filepath.Join(allowed, "sub", "dir", "f"),
+		allowed,
+	} {
+		if err := checkWrite(p); err != nil {
+			t.Errorf("got %v for %s inside the allowlist", err, p)
+		}
+	}
+	escape := filepath.Join(allowed, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{
+		filepath.Join(outside, "f"),
+		filepath.Join(allowed, "..", filepath.Base(outside), "f"),
+		allowed + "-sibling",
+		// a symlink cannot lead outside
+		filepath.Join(escape, "f"),
+	} {
+		if err := checkWrite(p); err == nil {
+			t.Errorf("got no error for %s outside the allowlist", p)
+		}
+	}
+
+	if err := writeFileAtomic(filepath.Join(outside, "f"), []byte("x")); err == nil {
+		t.Error("wrote outside the allowlist")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "f")); !os.IsNotExist(err) {
+		t.Errorf("the file outside the allowlist exists: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(allowed, "f"), []byte("x")); err != nil {
+		t.Errorf("got %v writing inside the allowlist", err)
+	}
+}
+
+func TestLoadWriteAllowlist(t *testing.T) {
+	testEnv(t)
+	allowed := t.TempDir()
+	// no config, no restriction
+	if err := LoadWriteAllowlist(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := InstallAutostart(); err != nil {
+		t.Errorf("got %v without an allowlist", err)
+	}
+
+	configFile := filepath.Join(configdir.LocalConfig(Progname), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "/p", "write_allowlist": ["`+allowed+`"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadWriteAllowlist(""); err != nil {
+		t.Fatal(err)
+	}
+	// the command line actions are restricted before loading the config
+	if err := UninstallAutostart(); err == nil {
+		t.Error("removed the autostart file outside of the allowlist")
+	}
+	if _, err := os.Stat(autostartFile()); err != nil {
+		t.Errorf("the autostart file is gone: %v", err)
+	}
+	if err := InstallAutostart(); err == nil {
+		t.Error("wrote the autostart file outside of the allowlist")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.zip")
+	fd, err := os.Create(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(fd)
+	w, err := zw.Create("ratings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	if err := ImportBundle("", bundle); err == nil {
+		t.Error("imported files outside of the allowlist")
+	}
+	if _, err := os.Stat(ratingsFile()); !os.IsNotExist(err) {
+		t.Errorf("the imported ratings exist: %v", err)
+	}
+
+	// relative paths are relative to the config file
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "/p", "write_allowlist": ["."]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadWriteAllowlist(configFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkWrite(filepath.Join(filepath.Dir(configFile), "ratings.json")); err != nil {
+		t.Errorf("got %v inside the config directory", err)
+	}
+	if err := checkWrite(filepath.Join(allowed, "f")); err == nil {
+		t.Error("got no error outside the config directory")
+	}
+
+	if err := os.WriteFile(configFile, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadWriteAllowlist(configFile); err == nil {
+		t.Error("got no error for an invalid config")
+	}
+}
+
+func TestMakeDirsChecksAllowlist(t *testing.T) {
+	testEnv(t)
+	allowed := t.TempDir()
+	setWriteAllowlist([]string{allowed})
+	if err := makeDirs(filepath.Join(allowed, "a", "b")); err != nil {
+		t.Errorf("got %v inside the allowlist", err)
+	}
+	// the cache is outside of the allowlist, so downloading doesn't create
+	// its directories
+	downloads := filepath.Join(configdir.LocalCache(Progname), "downloads")
+	if _, err := downloadURLs([]string{"http://127.0.0.1:1/a.jpg"}); err == nil {
+		t.Error("got no error downloading outside of the allowlist")
+	}
+	if _, err := os.Stat(downloads); !os.IsNotExist(err) {
+		t.Errorf("got %v, want %s not created", err, downloads)
+	}
+	// existing directories are fine
+	if err := makeDirs(configdir.LocalConfig(Progname)); err != nil {
+		t.Errorf("got %v for an existing directory", err)
+	}
+}
@@ -0,0 +1,113 @@
+package bgchanger_test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/insomniacslk/gnome-background-changer/bgchanger"
+	"github.com/kirsle/configdir"
+)
+
+// fakeSetter is a BackgroundSetter keeping the settings in memory.
+type fakeSetter struct {
+	sync.Mutex
+	settings map[string]string
+}
+
+func (fs *fakeSetter) Set(schema, key, value string) error {
+	fs.Lock()
+	defer fs.Unlock()
+	fs.settings[schema+" "+key] = value
+	return nil
+}
+
+func (fs *fakeSetter) Get(schema, key string) (string, error) {
+	fs.Lock()
+	defer fs.Unlock()
+	value, ok := fs.settings[schema+" "+key]
+	if !ok {
+		return "", fmt.Errorf("no such key %s", key)
+	}
+	return "'" + value + "'", nil
+}
+
+func (fs *fakeSetter) get(key string) string {
+	fs.Lock()
+	defer fs.Unlock()
+	return fs.settings["org.gnome.desktop.background "+key]
+}
+
+func TestPublicAPI(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	configdir.Refresh()
+	defer configdir.Refresh()
+	dir := filepath.Join(tmp, "pictures")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var pictures []string
+	for i, c := range []color.RGBA{{200, 0, 0, 255}, {0, 0, 200, 255}} {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 9))
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = c.R, c.G, c.B, c.A
+		}
+		p := filepath.Join(dir, fmt.Sprintf("%d.png", i))
+		fd, err := os.Create(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(fd, img); err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+		pictures = append(pictures, p)
+	}
+
+	setter := &fakeSetter{settings: map[string]string{
+		// GNOME is showing the first picture, so it is not picked again
+		"org.gnome.desktop.background picture-uri": "file://" + pictures[0],
+	}}
+	orig := bgchanger.Setter
+	bgchanger.Setter = setter
+	defer func() { bgchanger.Setter = orig }()
+	if err := bgchanger.CheckBackend(); err != nil {
+		t.Errorf("a replaced Setter needs no backend, got %v", err)
+	}
+
+	cfg, err := bgchanger.ParseConfig([]byte(fmt.Sprintf(`{"pictures_dir": %q}`, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := bgchanger.ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(candidates, ",") != strings.Join(pictures, ",") {
+		t.Fatalf("got candidates %v, want %v", candidates, pictures)
+	}
+	bgchanger.ChangeBG(cfg)
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[1] {
+		t.Errorf("got picture-uri %s, want the picture GNOME is not showing", uri)
+	}
+	if current := bgchanger.CurrentPicture(); current != pictures[1] {
+		t.Errorf("got current picture %s, want %s", current, pictures[1])
+	}
+	if err := bgchanger.ApplyIndex(cfg, 0); err != nil {
+		t.Fatal(err)
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[0] {
+		t.Errorf("got picture-uri %s after applying index 0", uri)
+	}
+	if err := bgchanger.ApplyIndex(cfg, 2); err == nil {
+		t.Error("applied an index out of range")
+	}
+}
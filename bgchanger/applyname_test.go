@@ -0,0 +1,66 @@
+package bgchanger
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyName(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_variant": "same"}`, dir)
+
+	if err := ApplyName(cfg, "1.png"); err != nil {
+		t.Fatal(err)
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[1] {
+		t.Errorf("got picture-uri %s, want %s", uri, pictures[1])
+	}
+	if uri := setter.get("picture-uri-dark"); uri != "file://"+pictures[1] {
+		t.Errorf("got picture-uri-dark %s, want the same picture in dark mode", uri)
+	}
+
+	// an absolute path does not need to be a candidate
+	other := writeTestPicture(t, filepath.Join(t.TempDir(), "other.png"), 16, 9, color.RGBA{R: 7, A: 255})
+	if err := ApplyName(cfg, other); err != nil {
+		t.Fatal(err)
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+other {
+		t.Errorf("got picture-uri %s, want %s", uri, other)
+	}
+	if err := ApplyName(cfg, filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("got no error for a missing absolute path")
+	}
+
+	before := len(setter.sets)
+	if err := ApplyName(cfg, "missing.png"); err == nil || !strings.Contains(err.Error(), "no candidate") {
+		t.Errorf("got %v, want a not found error", err)
+	}
+	if len(setter.sets) != before {
+		t.Error("the background changed for a name that matches nothing")
+	}
+}
+
+func TestApplyNameAmbiguous(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestPicture(t, filepath.Join(dir, sub, "sunset.png"), 16, 9, color.RGBA{G: 1, A: 255})
+	}
+	cfg := testConfig(t, `{"pictures_dir": "/unused", "sources": [{"type": "dir", "path": %q}, {"type": "dir", "path": %q}]}`,
+		filepath.Join(dir, "a"), filepath.Join(dir, "b"))
+	if err := ApplyName(cfg, "sunset.png"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("got %v, want an ambiguous name error", err)
+	}
+	// a relative path disambiguates
+	if err := ApplyName(cfg, filepath.Join("b", "sunset.png")); err != nil {
+		t.Error(err)
+	}
+}
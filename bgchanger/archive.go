@@ -0,0 +1,147 @@
+package bgchanger
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+const sourceArchive = "archive"
+
+// isArchive reports whether filename looks like a supported archive.
+func isArchive(filename string) bool {
+	lower := strings.ToLower(filename)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+var extractMu sync.Mutex
+
+// extractArchive extracts the pictures in the archive to a cache directory
+// and returns it. The archive is extracted again only if it was modified
+// since the last extraction.
+func extractArchive(archive string) (string, error) {
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	st, err := os.Stat(archive)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum([]byte(archive))
+	dir := path.Join(configdir.LocalCache(Progname), "archives", hex.EncodeToString(sum[:]))
+	stamp := path.Join(dir, ".mtime")
+	if data, err := os.ReadFile(stamp); err == nil && string(data) == st.ModTime().UTC().Format(time.RFC3339Nano) {
+		return dir, nil
+	}
+	log.Printf("Extracting pictures from '%s'", archive)
+	if err := checkWrite(dir); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("failed to clean up '%s': %w", dir, err)
+	}
+	if err := makeDirs(dir); err != nil {
+		return "", fmt.Errorf("failed to create '%s': %w", dir, err)
+	}
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		err = extractZip(archive, dir)
+	} else {
+		err = extractTarGz(archive, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to extract '%s': %w", archive, err)
+	}
+	if err := checkWrite(stamp); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(stamp, []byte(st.ModTime().UTC().Format(time.RFC3339Nano)), 0644); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// extractedName flattens the name of an archive entry into a file name, so
+// that all pictures end up in the same directory and entries cannot escape
+// it.
+func extractedName(name string) string {
+	name = strings.TrimLeft(path.Clean("/"+name), "/")
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+func extractZip(archive, dir string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !hasSupportedExtension(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeExtracted(path.Join(dir, extractedName(f.Name)), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archive, dir string) error {
+	fd, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	gz, err := gzip.NewReader(fd)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !hasSupportedExtension(hdr.Name) {
+			continue
+		}
+		if err := writeExtracted(path.Join(dir, extractedName(hdr.Name)), tr); err != nil {
+			return err
+		}
+	}
+}
+
+func writeExtracted(filename string, r io.Reader) error {
+	if err := checkWrite(filename); err != nil {
+		return err
+	}
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fd, r); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
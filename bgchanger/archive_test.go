@@ -0,0 +1,123 @@
+package bgchanger
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestZipArchiveCandidates(t *testing.T) {
+	testEnv(t)
+	picture, err := os.ReadFile(writeTestPictures(t, t.TempDir(), 1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{
+		"pack/a.png":    picture,
+		"pack/b/c.PNG":  picture,
+		"../escape.png": picture,
+		"README.txt":    []byte("not a picture"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := zw.Create("pack/dir.png/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "pack.zip")
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": %q}`, archive)
+	candidates, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range candidates {
+		names = append(names, filepath.Base(c))
+	}
+	sort.Strings(names)
+	if want := []string{"escape.png", "pack_a.png", "pack_b_c.PNG"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got candidates %v, want %v", names, want)
+	}
+	p, err := PickPicture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(candidates, p) {
+		t.Errorf("picked %s, not a candidate", p)
+	}
+}
+
+func TestTarGzArchiveReextracted(t *testing.T) {
+	testEnv(t)
+	picture, err := os.ReadFile(writeTestPictures(t, t.TempDir(), 1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "pack.tar.gz")
+	writeTarGz := func(names ...string) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, name := range names {
+			hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(picture)), Typeflag: tar.TypeReg}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(picture); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTarGz("one.png")
+	dir, err := extractArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "one.png")); err != nil {
+		t.Fatal(err)
+	}
+	writeTarGz("two.png")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(archive, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := extractArchive(archive); err != nil || again != dir {
+		t.Fatalf("got %s, %v, want %s", again, err, dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "one.png")); !os.IsNotExist(err) {
+		t.Errorf("the pictures of the previous archive were kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "two.png")); err != nil {
+		t.Errorf("the modified archive was not extracted: %v", err)
+	}
+}
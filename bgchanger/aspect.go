@@ -0,0 +1,73 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	aspectRatioAuto        = "auto"
+	defaultAspectTolerance = 0.05
+)
+
+var imageSizeCache = newFileCache(func(filename string) ([2]int, error) {
+	w, h, err := imageSize(filename)
+	return [2]int{w, h}, err
+})
+
+// parseAspectRatio parses a ratio like "16:9".
+func parseAspectRatio(s string) (float64, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid aspect_ratio '%s', want auto or a ratio like 16:9", s)
+	}
+	w, errW := strconv.ParseFloat(parts[0], 64)
+	h, errH := strconv.ParseFloat(parts[1], 64)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0, fmt.Errorf("invalid aspect_ratio '%s', want auto or a ratio like 16:9", s)
+	}
+	return w / h, nil
+}
+
+// targetAspectRatio returns the configured aspect ratio, reading the one of
+// the primary display for "auto".
+func targetAspectRatio(cfg *Config) (float64, error) {
+	if cfg.AspectRatio != aspectRatioAuto {
+		return parseAspectRatio(cfg.AspectRatio)
+	}
+	w, h, err := displayResolution()
+	if err != nil {
+		return 0, err
+	}
+	return float64(w) / float64(h), nil
+}
+
+// filterByAspectRatio returns the pictures whose aspect ratio is within the
+// configured tolerance of the target ratio, or all of them if none is.
+func filterByAspectRatio(cfg *Config, pictures []string) []string {
+	target, err := targetAspectRatio(cfg)
+	if err != nil {
+		log.Printf("Warning: cannot get aspect ratio, using any picture: %v", err)
+		return pictures
+	}
+	var matching []string
+	for _, p := range pictures {
+		size, err := imageSizeCache.get(p)
+		if err != nil || size[1] == 0 {
+			log.Printf("Warning: cannot get size of '%s': %v", p, err)
+			continue
+		}
+		ratio := float64(size[0]) / float64(size[1])
+		if math.Abs(ratio-target)/target <= cfg.AspectRatioTolerance {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		log.Printf("No pictures with aspect ratio %.2f, using any picture", target)
+		return pictures
+	}
+	return matching
+}
@@ -0,0 +1,62 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestAspectRatioFilter(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	gray := color.RGBA{128, 128, 128, 255}
+	wide := writeTestPicture(t, filepath.Join(dir, "wide.png"), 160, 90, gray)
+	almostWide := writeTestPicture(t, filepath.Join(dir, "almost.png"), 162, 90, gray)
+	writeTestPicture(t, filepath.Join(dir, "1610.png"), 160, 100, gray)
+	ultrawide := writeTestPicture(t, filepath.Join(dir, "ultrawide.png"), 210, 90, gray)
+
+	selectable := func(cfg *Config) map[string]bool {
+		picked := make(map[string]bool)
+		for i := 0; i < 40; i++ {
+			p, err := PickPicture(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			picked[p] = true
+		}
+		return picked
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "aspect_ratio": "16:9"}`, dir)
+	if picked := selectable(cfg); len(picked) != 2 || !picked[wide] || !picked[almostWide] {
+		t.Errorf("picked %v for 16:9, want %s and %s", picked, wide, almostWide)
+	}
+	cfg = testConfig(t, `{"pictures_dir": %q, "aspect_ratio": "16:9", "aspect_ratio_tolerance": 0.001}`, dir)
+	if picked := selectable(cfg); len(picked) != 1 || !picked[wide] {
+		t.Errorf("picked %v with a small tolerance, want only %s", picked, wide)
+	}
+	// the primary display is 21:9
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		return []byte("DP-1 connected primary 2560x1080+0+0 (normal)\n"), nil
+	}
+	cfg = testConfig(t, `{"pictures_dir": %q, "aspect_ratio": "auto", "aspect_ratio_tolerance": 0.02}`, dir)
+	if picked := selectable(cfg); len(picked) != 1 || !picked[ultrawide] {
+		t.Errorf("picked %v for the display, want only %s", picked, ultrawide)
+	}
+	// any picture is used if none matches
+	cfg = testConfig(t, `{"pictures_dir": %q, "aspect_ratio": "4:3"}`, dir)
+	if picked := selectable(cfg); len(picked) != 4 {
+		t.Errorf("picked %v for 4:3, want any picture", picked)
+	}
+}
+
+func TestParseAspectRatio(t *testing.T) {
+	for _, s := range []string{"16", "16:0", "a:b", "-4:3"} {
+		if _, err := parseAspectRatio(s); err == nil {
+			t.Errorf("parsed invalid aspect_ratio %q", s)
+		}
+	}
+	if r, err := parseAspectRatio("16:10"); err != nil || r != 1.6 {
+		t.Errorf("got %v, %v for 16:10", r, err)
+	}
+}
@@ -0,0 +1,77 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kirsle/configdir"
+)
+
+func autostartFile() string {
+	return path.Join(configdir.LocalConfig("autostart"), Progname+".desktop")
+}
+
+// desktopEntry returns the contents of an autostart .desktop file running the
+// given executable.
+func desktopEntry(executable string) string {
+	return fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Comment=Change background randomly
+Exec=%s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, Progname, quoteExec(executable))
+}
+
+// quoteExec quotes an argument of the Exec key as required by the desktop
+// entry specification.
+func quoteExec(arg string) string {
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+// IsAutostartInstalled reports whether the app starts on login.
+func IsAutostartInstalled() bool {
+	_, err := os.Stat(autostartFile())
+	return err == nil
+}
+
+// InstallAutostart makes the app start on login, by writing a .desktop file
+// for the current executable in ~/.config/autostart.
+func InstallAutostart() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if err := checkWrite(autostartFile()); err != nil {
+		return err
+	}
+	dir := configdir.LocalConfig("autostart")
+	if err := makeDirs(dir); err != nil {
+		return fmt.Errorf("failed to create autostart directory '%s': %w", dir, err)
+	}
+	if err := os.WriteFile(autostartFile(), []byte(desktopEntry(executable)), 0644); err != nil {
+		return fmt.Errorf("failed to write autostart file: %w", err)
+	}
+	log.Printf("Installed autostart file %s", autostartFile())
+	return nil
+}
+
+// UninstallAutostart stops the app from starting on login.
+func UninstallAutostart() error {
+	if err := checkWrite(autostartFile()); err != nil {
+		return err
+	}
+	if err := os.Remove(autostartFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove autostart file: %w", err)
+	}
+	log.Printf("Removed autostart file %s", autostartFile())
+	return nil
+}
@@ -0,0 +1,64 @@
+package bgchanger
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDesktopEntry(t *testing.T) {
+	entry := desktopEntry("/home/user/my apps/bgchanger")
+	for _, line := range []string{
+		"[Desktop Entry]",
+		"Type=Application",
+		"Name=" + Progname,
+		`Exec="/home/user/my apps/bgchanger"`,
+		"X-GNOME-Autostart-enabled=true",
+	} {
+		if !strings.Contains(entry, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, entry)
+		}
+	}
+	for arg, want := range map[string]string{
+		"/usr/bin/bgchanger": "/usr/bin/bgchanger",
+		`/opt/a"b$c`:         `"/opt/a\"b\$c"`,
+		`/opt/a\b`:           `"/opt/a\\b"`,
+	} {
+		if got := quoteExec(arg); got != want {
+			t.Errorf("quoteExec(%q) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestInstallAutostart(t *testing.T) {
+	testEnv(t)
+	if IsAutostartInstalled() {
+		t.Fatal("autostart installed in a new environment")
+	}
+	if err := InstallAutostart(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(autostartFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != desktopEntry(executable) {
+		t.Errorf("got\n%s\nwant\n%s", data, desktopEntry(executable))
+	}
+	if !IsAutostartInstalled() {
+		t.Error("autostart not reported as installed")
+	}
+	if err := UninstallAutostart(); err != nil {
+		t.Fatal(err)
+	}
+	if IsAutostartInstalled() {
+		t.Error("autostart still installed")
+	}
+	if err := UninstallAutostart(); err != nil {
+		t.Errorf("uninstalling twice: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package bgchanger
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/kirsle/configdir"
+)
+
+// blocklistHashRegexp matches the hex-encoded SHA-256 of an entry of the
+// blocklist.
+var blocklistHashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// blocklistCache holds the parsed blocklist, reloaded when the file changes,
+// e.g. after being synced from another machine.
+var blocklistCache = newFileCache(readBlocklist)
+
+// OnBlocklistChange is called after pictures are added to or removed from the
+// blocklist.
+var OnBlocklistChange = func() {}
+
+// BlockedPicture is an entry of the blocklist.
+type BlockedPicture struct {
+	Hash string
+	// Name is the file name of the picture when it was blocked, if known.
+	Name string
+}
+
+// blocklistFile returns the path of the blocklist, which lists the content
+// hashes of the pictures that must never be shown.
+func blocklistFile(cfg *Config) string {
+	if cfg.BlocklistFile != "" {
+		return cfg.BlocklistFile
+	}
+	return path.Join(configdir.LocalConfig(Progname), "blocklist.txt")
+}
+
+// readBlocklist parses a blocklist file, with one hex-encoded SHA-256 per
+// line. Empty lines and lines starting with # are ignored, and other invalid
+// lines are skipped with a warning.
+func readBlocklist(filename string) (map[string]bool, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	hashes := make(map[string]bool)
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.ToLower(line)
+		if !blocklistHashRegexp.MatchString(line) {
+			log.Printf("Warning: ignoring invalid line '%s' in blocklist '%s'", line, filename)
+			continue
+		}
+		hashes[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist '%s': %w", filename, err)
+	}
+	return hashes, nil
+}
+
+// filterBlocked removes the pictures whose contents are in the blocklist.
+// Unlike excludePictures, this can leave no pictures at all.
+func filterBlocked(cfg *Config, pictures []string) []string {
+	blocked, err := blocklistCache.get(blocklistFile(cfg))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read blocklist: %v", err)
+		}
+		return pictures
+	}
+	if len(blocked) == 0 {
+		return pictures
+	}
+	var kept []string
+	for _, p := range pictures {
+		h, err := contentHash(p)
+		if err != nil {
+			log.Printf("Warning: cannot hash '%s': %v", p, err)
+		} else if blocked[h] {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if n := len(pictures) - len(kept); n > 0 {
+		debugf("Skipped %d blocked pictures", n)
+	}
+	return kept
+}
+
+// blockPicture adds the contents of the picture to the blocklist.
+func blockPicture(cfg *Config, filename string) error {
+	h, err := contentHash(filename)
+	if err != nil {
+		return fmt.Errorf("failed to hash '%s': %w", filename, err)
+	}
+	blFile := blocklistFile(cfg)
+	if err := checkWrite(blFile); err != nil {
+		return err
+	}
+	if err := makeDirs(path.Dir(blFile)); err != nil {
+		return fmt.Errorf("failed to create blocklist directory: %w", err)
+	}
+	fd, err := os.OpenFile(blFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	if _, err := fmt.Fprintf(fd, "# %s\n%s\n", path.Base(filename), h); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	log.Printf("Blocked '%s'", filename)
+	OnBlocklistChange()
+	return nil
+}
+
+// Blocklist returns the valid entries of the blocklist, in file order. The
+// name of each entry comes from the comment line right above its hash.
+func Blocklist(cfg *Config) ([]BlockedPicture, error) {
+	data, err := os.ReadFile(blocklistFile(cfg))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	var (
+		entries []BlockedPicture
+		name    string
+	)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			name = ""
+		case strings.HasPrefix(line, "#"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		case blocklistHashRegexp.MatchString(strings.ToLower(line)):
+			entries = append(entries, BlockedPicture{Hash: strings.ToLower(line), Name: name})
+			name = ""
+		default:
+			// invalid lines are ignored, as by readBlocklist
+			name = ""
+		}
+	}
+	return entries, nil
+}
+
+// Unblock removes the picture with the given hash from the blocklist, along
+// with the comment line naming it.
+func Unblock(cfg *Config, hash string) error {
+	data, err := os.ReadFile(blocklistFile(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	var kept []string
+	for _, line := range lines {
+		if strings.ToLower(strings.TrimSpace(line)) == hash {
+			if n := len(kept); n > 0 && strings.HasPrefix(strings.TrimSpace(kept[n-1]), "#") {
+				kept = kept[:n-1]
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == len(lines) {
+		return fmt.Errorf("'%s' is not in the blocklist", hash)
+	}
+	if err := writeFileAtomic(blocklistFile(cfg), []byte(strings.Join(kept, "\n"))); err != nil {
+		return fmt.Errorf("failed to write blocklist: %w", err)
+	}
+	log.Printf("Unblocked %s", hash)
+	OnBlocklistChange()
+	return nil
+}
+
+// ClearBlocklist removes all the pictures from the blocklist.
+func ClearBlocklist(cfg *Config) error {
+	if err := checkWrite(blocklistFile(cfg)); err != nil {
+		return err
+	}
+	if err := os.Remove(blocklistFile(cfg)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear blocklist: %w", err)
+	}
+	log.Printf("Cleared the blocklist")
+	OnBlocklistChange()
+	return nil
+}
+
+// BlockCurrent adds the current picture to the blocklist, and changes the
+// background.
+func BlockCurrent(cfg *Config) {
+	filename := CurrentPicture()
+	if filename == "" {
+		log.Printf("No background was set yet, nothing to block")
+		return
+	}
+	if err := blockPicture(cfg, filename); err != nil {
+		log.Printf("Error: %v", err)
+		return
+	}
+	ChangeBG(cfg)
+}
@@ -0,0 +1,120 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlocklistSurvivesRename(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := blockPicture(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	renamed := filepath.Join(dir, "renamed.png")
+	if err := os.Rename(pictures[0], renamed); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := filepath.Join(t.TempDir(), "copy.png")
+	if err := os.WriteFile(elsewhere, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanned, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 2 || contains(scanned, renamed) {
+		t.Errorf("got %v, want the renamed blocked picture excluded", scanned)
+	}
+	if kept := filterBlocked(cfg, []string{elsewhere, pictures[1]}); len(kept) != 1 || kept[0] != pictures[1] {
+		t.Errorf("got %v, want the copy of the blocked picture excluded", kept)
+	}
+
+	entries, err := Blocklist(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "0.png" {
+		t.Errorf("got blocklist %+v, want 0.png", entries)
+	}
+}
+
+func TestBlocklistEntries(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	var changes int
+	oldHook := OnBlocklistChange
+	OnBlocklistChange = func() { changes++ }
+	defer func() { OnBlocklistChange = oldHook }()
+
+	for _, p := range pictures[:2] {
+		if err := blockPicture(cfg, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Blocklist(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "0.png" || entries[1].Name != "1.png" {
+		t.Fatalf("got entries %+v, want 0.png and 1.png", entries)
+	}
+
+	if err := Unblock(cfg, entries[0].Hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unblock(cfg, entries[0].Hash); err == nil {
+		t.Error("unblocked a picture twice")
+	}
+	if left, err := Blocklist(cfg); err != nil || len(left) != 1 || left[0] != entries[1] {
+		t.Errorf("got %+v, %v after unblocking, want only %+v", left, err, entries[1])
+	}
+	if scanned, err := ScanCandidates(cfg); err != nil || len(scanned) != 2 {
+		t.Errorf("got candidates %v, %v, want the unblocked pictures", scanned, err)
+	}
+
+	if err := ClearBlocklist(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if left, err := Blocklist(cfg); err != nil || len(left) != 0 {
+		t.Errorf("got %+v, %v after clearing", left, err)
+	}
+	if changes != 4 {
+		t.Errorf("got %d blocklist change notifications, want 4", changes)
+	}
+}
+
+func TestBlocklistInvalidLines(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	h, err := contentHash(pictures[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blocklistFile(cfg), []byte("abc\n# named\nnot a hash\n"+strings.ToUpper(h)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Blocklist(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Hash != h || entries[0].Name != "" {
+		t.Errorf("got entries %+v, want only %s", entries, h)
+	}
+	if scanned, err := ScanCandidates(cfg); err != nil || len(scanned) != 1 || scanned[0] != pictures[1] {
+		t.Errorf("got candidates %v, %v, want %s", scanned, err, pictures[1])
+	}
+}
@@ -0,0 +1,92 @@
+package bgchanger
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// BoostDuration is how long Boost speeds up the changes for.
+var BoostDuration = 10 * time.Minute
+
+// boostInterval is the interval while boosted, unless the accessibility mode
+// asks for a longer one.
+const boostInterval = time.Minute
+
+// OnBoostChange is called with whether the changes are sped up, when that
+// changes.
+var OnBoostChange = func(boosted bool) {}
+
+// boost is the temporary speed up of the changes.
+var boost = struct {
+	sync.Mutex
+	until time.Time
+	timer *time.Timer
+}{}
+
+// boostedInterval returns the interval to use while boosted, and whether the
+// changes are boosted at all.
+func boostedInterval(cfg *Config) (xjson.Duration, bool) {
+	boost.Lock()
+	defer boost.Unlock()
+	if boost.timer == nil {
+		return 0, false
+	}
+	return accessibleInterval(cfg, xjson.Duration(boostInterval)), true
+}
+
+// Boosted returns whether the changes are sped up, and until when.
+func Boosted() (time.Time, bool) {
+	boost.Lock()
+	defer boost.Unlock()
+	return boost.until, boost.timer != nil
+}
+
+// Boost speeds up the changes for BoostDuration, after which the configured
+// interval is restored. Boosting again restarts the period.
+func Boost(cfg *Config) {
+	before := EffectiveInterval(cfg)
+	boost.Lock()
+	if boost.timer != nil {
+		boost.timer.Stop()
+	}
+	boost.until = time.Now().Add(BoostDuration)
+	var t *time.Timer
+	t = time.AfterFunc(BoostDuration, func() {
+		boost.Lock()
+		// a newer boost or a cancel may have replaced this timer already
+		expired := boost.timer == t
+		boost.Unlock()
+		if expired {
+			endBoost(cfg)
+		}
+	})
+	boost.timer = t
+	boost.Unlock()
+	log.Printf("Speeding up the changes until %s", boost.until.Format("15:04"))
+	OnBoostChange(true)
+	notifyIntervalChange(cfg, before)
+}
+
+// CancelBoost restores the configured interval before the end of a boost.
+func CancelBoost(cfg *Config) {
+	endBoost(cfg)
+}
+
+func endBoost(cfg *Config) {
+	before := EffectiveInterval(cfg)
+	boost.Lock()
+	if boost.timer == nil {
+		boost.Unlock()
+		return
+	}
+	boost.timer.Stop()
+	boost.timer = nil
+	boost.until = time.Time{}
+	boost.Unlock()
+	log.Printf("Changes are no longer sped up")
+	OnBoostChange(false)
+	notifyIntervalChange(cfg, before)
+}
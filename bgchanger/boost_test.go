@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBoost(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "1h"}`)
+	old := BoostDuration
+	BoostDuration = 50 * time.Millisecond
+	t.Cleanup(func() { BoostDuration = old })
+	changes := make(chan bool, 2)
+	OnBoostChange = func(boosted bool) { changes <- boosted }
+	t.Cleanup(func() { OnBoostChange = func(bool) {} })
+
+	Boost(cfg)
+	if got := time.Duration(EffectiveInterval(cfg)); got != boostInterval {
+		t.Errorf("got interval %s while boosted, want %s", got, boostInterval)
+	}
+	if until, ok := Boosted(); !ok || until.IsZero() {
+		t.Errorf("got boosted %v until %v, want a boost", ok, until)
+	}
+	if len(intervalChanges) == 0 {
+		t.Error("the interval change was not notified")
+	}
+	if boosted := <-changes; !boosted {
+		t.Error("OnBoostChange was not called with true")
+	}
+
+	// the configured interval comes back after the boost period
+	drain(intervalChanges)
+	select {
+	case boosted := <-changes:
+		if boosted {
+			t.Error("got a second boost instead of its end")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the boost did not end")
+	}
+	if got := time.Duration(EffectiveInterval(cfg)); got != time.Hour {
+		t.Errorf("got interval %s after the boost, want the configured 1h", got)
+	}
+	if _, ok := Boosted(); ok {
+		t.Error("still boosted after the boost period")
+	}
+	// notified after OnBoostChange, from the timer goroutine
+	select {
+	case <-intervalChanges:
+	case <-time.After(5 * time.Second):
+		t.Error("the end of the boost was not notified")
+	}
+}
+
+func TestCancelBoost(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "1h", "accessibility": {"enabled": true, "min_interval": "5m"}}`)
+	Boost(cfg)
+	if got := time.Duration(EffectiveInterval(cfg)); got != 5*time.Minute {
+		t.Errorf("got interval %s while boosted, want the accessibility min_interval", got)
+	}
+	CancelBoost(cfg)
+	if got := time.Duration(EffectiveInterval(cfg)); got != time.Hour {
+		t.Errorf("got interval %s after cancelling, want the configured 1h", got)
+	}
+	if _, ok := Boosted(); ok {
+		t.Error("still boosted after cancelling")
+	}
+}
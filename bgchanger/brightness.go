@@ -0,0 +1,82 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// BrightnessConfig configures the "brightness" mode, which prefers bright
+// pictures during the day and dark ones at night.
+type BrightnessConfig struct {
+	DayStart int             `json:"day_start"`
+	DayEnd   int             `json:"day_end"`
+	Day      BrightnessRange `json:"day"`
+	Night    BrightnessRange `json:"night"`
+}
+
+// BrightnessRange is a range of average luminance, between 0 and 1.
+type BrightnessRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+func (br BrightnessRange) contains(l float64) bool {
+	return l >= br.Min && l <= br.Max
+}
+
+func (bc *BrightnessConfig) setDefaults() {
+	if bc.DayStart == 0 && bc.DayEnd == 0 {
+		bc.DayStart, bc.DayEnd = 7, 19
+	}
+	if bc.Day == (BrightnessRange{}) {
+		bc.Day = BrightnessRange{Min: 0.5, Max: 1}
+	}
+	if bc.Night == (BrightnessRange{}) {
+		bc.Night = BrightnessRange{Min: 0, Max: 0.4}
+	}
+}
+
+func (bc *BrightnessConfig) validate() error {
+	if bc.DayStart < 0 || bc.DayStart > 23 || bc.DayEnd < 0 || bc.DayEnd > 23 {
+		return fmt.Errorf("brightness day_start and day_end must be hours between 0 and 23")
+	}
+	return nil
+}
+
+// target returns the brightness range to prefer at the given time. A
+// day_start after day_end makes the day wrap around midnight.
+func (bc *BrightnessConfig) target(now time.Time) BrightnessRange {
+	h := now.Hour()
+	day := h >= bc.DayStart && h < bc.DayEnd
+	if bc.DayStart > bc.DayEnd {
+		day = h >= bc.DayStart || h < bc.DayEnd
+	}
+	if day {
+		return bc.Day
+	}
+	return bc.Night
+}
+
+// filterByBrightness returns the pictures whose average luminance falls in
+// the range configured for the given time, or all of them if none does. The
+// luminance comes from the cached, sampled averageColor.
+func filterByBrightness(bc *BrightnessConfig, pictures []string, now time.Time) []string {
+	target := bc.target(now)
+	var matching []string
+	for _, p := range pictures {
+		c, err := averageColor(p)
+		if err != nil {
+			log.Printf("Warning: cannot compute brightness of '%s': %v", p, err)
+			continue
+		}
+		if target.contains(luminance(c)) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		log.Printf("No pictures with brightness in %.2f-%.2f, using any picture", target.Min, target.Max)
+		return pictures
+	}
+	return matching
+}
@@ -0,0 +1,44 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilterByBrightness(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	bright := writeTestPicture(t, filepath.Join(dir, "bright.png"), 16, 9, color.RGBA{240, 240, 240, 255})
+	dark := writeTestPicture(t, filepath.Join(dir, "dark.png"), 16, 9, color.RGBA{10, 10, 10, 255})
+	pictures := []string{bright, dark}
+
+	for _, tc := range []struct {
+		config string
+		hour   int
+		want   string
+	}{
+		{`{"pictures_dir": "/p"}`, 12, bright},
+		{`{"pictures_dir": "/p"}`, 23, dark},
+		{`{"pictures_dir": "/p", "brightness": {"day_start": 22, "day_end": 6}}`, 23, bright},
+		{`{"pictures_dir": "/p", "brightness": {"day_start": 22, "day_end": 6}}`, 12, dark},
+	} {
+		cfg := testConfig(t, tc.config)
+		now := time.Date(2026, 1, 1, tc.hour, 0, 0, 0, time.Local)
+		got := filterByBrightness(&cfg.Brightness, pictures, now)
+		if len(got) != 1 || got[0] != tc.want {
+			t.Errorf("%s at %d:00: got %v, want %s", tc.config, tc.hour, got, tc.want)
+		}
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": "/p", "brightness": {"night": {"min": 0.5, "max": 0.6}}}`)
+	now := time.Date(2026, 1, 1, 23, 0, 0, 0, time.Local)
+	if got := filterByBrightness(&cfg.Brightness, pictures, now); len(got) != len(pictures) {
+		t.Errorf("got %v with no matching picture, want all of them", got)
+	}
+
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "brightness": {"day_start": 24}}`)); err == nil {
+		t.Error("day_start 24 accepted")
+	}
+}
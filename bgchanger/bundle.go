@@ -0,0 +1,190 @@
+package bgchanger
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kirsle/configdir"
+)
+
+// bundleConfig is the name of the config file in a bundle.
+const bundleConfig = "config.json"
+
+// bundleFiles returns the files saved by ExportBundle, by their name in the
+// bundle, for the config loaded from configFile.
+func bundleFiles(configFile string, cfg *Config) map[string]string {
+	return map[string]string{
+		bundleConfig:         configFile,
+		"ratings.json":       ratingsFile(),
+		"blocklist.txt":      blocklistFile(cfg),
+		"display_times.json": displayTimesFile(),
+		"comfort.json":       comfortFile(),
+		"last_shown.json":    lastShownFile(),
+		"state.json":         stateFile(),
+	}
+}
+
+// secretKeys are the config keys whose values are redacted on export.
+var secretKeys = map[string]bool{
+	"api_key":           true,
+	"access_key":        true,
+	"access_key_id":     true,
+	"secret_key":        true,
+	"secret_access_key": true,
+	"session_token":     true,
+	"token":             true,
+	"secret":            true,
+	"password":          true,
+}
+
+const redacted = "REDACTED"
+
+// ExportBundle writes the config loaded from configFile, and the state files
+// that exist, to a zip file. Unless secrets is true, the values of config keys
+// that are credentials are redacted.
+func ExportBundle(configFile string, cfg *Config, filename string, secrets bool) error {
+	if err := checkWrite(filename); err != nil {
+		return err
+	}
+	fd, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	zw := zip.NewWriter(fd)
+	files := bundleFiles(configFile, cfg)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			fd.Close()
+			return fmt.Errorf("failed to read '%s': %w", name, err)
+		}
+		if name == bundleConfig && !secrets {
+			if data, err = redactSecrets(data); err != nil {
+				fd.Close()
+				return fmt.Errorf("failed to redact config: %w", err)
+			}
+		}
+		w, err := zw.Create(name)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			fd.Close()
+			return fmt.Errorf("failed to write '%s' to bundle: %w", name, err)
+		}
+		log.Printf("Exported %s", name)
+	}
+	if err := zw.Close(); err != nil {
+		fd.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return fd.Close()
+}
+
+// redactSecrets replaces the values of the config keys that are credentials,
+// at any depth.
+func redactSecrets(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(redactValue(v), "", "    ")
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if secretKeys[strings.ToLower(k)] {
+				v[k] = redacted
+			} else {
+				v[k] = redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+// ImportBundle restores the files of a bundle written by ExportBundle,
+// replacing the existing ones. The config is restored to configFile, or to the
+// default config file if empty, and must be valid. The other files are
+// restored where the restored config, or else the current one, expects them.
+// Unknown files are ignored.
+func ImportBundle(configFile, filename string) error {
+	if isRemoteConfig(configFile) {
+		return fmt.Errorf("cannot import into a remote config")
+	}
+	if configFile == "" {
+		configPath := configdir.LocalConfig(Progname)
+		if err := makeDirs(configPath); err != nil {
+			return fmt.Errorf("failed to create config path '%s': %w", configPath, err)
+		}
+		configFile = path.Join(configPath, "config.json")
+	}
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer zr.Close()
+	contents := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read '%s' from bundle: %w", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read '%s' from bundle: %w", f.Name, err)
+		}
+		contents[f.Name] = data
+	}
+	cfg := &Config{}
+	if data, ok := contents[bundleConfig]; ok {
+		if cfg, err = ParseConfig(data); err != nil {
+			return fmt.Errorf("invalid config in bundle: %w", err)
+		}
+		cfg.resolvePaths(filepath.Dir(configFile))
+	} else if current, err := ReloadConfig(configFile); err == nil {
+		cfg = current
+	}
+	files := bundleFiles(configFile, cfg)
+	for _, f := range zr.File {
+		dest, ok := files[f.Name]
+		if !ok {
+			log.Printf("Warning: ignoring unknown file '%s' in bundle", f.Name)
+			continue
+		}
+		if err := checkWrite(dest); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return fmt.Errorf("failed to restore '%s': %w", f.Name, err)
+		}
+		if err := writeFileAtomic(dest, contents[f.Name]); err != nil {
+			return fmt.Errorf("failed to restore '%s': %w", f.Name, err)
+		}
+		log.Printf("Imported %s to %s", f.Name, dest)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package bgchanger
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	blocklist := filepath.Join(dir, "lists", "blocked.txt")
+	configFile := filepath.Join(dir, "bgchanger.json")
+	config := fmt.Sprintf(`{"pictures_dir": %q, "hotkey": "<Super>b", "blocklist_file": %q,
+		"sources": [{"type": "dir", "path": %q, "api_key": "s3cr3t"}]}`, dir, blocklist, dir)
+	// the state files are looked for in the current config and cache
+	// directories
+	files := map[string]func() string{
+		config: func() string { return configFile },
+		"0123456789abcdef0123456789abcdef 1.png\n": func() string { return blocklist },
+		`{"a.png": 1}`:                             ratingsFile,
+		`{"a.png": 60000000000}`:                   displayTimesFile,
+		`{"last_applied": "a.png", "focus": true}`: stateFile,
+	}
+	for data, filename := range files {
+		if err := os.MkdirAll(filepath.Dir(filename()), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename(), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "bundle.zip")
+	if err := ExportBundle(configFile, cfg, bundle, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// restore on a new machine
+	testEnv(t)
+	os.Remove(configFile)
+	os.Remove(blocklist)
+	if err := ImportBundle(configFile, bundle); err != nil {
+		t.Fatal(err)
+	}
+	for want, filename := range files {
+		data, err := os.ReadFile(filename())
+		if err != nil {
+			t.Errorf("not restored: %v", err)
+			continue
+		}
+		if want != config && string(data) != want {
+			t.Errorf("restored %s as %q, want %q", filename(), data, want)
+		}
+	}
+	restored, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Hotkey != "<Super>b" || restored.BlocklistFile != blocklist {
+		t.Errorf("restored hotkey %q and blocklist_file %q, want them unchanged", restored.Hotkey, restored.BlocklistFile)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t") || !strings.Contains(string(data), redacted) {
+		t.Errorf("the api_key was not redacted: %s", data)
+	}
+}
+
+func TestExportSecrets(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "/p", "sources": [{"type": "dir", "path": "/p", "token": "s3cr3t"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "bundle.zip")
+	if err := ExportBundle(configFile, cfg, bundle, true); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var buf bytes.Buffer
+	for _, f := range zr.File {
+		if f.Name == bundleConfig {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.ReadFrom(rc)
+			rc.Close()
+		}
+	}
+	if !strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("the token was redacted with secrets: %s", buf.String())
+	}
+}
+
+func TestImportInvalidBundle(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.zip")
+	fd, err := os.Create(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(fd)
+	for name, data := range map[string]string{bundleConfig: `{"interval": "-1h"}`, "ratings.json": "{}"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	zw.Close()
+	fd.Close()
+	configFile := filepath.Join(dir, "config.json")
+	if err := ImportBundle(configFile, bundle); err == nil {
+		t.Fatal("imported a bundle with an invalid config")
+	}
+	if _, err := os.Stat(ratingsFile()); !os.IsNotExist(err) {
+		t.Errorf("restored files from an invalid bundle: %v", err)
+	}
+	if err := ImportBundle("https://example.com/config.json", bundle); err == nil {
+		t.Error("imported into a remote config")
+	}
+}
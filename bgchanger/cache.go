@@ -0,0 +1,56 @@
+package bgchanger
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// fileCache caches values computed from files, recomputing them when the
+// file's modification time changes.
+type fileCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]fileCacheEntry[T]
+	compute func(filename string) (T, error)
+}
+
+type fileCacheEntry[T any] struct {
+	modTime time.Time
+	value   T
+}
+
+func newFileCache[T any](compute func(filename string) (T, error)) *fileCache[T] {
+	return &fileCache[T]{
+		entries: make(map[string]fileCacheEntry[T]),
+		compute: compute,
+	}
+}
+
+func (fc *fileCache[T]) get(filename string) (T, error) {
+	var zero T
+	st, err := os.Stat(filename)
+	if err != nil {
+		return zero, err
+	}
+	fc.mu.Lock()
+	e, ok := fc.entries[filename]
+	fc.mu.Unlock()
+	if ok && e.modTime.Equal(st.ModTime()) {
+		return e.value, nil
+	}
+	v, err := fc.compute(filename)
+	if err != nil {
+		return zero, err
+	}
+	fc.mu.Lock()
+	fc.entries[filename] = fileCacheEntry[T]{modTime: st.ModTime(), value: v}
+	fc.mu.Unlock()
+	return v, nil
+}
+
+// reset drops all the cached values.
+func (fc *fileCache[T]) reset() {
+	fc.mu.Lock()
+	fc.entries = make(map[string]fileCacheEntry[T])
+	fc.mu.Unlock()
+}
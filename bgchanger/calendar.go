@@ -0,0 +1,89 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// normalizeCalendarSources checks the keys of calendar_sources, which are
+// month numbers ("1" to "12") or ISO week numbers ("w1" to "w53"), and returns
+// the entries with the keys in the form calendarDir looks up, so that e.g.
+// "03" and "W03" become "3" and "w3".
+func normalizeCalendarSources(sources map[string]string) (map[string]string, error) {
+	if sources == nil {
+		return nil, nil
+	}
+	normalized := make(map[string]string, len(sources))
+	for k, dir := range sources {
+		var (
+			n      int
+			err    error
+			max    = 12
+			prefix string
+		)
+		if key := strings.ToLower(k); strings.HasPrefix(key, "w") {
+			n, err = strconv.Atoi(key[1:])
+			max, prefix = 53, "w"
+		} else {
+			n, err = strconv.Atoi(key)
+		}
+		if err != nil || n < 1 || n > max {
+			return nil, fmt.Errorf("invalid calendar_sources key '%s', want a month like \"3\" or a week like \"w12\"", k)
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("calendar_sources entry '%s' has an empty directory", k)
+		}
+		key := prefix + strconv.Itoa(n)
+		if _, ok := normalized[key]; ok {
+			return nil, fmt.Errorf("calendar_sources has more than one entry for '%s'", key)
+		}
+		normalized[key] = dir
+	}
+	return normalized, nil
+}
+
+// calendarDir returns the directory configured for the given date, if any.
+// Week entries take precedence over month entries.
+func calendarDir(sources map[string]string, now time.Time) (string, bool) {
+	_, week := now.ISOWeek()
+	if dir, ok := sources[fmt.Sprintf("w%d", week)]; ok {
+		return dir, true
+	}
+	dir, ok := sources[strconv.Itoa(int(now.Month()))]
+	return dir, ok
+}
+
+// CalendarTimer returns a channel that fires at the next midnight, when
+// ApplyCalendarChange should be called.
+func CalendarTimer() <-chan time.Time {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	return time.After(midnight.Sub(now))
+}
+
+// ApplyCalendarChange recomputes the active sources at midnight, and changes
+// the background if calendar_sources maps the new day to another directory
+// than the previous one. It reports whether it did, in which case the change
+// timer has to be restarted.
+func ApplyCalendarChange(cfg *Config) bool {
+	return applyCalendarChange(cfg, time.Now())
+}
+
+func applyCalendarChange(cfg *Config, now time.Time) bool {
+	dir, ok := calendarDir(cfg.CalendarSources, now)
+	prev, prevOK := calendarDir(cfg.CalendarSources, now.AddDate(0, 0, -1))
+	if dir == prev && ok == prevOK {
+		return false
+	}
+	if ok {
+		log.Printf("Calendar source changed to '%s'", dir)
+	} else {
+		log.Printf("No calendar source for today, using the other sources")
+	}
+	invalidatePreload()
+	AutoChangeBG(cfg)
+	return true
+}
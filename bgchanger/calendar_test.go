@@ -0,0 +1,74 @@
+package bgchanger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalendarDir(t *testing.T) {
+	cfg := testConfig(t, `{"pictures_dir": "/p", "calendar_sources": {
+		"03": "spring", "12": "winter", "W33": "holidays"
+	}}`)
+	for _, tc := range []struct {
+		date time.Time
+		want string
+	}{
+		{time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local), "/spring"},
+		{time.Date(2026, 12, 1, 12, 0, 0, 0, time.Local), "/winter"},
+		// in ISO week 33
+		{time.Date(2026, 8, 12, 12, 0, 0, 0, time.Local), "/holidays"},
+	} {
+		dir, ok := calendarDir(cfg.CalendarSources, tc.date)
+		if !ok || filepath.Base(dir) != filepath.Base(tc.want) {
+			t.Errorf("got %q, %v on %s, want %s", dir, ok, tc.date.Format("2006-01-02"), tc.want)
+		}
+	}
+	if dir, ok := calendarDir(cfg.CalendarSources, time.Date(2026, 7, 1, 12, 0, 0, 0, time.Local)); ok {
+		t.Errorf("got %s in July, want no calendar source", dir)
+	}
+
+	for _, config := range []string{
+		`{"pictures_dir": "/p", "calendar_sources": {"13": "x"}}`,
+		`{"pictures_dir": "/p", "calendar_sources": {"w54": "x"}}`,
+		`{"pictures_dir": "/p", "calendar_sources": {"march": "x"}}`,
+		`{"pictures_dir": "/p", "calendar_sources": {"3": "x", "03": "y"}}`,
+		`{"pictures_dir": "/p", "calendar_sources": {"3": ""}}`,
+	} {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Errorf("%s accepted", config)
+		}
+	}
+}
+
+func TestCalendarSourceFollowsDate(t *testing.T) {
+	setter := testEnv(t)
+	fallback, march, april := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestPictures(t, fallback, 2)
+	writeTestPictures(t, march, 2)
+	writeTestPictures(t, april, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "calendar_sources": {"3": %q, "4": %q}}`, fallback, march, april)
+
+	if applyCalendarChange(cfg, time.Date(2026, 3, 15, 0, 0, 1, 0, time.Local)) {
+		t.Error("changed at midnight within the same month")
+	}
+	if !applyCalendarChange(cfg, time.Date(2026, 4, 1, 0, 0, 1, 0, time.Local)) {
+		t.Error("not changed at the midnight starting April")
+	}
+	if n := len(setter.values("picture-uri")); n != 1 {
+		t.Errorf("got %d changes, want 1", n)
+	}
+	// the current month is not faked when picking
+	now := time.Now()
+	want := map[time.Month]string{time.March: march, time.April: april}[now.Month()]
+	if want == "" {
+		want = fallback
+	}
+	p, err := PickPicture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(p) != want {
+		t.Errorf("picked %s in %s, want a picture from %s", p, now.Month(), want)
+	}
+}
@@ -0,0 +1,180 @@
+package bgchanger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+)
+
+// caption text sources
+const (
+	captionFilename  = "filename"
+	captionExifTitle = "exif_title"
+	captionTemplate  = "template"
+)
+
+// caption positions
+const (
+	captionTopLeft     = "top_left"
+	captionTopRight    = "top_right"
+	captionBottomLeft  = "bottom_left"
+	captionBottomRight = "bottom_right"
+)
+
+const (
+	defaultCaptionFontSize = 24
+	defaultCaptionOpacity  = 0.8
+)
+
+// CaptionConfig configures the caption step, which writes the name or the
+// title of the picture in a corner of it.
+type CaptionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Source is where the text comes from: filename, exif_title, which falls
+	// back to the file name for pictures without one, or template.
+	Source string `json:"source"`
+	// Template is the text with the template source, in which {name} is
+	// replaced with the file name and {title} with the EXIF title.
+	Template string `json:"template"`
+	Position string `json:"position"`
+	// FontSize is the height of the text in pixels.
+	FontSize int     `json:"font_size"`
+	Opacity  float64 `json:"opacity"`
+}
+
+func (cc *CaptionConfig) validate() error {
+	switch cc.Source {
+	case "", captionFilename, captionExifTitle:
+	case captionTemplate:
+		if cc.Template == "" {
+			return fmt.Errorf("caption template cannot be empty with the template source")
+		}
+	default:
+		return fmt.Errorf("unknown caption source '%s'", cc.Source)
+	}
+	switch cc.Position {
+	case "", captionTopLeft, captionTopRight, captionBottomLeft, captionBottomRight:
+	default:
+		return fmt.Errorf("unknown caption position '%s'", cc.Position)
+	}
+	if cc.FontSize < 0 {
+		return fmt.Errorf("caption font_size cannot be negative")
+	}
+	if cc.Opacity < 0 || cc.Opacity > 1 {
+		return fmt.Errorf("invalid caption opacity %v, want 0 to 1", cc.Opacity)
+	}
+	return nil
+}
+
+// text returns the caption of the picture at filename.
+func (cc *CaptionConfig) text(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	switch cc.Source {
+	case captionExifTitle, captionTemplate:
+		title, err := readExifTitle(filename)
+		if err != nil {
+			debugf("cannot read EXIF title of '%s': %v", filename, err)
+		}
+		if cc.Source == captionTemplate {
+			return strings.NewReplacer("{name}", name, "{title}", title).Replace(cc.Template)
+		}
+		if title != "" {
+			return title
+		}
+	}
+	return name
+}
+
+// drawCaption draws text at the given position of img, in white with a dark
+// shadow so that it is legible on any picture. The default position is the
+// bottom right corner.
+func drawCaption(img image.Image, text, position string, fontSize int, opacity float64) *image.RGBA {
+	if position == "" {
+		position = captionBottomRight
+	}
+	out := toRGBA(img)
+	b := out.Bounds()
+	scale := fontSize / (glyphHeight + 1)
+	if scale < 1 {
+		scale = 1
+	}
+	w, h := textWidth(text, scale), glyphHeight*scale
+	margin := fontSize
+	x, y := b.Min.X+margin, b.Min.Y+margin
+	if position == captionTopRight || position == captionBottomRight {
+		x = b.Max.X - margin - w
+	}
+	if position == captionBottomLeft || position == captionBottomRight {
+		y = b.Max.Y - margin - h
+	}
+	shadow := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	drawText(out, text, x+scale, y+scale, scale, shadow, opacity)
+	drawText(out, text, x, y, scale, white, opacity)
+	return out
+}
+
+// drawText blends text onto img with its top left corner at x, y, each font
+// pixel being a scale by scale square.
+func drawText(img *image.RGBA, text string, x, y, scale int, c color.RGBA, opacity float64) {
+	blend := func(v, to uint8) uint8 {
+		return uint8(float64(v)*(1-opacity) + float64(to)*opacity + 0.5)
+	}
+	b := img.Bounds()
+	for _, r := range text {
+		g := glyph(r)
+		for gx := 0; gx < glyphWidth; gx++ {
+			for gy := 0; gy < glyphHeight; gy++ {
+				if !glyphPixel(g, gx, gy) {
+					continue
+				}
+				for py := y + gy*scale; py < y+(gy+1)*scale; py++ {
+					for px := x + gx*scale; px < x+(gx+1)*scale; px++ {
+						if !(image.Point{px, py}.In(b)) {
+							continue
+						}
+						p := img.RGBAAt(px, py)
+						img.SetRGBA(px, py, color.RGBA{R: blend(p.R, c.R), G: blend(p.G, c.G), B: blend(p.B, c.B), A: p.A})
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
+
+// caption writes the caption of original, the picture as found in the
+// sources, onto filename, the picture after the previous steps, and returns
+// the path of the cached result.
+func caption(cfg *Config, original, filename string) (string, error) {
+	cc := &cfg.Caption
+	text := cc.text(original)
+	if text == "" {
+		return filename, nil
+	}
+	fontSize := cc.FontSize
+	if fontSize == 0 {
+		fontSize = defaultCaptionFontSize
+	}
+	opacity := cc.Opacity
+	if opacity == 0 {
+		opacity = defaultCaptionOpacity
+	}
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d|%v", text, cc.Position, fontSize, opacity)))
+	cached, ok, err := cachedVariant("captioned", filename, "caption"+hex.EncodeToString(sum[:4]))
+	if err != nil || ok {
+		return cached, err
+	}
+	img, err := loadImage(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := writeJPEG(cached, drawCaption(img, text, cc.Position, fontSize, opacity)); err != nil {
+		return "", fmt.Errorf("failed to write captioned picture: %w", err)
+	}
+	return cached, nil
+}
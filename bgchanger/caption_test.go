@@ -0,0 +1,136 @@
+package bgchanger
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// changedPixels counts the pixels of r that differ in a and b by more than
+// the JPEG noise.
+func changedPixels(a, b image.Image, r image.Rectangle) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ar, ag, ab, _ := a.At(x, y).RGBA()
+			br, bg, bb, _ := b.At(x, y).RGBA()
+			d := int(ar>>8) - int(br>>8) + int(ag>>8) - int(bg>>8) + int(ab>>8) - int(bb>>8)
+			if d > 48 || d < -48 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestDrawCaption(t *testing.T) {
+	// not an RGBA, which drawCaption would draw onto
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	for i := range src.Pix {
+		src.Pix[i] = 64
+		if i%4 == 3 {
+			src.Pix[i] = 255
+		}
+	}
+	for _, tc := range []struct {
+		position string
+		corner   image.Rectangle
+	}{
+		{"", image.Rect(200, 100, 400, 200)},
+		{captionBottomRight, image.Rect(200, 100, 400, 200)},
+		{captionTopLeft, image.Rect(0, 0, 200, 100)},
+		{captionTopRight, image.Rect(200, 0, 400, 100)},
+		{captionBottomLeft, image.Rect(0, 100, 200, 200)},
+	} {
+		out := drawCaption(src, "hello", tc.position, 24, 1)
+		if n := changedPixels(src, out, tc.corner); n == 0 {
+			t.Errorf("%q: the caption is not in %v", tc.position, tc.corner)
+		}
+		if n := changedPixels(src, out, out.Bounds()); n != changedPixels(src, out, tc.corner) {
+			t.Errorf("%q: the caption is outside of %v", tc.position, tc.corner)
+		}
+	}
+}
+
+func TestCaptionText(t *testing.T) {
+	dir := t.TempDir()
+	plain := writeTestPicture(t, filepath.Join(dir, "plain.png"), 4, 4, color.RGBA{A: 255})
+	titled := writeExifJPEG(t, filepath.Join(dir, "titled.jpg"), map[uint16]string{exifTagImageDescription: "Sunset"})
+	for _, tc := range []struct {
+		cc       CaptionConfig
+		filename string
+		want     string
+	}{
+		{CaptionConfig{}, plain, "plain"},
+		{CaptionConfig{Source: captionFilename}, titled, "titled"},
+		{CaptionConfig{Source: captionExifTitle}, titled, "Sunset"},
+		{CaptionConfig{Source: captionExifTitle}, plain, "plain"},
+		{CaptionConfig{Source: captionTemplate, Template: "{title} ({name})"}, titled, "Sunset (titled)"},
+	} {
+		if got := tc.cc.text(tc.filename); got != tc.want {
+			t.Errorf("%+v on %s: got %q, want %q", tc.cc, filepath.Base(tc.filename), got, tc.want)
+		}
+	}
+}
+
+func TestCaption(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	p := writeTestPicture(t, filepath.Join(dir, "a.png"), 400, 200, color.RGBA{R: 64, G: 64, B: 64, A: 255})
+
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if got := preparePicture(cfg, p); got != p {
+		t.Errorf("got %s without a caption, want the picture itself", got)
+	}
+
+	cfg = testConfig(t, `{"pictures_dir": %q, "caption": {"enabled": true, "position": "top_left", "opacity": 1}}`, dir)
+	ChangeBG(cfg)
+	uri := setter.get("picture-uri")
+	captioned := strings.TrimPrefix(uri, "file://")
+	if captioned == p || !strings.HasPrefix(captioned, filepath.Join(os.Getenv("XDG_CACHE_HOME"), Progname, "captioned")) {
+		t.Fatalf("got picture-uri %s, want the cached captioned copy", uri)
+	}
+	src, err := loadImage(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := loadImage(captioned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := changedPixels(src, out, image.Rect(0, 0, 200, 100)); n == 0 {
+		t.Error("the top left corner of the captioned picture is unchanged")
+	}
+	if n := changedPixels(src, out, image.Rect(0, 100, 400, 200)); n != 0 {
+		t.Errorf("%d pixels changed outside of the top left corner", n)
+	}
+
+	// the cached copy is reused, and written without leftover temporary files
+	if got := preparePicture(cfg, p); got != captioned {
+		t.Errorf("got %s, want the cached %s", got, captioned)
+	}
+	entries, err := os.ReadDir(filepath.Dir(captioned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files in the caption cache, want 1", len(entries))
+	}
+}
+
+func TestCaptionValidate(t *testing.T) {
+	for _, c := range []string{
+		`{"pictures_dir": "/p", "caption": {"source": "nope"}}`,
+		`{"pictures_dir": "/p", "caption": {"source": "template"}}`,
+		`{"pictures_dir": "/p", "caption": {"position": "middle"}}`,
+		`{"pictures_dir": "/p", "caption": {"font_size": -1}}`,
+		`{"pictures_dir": "/p", "caption": {"opacity": 2}}`,
+	} {
+		if _, err := ParseConfig([]byte(c)); err == nil {
+			t.Errorf("%s: expected an error", c)
+		}
+	}
+}
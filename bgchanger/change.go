@@ -0,0 +1,216 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChangeBG changes the background to a randomly picked picture, recording
+// the outcome for the tray and the status.
+func ChangeBG(cfg *Config) {
+	err := changeBackground(cfg)
+	if err != nil {
+		log.Printf("Error: %v", err)
+	}
+	setLastError(err)
+	recordOutcome(cfg, err)
+}
+
+func changeBackground(cfg *Config) error {
+	if cfg.Mode == modeGradient {
+		if err := setGradient(cfg); err != nil {
+			return fmt.Errorf("failed to change background: %w", err)
+		}
+		return nil
+	}
+	if ComfortEnabled() {
+		if err := SetBackground(cfg, comfortPicture(cfg)); err != nil {
+			return fmt.Errorf("failed to change background: %w", err)
+		}
+		return nil
+	}
+	// the queued pictures come before any other pick
+	if picture, ok := popQueue(cfg); ok {
+		if err := SetBackground(cfg, picture); err != nil {
+			return fmt.Errorf("failed to change background: %w", err)
+		}
+		return nil
+	}
+	if cfg.Playlist != "" {
+		if picture, ok := nextPlaylistPicture(cfg); ok && picture != "" {
+			if err := SetBackground(cfg, picture); err != nil {
+				return fmt.Errorf("failed to change background: %w", err)
+			}
+			return nil
+		}
+	}
+	// there is a preloaded picture with preload, or after a preview
+	filename, src, ok := takePreloaded(cfg)
+	if !ok {
+		var err error
+		if filename, src, err = pickPicture(cfg, changeExclusions(cfg)); err != nil {
+			if noPicturesAnywhere(cfg) {
+				return applyNoPictures(cfg)
+			}
+			return fmt.Errorf("cannot get random picture: %w", err)
+		}
+	}
+	hideFallbackColor(cfg)
+	if err := SetBackground(cfg, filename); err != nil {
+		return fmt.Errorf("failed to change background: %w", err)
+	}
+	setActiveSource(cfg, src)
+	updateCandidateCount(cfg)
+	if cfg.Preload {
+		preloadNext(cfg)
+	}
+	if cfg.ChangeLockscreen && cfg.LockscreenIndependent {
+		lockFilename, err := PickPicture(cfg, filename)
+		if err != nil {
+			return fmt.Errorf("cannot get random picture for the lock screen: %w", err)
+		}
+		if err := setLockscreen(preparePicture(cfg, lockFilename)); err != nil {
+			return fmt.Errorf("failed to change lock screen background: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLockscreen applies the given picture as the lock screen background.
+func setLockscreen(filename string) error {
+	if err := gsettingsSet(screensaverSchema, "picture-uri", "file://"+filename); err != nil {
+		return err
+	}
+	log.Printf("Lock screen background changed to '%s'", filename)
+	return nil
+}
+
+// SetBackground applies the given picture as the desktop background, and as
+// the lock screen background if configured to follow the desktop.
+func SetBackground(cfg *Config, filename string) error {
+	if err := fetchRemote(filename); err != nil {
+		return err
+	}
+	if isVideo(filename) {
+		if err := playVideo(cfg, filename); err != nil {
+			return err
+		}
+		logWith(logFields{"path": filename}, "Background changed to video '%s'", filename)
+		setCurrent(cfg, filename)
+		updateCurrentSymlink(cfg, filename)
+		if cfg.PostChangeHook != "" {
+			startPostChangeHook(cfg, filename)
+		}
+		return nil
+	}
+	StopVideo()
+	previous := CurrentPicture()
+	if from, flipping := flipFade(false); flipping {
+		// switching to light mode, from the dark mode picture
+		previous = from
+	}
+	if cfg.Transition == transitionFade && previous != "" && previous != filename && !isVideo(previous) {
+		playFade(cfg, "picture-uri", previous, filename)
+	}
+	applied := preparePicture(cfg, filename)
+	spanned := false
+	if cfg.PerMonitor {
+		if p, err := spanMonitors(cfg, filename, applied); err != nil {
+			log.Printf("Warning: cannot span the displays: %v", err)
+		} else {
+			spanned = p != applied
+			applied = p
+		}
+	}
+	if err := gsettingsSet(backgroundSchema, "picture-uri", "file://"+applied); err != nil {
+		return err
+	}
+	options, setOptions := orientationOptions(cfg, filename)
+	if spanned {
+		options, setOptions = "spanned", true
+	} else if cfg.PerMonitor && !setOptions {
+		options, setOptions = defaultPictureOptions, true
+	}
+	if setOptions {
+		savePictureOptions()
+		if err := gsettingsSet(backgroundSchema, "picture-options", options); err != nil {
+			log.Printf("Error: failed to set picture-options: %v", err)
+		}
+	}
+	logWith(logFields{"path": filename, "applied": applied}, "Background changed to '%s'", filename)
+	setCurrent(cfg, filename)
+	updateCurrentSymlink(cfg, applied)
+	setDarkPicture(cfg, filename, applied)
+	if cfg.SyncAccentColor {
+		syncAccentColor(filename)
+	}
+	if cfg.ChangeLockscreen && !cfg.LockscreenIndependent {
+		if err := setLockscreen(applied); err != nil {
+			log.Printf("Error when changing lock screen background: %v", err)
+		}
+	}
+	if cfg.PostChangeHook != "" {
+		startPostChangeHook(cfg, filename)
+	}
+	return nil
+}
+
+// ApplyIndex applies the picture at the given zero-based index in the sorted
+// candidate list, as printed by -list.
+func ApplyIndex(cfg *Config, index int) error {
+	pictures, err := indexedCandidates(cfg)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(pictures) {
+		return fmt.Errorf("index %d out of range, there are %d candidates", index, len(pictures))
+	}
+	return SetBackground(cfg, pictures[index])
+}
+
+// ApplyName applies the picture with the given file name among the
+// candidates, or at the given absolute path.
+func ApplyName(cfg *Config, name string) error {
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err != nil {
+			return fmt.Errorf("cannot use '%s': %w", name, err)
+		}
+		return SetBackground(cfg, name)
+	}
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		return err
+	}
+	var matches []string
+	for _, p := range pictures {
+		if filepath.Base(p) == name || strings.HasSuffix(p, string(filepath.Separator)+name) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("no candidate named '%s'", name)
+	case 1:
+		return SetBackground(cfg, matches[0])
+	default:
+		return fmt.Errorf("'%s' is ambiguous, it matches %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// RateCurrent adds delta to the rating of the current picture.
+func RateCurrent(delta int) {
+	filename := CurrentPicture()
+	if filename == "" {
+		log.Printf("No background was set yet, nothing to rate")
+		return
+	}
+	if err := ratePicture(filename, delta); err != nil {
+		log.Printf("Error: %v", err)
+	}
+	if delta > 0 {
+		recordLiked(filename)
+	}
+}
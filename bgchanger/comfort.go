@@ -0,0 +1,117 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/kirsle/configdir"
+)
+
+// comfort is the state of the comfort mode, which keeps a single favorite
+// picture as the background until turned off. It is saved across restarts.
+var comfort = struct {
+	sync.Mutex
+	loaded bool
+	comfortState
+}{}
+
+type comfortState struct {
+	Enabled bool `json:"enabled"`
+	// LastLiked is the picture most recently liked, used when
+	// comfort_picture is not configured.
+	LastLiked string `json:"last_liked"`
+}
+
+func comfortFile() string {
+	return path.Join(configdir.LocalConfig(Progname), "comfort.json")
+}
+
+// loadComfortLocked reads the comfort mode state, if not done already. The
+// comfort lock must be held.
+func loadComfortLocked() {
+	if comfort.loaded {
+		return
+	}
+	comfort.loaded = true
+	data, err := os.ReadFile(comfortFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read comfort mode state: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &comfort.comfortState); err != nil {
+		log.Printf("Warning: cannot parse comfort mode state: %v", err)
+	}
+}
+
+// saveComfortLocked saves the comfort mode state. The comfort lock must be
+// held.
+func saveComfortLocked() error {
+	data, err := json.MarshalIndent(comfort.comfortState, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal comfort mode state: %w", err)
+	}
+	if err := writeFileAtomic(comfortFile(), data); err != nil {
+		return fmt.Errorf("failed to save comfort mode state: %w", err)
+	}
+	return nil
+}
+
+// ComfortEnabled reports whether the comfort mode is on.
+func ComfortEnabled() bool {
+	comfort.Lock()
+	defer comfort.Unlock()
+	loadComfortLocked()
+	return comfort.Enabled
+}
+
+// comfortPicture returns the picture shown in comfort mode, which is
+// comfort_picture if set, or else the picture most recently liked.
+func comfortPicture(cfg *Config) string {
+	if cfg.ComfortPicture != "" {
+		return cfg.ComfortPicture
+	}
+	comfort.Lock()
+	defer comfort.Unlock()
+	loadComfortLocked()
+	return comfort.LastLiked
+}
+
+// recordLiked remembers the picture as the last liked one.
+func recordLiked(filename string) {
+	comfort.Lock()
+	defer comfort.Unlock()
+	loadComfortLocked()
+	comfort.LastLiked = filename
+	if err := saveComfortLocked(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// SetComfort turns the comfort mode on, showing the comfort picture, or off,
+// going back to a freshly picked picture.
+func SetComfort(cfg *Config, enabled bool) error {
+	if enabled && comfortPicture(cfg) == "" {
+		return fmt.Errorf("no comfort picture, set comfort_picture or like a picture first")
+	}
+	comfort.Lock()
+	loadComfortLocked()
+	comfort.Enabled = enabled
+	err := saveComfortLocked()
+	comfort.Unlock()
+	if err != nil {
+		return err
+	}
+	if enabled {
+		log.Printf("Comfort mode on")
+	} else {
+		log.Printf("Comfort mode off")
+	}
+	ChangeBG(cfg)
+	return nil
+}
@@ -0,0 +1,66 @@
+package bgchanger
+
+import "testing"
+
+func TestComfortMode(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 4)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := SetComfort(cfg, true); err == nil {
+		t.Fatal("comfort mode turned on without a comfort picture")
+	}
+
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	RateCurrent(1)
+	if err := SetBackground(cfg, pictures[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComfort(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	if cur := CurrentPicture(); cur != pictures[0] {
+		t.Fatalf("showing %s in comfort mode, want the last liked %s", cur, pictures[0])
+	}
+	// the automatic changes are paused, and the explicit ones keep the
+	// comfort picture
+	AutoChangeBG(cfg)
+	ChangeBG(cfg)
+	if cur := CurrentPicture(); cur != pictures[0] {
+		t.Errorf("changed to %s in comfort mode", cur)
+	}
+
+	// the comfort mode is restored after a restart
+	resetState()
+	if !ComfortEnabled() || comfortPicture(cfg) != pictures[0] {
+		t.Errorf("comfort mode %v with %s after a restart, want on with %s", ComfortEnabled(), comfortPicture(cfg), pictures[0])
+	}
+
+	if err := SetComfort(cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	changed := false
+	for i := 0; i < 20 && !changed; i++ {
+		AutoChangeBG(cfg)
+		changed = CurrentPicture() != pictures[0]
+	}
+	if !changed {
+		t.Error("the rotation did not resume after comfort mode")
+	}
+}
+
+func TestComfortPictureConfig(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "comfort_picture": %q}`, dir, pictures[1])
+	recordLiked(pictures[0])
+	if err := SetComfort(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	if cur := CurrentPicture(); cur != pictures[1] {
+		t.Errorf("showing %s, want comfort_picture %s", cur, pictures[1])
+	}
+}
@@ -0,0 +1,67 @@
+package bgchanger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCommandTimeout = 5 * time.Second
+
+// commandTimeout bounds how long external commands like gsettings or xrandr
+// can run, so that a stalled tool cannot freeze the change loop. It is a
+// time.Duration accessed atomically, as reloading the config changes it
+// while commands run on other goroutines.
+var commandTimeout = int64(defaultCommandTimeout)
+
+// setCommandTimeout sets the timeout of external commands, using the default
+// if d is not positive.
+func setCommandTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultCommandTimeout
+	}
+	atomic.StoreInt64(&commandTimeout, int64(d))
+}
+
+func getCommandTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&commandTimeout))
+}
+
+// newCommand returns a command that is killed after commandTimeout, and a
+// function to release its resources.
+func newCommand(name string, args ...string) (*exec.Cmd, context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), getCommandTimeout())
+	return exec.CommandContext(ctx, name, args...), ctx, cancel
+}
+
+// timeoutError replaces the error of a command that was killed because it
+// timed out with a clearer one.
+func timeoutError(ctx context.Context, name string, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("Warning: '%s' timed out after %s, killed", name, getCommandTimeout())
+		return fmt.Errorf("'%s' timed out after %s", name, getCommandTimeout())
+	}
+	return err
+}
+
+// runCommand runs an external command, forwarding its output to ours. It is a
+// variable so that the way commands are run can be swapped out.
+var runCommand = func(name string, args ...string) error {
+	cmd, ctx, cancel := newCommand(name, args...)
+	defer cancel()
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return timeoutError(ctx, name, cmd.Run())
+}
+
+// commandOutput runs an external command and returns its standard output.
+var commandOutput = func(name string, args ...string) ([]byte, error) {
+	cmd, ctx, cancel := newCommand(name, args...)
+	defer cancel()
+	out, err := cmd.Output()
+	return out, timeoutError(ctx, name, err)
+}
@@ -0,0 +1,31 @@
+package bgchanger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandTimeout(t *testing.T) {
+	setCommandTimeout(100 * time.Millisecond)
+	defer setCommandTimeout(0)
+	start := time.Now()
+	err := runCommand("sleep", "10")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("the command was killed after %s", d)
+	}
+	if _, err := commandOutput("sleep", "10"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v from commandOutput, want a timeout", err)
+	}
+	if out, err := commandOutput("echo", "hello"); err != nil || string(out) != "hello\n" {
+		t.Errorf("got %q, %v, want the output of a quick command", out, err)
+	}
+
+	setCommandTimeout(-time.Second)
+	if d := getCommandTimeout(); d != defaultCommandTimeout {
+		t.Errorf("got timeout %s for a negative one, want the default %s", d, defaultCommandTimeout)
+	}
+}
@@ -0,0 +1,470 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+	"github.com/kirsle/configdir"
+)
+
+// Progname is the name of the program, used for its config and cache
+// directories.
+const Progname = "bgchanger"
+
+// Config contains the program's configuration.
+type Config struct {
+	PicturesDir           string              `json:"pictures_dir"`
+	DarkPicturesDir       string              `json:"dark_pictures_dir"`
+	DarkVariant           string              `json:"dark_variant"`
+	DarkenAmount          float64             `json:"darken_amount"`
+	DifferByTheme         bool                `json:"differ_by_theme"`
+	FollowColorScheme     bool                `json:"follow_color_scheme"`
+	Interval              xjson.Duration      `json:"interval"`
+	ManualHold            xjson.Duration      `json:"manual_hold"`
+	IntervalClickAction   string              `json:"interval_click_action"`
+	TrayClickAction       string              `json:"tray_click_action"`
+	AdaptiveInterval      AdaptiveConfig      `json:"adaptive_interval"`
+	Editor                string              `json:"editor"`
+	IconPath              string              `json:"icon_path"`
+	CurrentSymlink        string              `json:"current_symlink"`
+	RemoveSymlinkOnExit   bool                `json:"remove_symlink_on_exit"`
+	ConfirmDestructive    *bool               `json:"confirm_destructive"`
+	ChangeOnStart         bool                `json:"change_on_start"`
+	Hotkey                string              `json:"hotkey"`
+	CheckUpdates          bool                `json:"check_updates"`
+	ChangeLockscreen      bool                `json:"change_lockscreen"`
+	LockscreenIndependent bool                `json:"lockscreen_independent"`
+	Sources               []SourceConfig      `json:"sources"`
+	CalendarSources       map[string]string   `json:"calendar_sources"`
+	TimeSources           []TimePeriod        `json:"time_sources"`
+	NetworkSources        []NetworkSource     `json:"network_sources"`
+	FailureAlertAfter     int                 `json:"failure_alert_after"`
+	PauseOnFailures       bool                `json:"pause_on_failures"`
+	MaxCandidates         int                 `json:"max_candidates"`
+	LargeDirectory        int                 `json:"large_directory"`
+	SupportedExtensions   []string            `json:"supported_extensions"`
+	ScanRetries           *int                `json:"scan_retries"`
+	ScanRetryDelay        xjson.Duration      `json:"scan_retry_delay"`
+	MaxAge                xjson.Duration      `json:"max_age"`
+	MinAge                xjson.Duration      `json:"min_age"`
+	AgeFallback           *bool               `json:"age_fallback"`
+	Dedup                 bool                `json:"dedup"`
+	Watch                 bool                `json:"watch"`
+	WatchGrace            xjson.Duration      `json:"watch_grace"`
+	BlocklistFile         string              `json:"blocklist_file"`
+	RejectsDir            string              `json:"rejects_dir"`
+	ExtensionWeights      map[string]float64  `json:"extension_weights"`
+	PerImageCooldown      xjson.Duration      `json:"per_image_cooldown"`
+	Playlist              string              `json:"playlist"`
+	PersistQueue          bool                `json:"persist_queue"`
+	ComfortPicture        string              `json:"comfort_picture"`
+	FocusPicture          string              `json:"focus_picture"`
+	FocusColor            string              `json:"focus_color"`
+	FallbackColor         string              `json:"fallback_color"`
+	AspectRatio           string              `json:"aspect_ratio"`
+	AspectRatioTolerance  float64             `json:"aspect_ratio_tolerance"`
+	Mode                  string              `json:"mode"`
+	DailySalt             string              `json:"daily_salt"`
+	Brightness            BrightnessConfig    `json:"brightness"`
+	Hemisphere            string              `json:"hemisphere"`
+	TargetColor           string              `json:"target_color"`
+	SyncAccentColor       bool                `json:"sync_accent_color"`
+	SkipWhenFullscreen    bool                `json:"skip_when_fullscreen"`
+	QuietHours            []QuietWindow       `json:"quiet_hours"`
+	MaxDimension          int                 `json:"max_dimension"`
+	Prescale              bool                `json:"prescale"`
+	PerMonitor            bool                `json:"per_monitor"`
+	PictureOptions        string              `json:"picture_options"`
+	LandscapeOptions      string              `json:"landscape_options"`
+	PortraitOptions       string              `json:"portrait_options"`
+	PrescaleMode          string              `json:"prescale_mode"`
+	Tint                  TintConfig          `json:"tint"`
+	Caption               CaptionConfig       `json:"caption"`
+	Preload               bool                `json:"preload"`
+	PrimaryColor          string              `json:"primary_color"`
+	SecondaryColor        string              `json:"secondary_color"`
+	ColorShadingType      string              `json:"color_shading_type"`
+	Transition            string              `json:"transition"`
+	TransitionDuration    xjson.Duration      `json:"transition_duration"`
+	TransitionFrames      int                 `json:"transition_frames"`
+	ControlAddr           string              `json:"control_addr"`
+	VideoBackend          string              `json:"video_backend"`
+	PostChangeHook        string              `json:"post_change_hook"`
+	PreChangeHook         string              `json:"pre_change_hook"`
+	HookRetries           *int                `json:"pre_change_hook_retries"`
+	HookTimeout           xjson.Duration      `json:"hook_timeout"`
+	CommandTimeout        xjson.Duration      `json:"command_timeout"`
+	LogFormat             string              `json:"log_format"`
+	WriteAllowlist        []string            `json:"write_allowlist"`
+	StartupDelay          *xjson.Duration     `json:"startup_delay"`
+	SelectionLog          string              `json:"selection_log"`
+	Accessibility         AccessibilityConfig `json:"accessibility"`
+
+	// Profile names the profile to use, among Profiles. Each profile holds
+	// settings that replace the ones above when active.
+	Profile  string                     `json:"profile"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+// LoadConfig loads the config file from the user config directory, creating it
+// with the editor if it does not exist. It returns the config file path and
+// the parsed config.
+func LoadConfig() (string, *Config, error) {
+	cfg := Config{}
+
+	configPath := configdir.LocalConfig(Progname)
+	configFile := path.Join(configPath, "config.json")
+	log.Printf("Trying to load config file %s", configFile)
+	if err := makeDirs(configPath); err != nil {
+		if os.IsNotExist(err) {
+			return configFile, &cfg, nil
+		}
+		return configFile, nil, fmt.Errorf("failed to create config path '%s': %w", configPath, err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// create a template file and open it with the default editor
+			if err := openEditor(configFile); err != nil {
+				return configFile, &cfg, fmt.Errorf("failed to create config file: %w", err)
+			}
+			// re-read the config file
+			data, err = os.ReadFile(configFile)
+			if err != nil {
+				return configFile, &cfg, fmt.Errorf("failed to read config file: %w", err)
+			}
+			// after this point, the newly created config file will be parsed by
+			// the rest of this function.
+		} else {
+			return configFile, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	parsed, err := ParseConfig(data)
+	if err != nil {
+		return configFile, nil, err
+	}
+	parsed.resolvePaths(filepath.Dir(configFile))
+	setCommandTimeout(time.Duration(parsed.CommandTimeout))
+	setWriteAllowlist(parsed.WriteAllowlist)
+	setSupportedExtensions(parsed.SupportedExtensions)
+	setLargeDirectory(parsed.LargeDirectory)
+	setLogFormat(parsed.LogFormat)
+	return configFile, parsed, nil
+}
+
+// ReloadConfig re-reads an existing config file.
+func ReloadConfig(configFile string) (*Config, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.resolvePaths(filepath.Dir(configFile))
+	return cfg, nil
+}
+
+// resolvePaths makes the relative paths in the config relative to baseDir,
+// the directory of the config file, so that a config can be moved together
+// with its pictures.
+func (cfg *Config) resolvePaths(baseDir string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(baseDir, *p)
+		}
+	}
+	resolve(&cfg.PicturesDir)
+	resolve(&cfg.DarkPicturesDir)
+	resolve(&cfg.BlocklistFile)
+	resolve(&cfg.IconPath)
+	resolve(&cfg.ComfortPicture)
+	resolve(&cfg.FocusPicture)
+	resolve(&cfg.SelectionLog)
+	resolve(&cfg.Playlist)
+	resolve(&cfg.CurrentSymlink)
+	for i := range cfg.WriteAllowlist {
+		resolve(&cfg.WriteAllowlist[i])
+	}
+	for i := range cfg.Sources {
+		resolve(&cfg.Sources[i].Path)
+	}
+	for i := range cfg.TimeSources {
+		for j := range cfg.TimeSources[i].Sources {
+			resolve(&cfg.TimeSources[i].Sources[j].Path)
+		}
+	}
+	for i := range cfg.NetworkSources {
+		resolve(&cfg.NetworkSources[i].Path)
+	}
+	for k, dir := range cfg.CalendarSources {
+		resolve(&dir)
+		cfg.CalendarSources[k] = dir
+	}
+}
+
+// ParseConfig parses and validates the config file contents, and fills in the
+// defaults.
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config file: %w", err)
+	}
+	if err := applyProfile(&cfg); err != nil {
+		return nil, err
+	}
+
+	// sanity checks
+	if cfg.PicturesDir == "" && len(cfg.Sources) == 0 && cfg.Mode != modeGradient {
+		return nil, fmt.Errorf("pictures_dir cannot be empty")
+	}
+	calendar, err := normalizeCalendarSources(cfg.CalendarSources)
+	if err != nil {
+		return nil, err
+	}
+	cfg.CalendarSources = calendar
+	if err := validateExtensions(cfg.SupportedExtensions); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Sources {
+		if err := cfg.Sources[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid sources entry %d: %w", i, err)
+		}
+	}
+	for i := range cfg.TimeSources {
+		if err := cfg.TimeSources[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid time_sources entry %d: %w", i, err)
+		}
+	}
+	if cfg.LargeDirectory < 0 {
+		return nil, fmt.Errorf("large_directory cannot be negative")
+	}
+	if cfg.FailureAlertAfter < 0 {
+		return nil, fmt.Errorf("failure_alert_after cannot be negative")
+	}
+	if cfg.PauseOnFailures && cfg.FailureAlertAfter == 0 {
+		return nil, fmt.Errorf("pause_on_failures requires failure_alert_after")
+	}
+	for i := range cfg.NetworkSources {
+		if err := cfg.NetworkSources[i].validate(cfg.Profiles); err != nil {
+			return nil, fmt.Errorf("invalid network_sources entry %d: %w", i, err)
+		}
+	}
+	switch cfg.Mode {
+	case "", modeRandom, modeBrightness, modeNewest, modeFair, modeOnThisDay, modeDiscovery:
+	case modeDaily:
+		if cfg.Preload || cfg.PerImageCooldown > 0 {
+			return nil, fmt.Errorf("mode %s cannot be used with preload or per_image_cooldown", modeDaily)
+		}
+	case modeSeasonal:
+		if err := validateHemisphere(cfg.Hemisphere); err != nil {
+			return nil, err
+		}
+	case modeAccent:
+		if cfg.TargetColor != "" && !hexColorRegexp.MatchString(cfg.TargetColor) {
+			return nil, fmt.Errorf("invalid target_color '%s', want #rrggbb", cfg.TargetColor)
+		}
+	case modeGradient:
+		if err := validateGradient(&cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode '%s'", cfg.Mode)
+	}
+	if cfg.AspectRatio != "" && cfg.AspectRatio != aspectRatioAuto {
+		if _, err := parseAspectRatio(cfg.AspectRatio); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AspectRatioTolerance < 0 {
+		return nil, fmt.Errorf("aspect_ratio_tolerance cannot be negative")
+	}
+	if cfg.PerImageCooldown < 0 {
+		return nil, fmt.Errorf("per_image_cooldown cannot be negative")
+	}
+	if cfg.MaxAge < 0 || cfg.MinAge < 0 {
+		return nil, fmt.Errorf("max_age and min_age cannot be negative")
+	}
+	if cfg.MaxAge > 0 && cfg.MinAge > cfg.MaxAge {
+		return nil, fmt.Errorf("min_age cannot be greater than max_age")
+	}
+	if cfg.HookRetries != nil && *cfg.HookRetries < 0 {
+		return nil, fmt.Errorf("pre_change_hook_retries cannot be negative")
+	}
+	if cfg.ScanRetries != nil && *cfg.ScanRetries < 0 {
+		return nil, fmt.Errorf("scan_retries cannot be negative")
+	}
+	if cfg.FocusColor != "" && !hexColorRegexp.MatchString(cfg.FocusColor) {
+		return nil, fmt.Errorf("invalid focus_color '%s', want #rrggbb", cfg.FocusColor)
+	}
+	if cfg.FallbackColor != "" && !hexColorRegexp.MatchString(cfg.FallbackColor) {
+		return nil, fmt.Errorf("invalid fallback_color '%s', want #rrggbb", cfg.FallbackColor)
+	}
+	switch cfg.DarkVariant {
+	case "", darkVariantSame, darkVariantDarken:
+	default:
+		return nil, fmt.Errorf("unknown dark_variant '%s'", cfg.DarkVariant)
+	}
+	if cfg.DifferByTheme && (cfg.DarkPicturesDir != "" || cfg.DarkVariant != "") {
+		return nil, fmt.Errorf("differ_by_theme cannot be used with dark_pictures_dir or dark_variant")
+	}
+	if cfg.FollowColorScheme && cfg.DarkPicturesDir == "" && !cfg.DifferByTheme && cfg.DarkVariant == "" {
+		return nil, fmt.Errorf("follow_color_scheme requires dark_pictures_dir, dark_variant or differ_by_theme")
+	}
+	if cfg.DarkenAmount < 0 || cfg.DarkenAmount > 1 {
+		return nil, fmt.Errorf("darken_amount must be between 0 and 1")
+	}
+	if err := validateExtensionWeights(cfg.ExtensionWeights); err != nil {
+		return nil, err
+	}
+	if err := cfg.AdaptiveInterval.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Tint.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Caption.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateHotkey(cfg.Hotkey); err != nil {
+		return nil, err
+	}
+	if cfg.ManualHold < 0 {
+		return nil, fmt.Errorf("manual_hold cannot be negative")
+	}
+	if cfg.MaxDimension < 0 {
+		return nil, fmt.Errorf("max_dimension cannot be negative")
+	}
+	switch cfg.PrescaleMode {
+	case "", prescaleCrop, prescaleFit:
+	default:
+		return nil, fmt.Errorf("unknown prescale_mode '%s'", cfg.PrescaleMode)
+	}
+	switch cfg.Transition {
+	case "", transitionFade:
+	default:
+		return nil, fmt.Errorf("unknown transition '%s'", cfg.Transition)
+	}
+	for i := range cfg.QuietHours {
+		if err := cfg.QuietHours[i].parse(); err != nil {
+			return nil, fmt.Errorf("invalid quiet_hours entry %d: %w", i, err)
+		}
+	}
+	if err := validateIntervalClickAction(cfg.IntervalClickAction); err != nil {
+		return nil, err
+	}
+	if err := validateTrayClickAction(cfg.TrayClickAction); err != nil {
+		return nil, err
+	}
+	for name, options := range map[string]string{
+		"picture_options":   cfg.PictureOptions,
+		"landscape_options": cfg.LandscapeOptions,
+		"portrait_options":  cfg.PortraitOptions,
+	} {
+		if err := validatePictureOptions(name, options); err != nil {
+			return nil, err
+		}
+	}
+	switch cfg.LogFormat {
+	case "", logFormatText, logFormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown log_format '%s', want text or json", cfg.LogFormat)
+	}
+
+	// defaults
+	cfg.Brightness.setDefaults()
+	if err := cfg.Brightness.validate(); err != nil {
+		return nil, err
+	}
+	cfg.AdaptiveInterval.setDefaults()
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = logFormatText
+	}
+	if cfg.TransitionDuration <= 0 {
+		cfg.TransitionDuration = xjson.Duration(defaultTransitionDuration)
+	}
+	if cfg.TransitionFrames <= 0 {
+		cfg.TransitionFrames = defaultTransitionFrames
+	}
+	if cfg.StartupDelay == nil {
+		d := xjson.Duration(defaultStartupDelay)
+		cfg.StartupDelay = &d
+	}
+	if cfg.ConfirmDestructive == nil {
+		confirm := true
+		cfg.ConfirmDestructive = &confirm
+	}
+	if len(cfg.SupportedExtensions) == 0 {
+		cfg.SupportedExtensions = append([]string(nil), defaultExtensions...)
+	}
+	if cfg.AgeFallback == nil {
+		fallback := true
+		cfg.AgeFallback = &fallback
+	}
+	if cfg.ScanRetries == nil {
+		n := defaultScanRetries
+		cfg.ScanRetries = &n
+	}
+	if cfg.WatchGrace <= 0 {
+		cfg.WatchGrace = xjson.Duration(defaultWatchGrace)
+	}
+	if cfg.ScanRetryDelay <= 0 {
+		cfg.ScanRetryDelay = xjson.Duration(defaultScanRetryDelay)
+	}
+	if cfg.HookRetries == nil {
+		n := defaultHookRetries
+		cfg.HookRetries = &n
+	}
+	if cfg.HookTimeout <= 0 {
+		cfg.HookTimeout = xjson.Duration(defaultHookTimeout)
+	}
+	if cfg.CommandTimeout <= 0 {
+		cfg.CommandTimeout = xjson.Duration(defaultCommandTimeout)
+	}
+	if cfg.DarkenAmount == 0 {
+		cfg.DarkenAmount = defaultDarkenAmount
+	}
+	if cfg.AspectRatioTolerance == 0 {
+		cfg.AspectRatioTolerance = defaultAspectTolerance
+	}
+	if cfg.RejectsDir == "" {
+		cfg.RejectsDir = defaultRejectsDir
+	}
+	applyAccessibility(&cfg)
+
+	return &cfg, nil
+}
+
+// effectiveConfig is the config as printed by PrintConfig, with the values
+// derived from it.
+type effectiveConfig struct {
+	*Config
+	ConfigFile string `json:"config_file"`
+	// ActiveSources are the sources pictures are picked from right now,
+	// e.g. those of the current period of time_sources.
+	ActiveSources     []SourceConfig `json:"active_sources"`
+	EffectiveInterval xjson.Duration `json:"effective_interval"`
+}
+
+// PrintConfig prints the config as JSON, after applying the profile, the
+// defaults and the accessibility mode, and resolving the relative paths.
+func PrintConfig(configFile string, cfg *Config) error {
+	data, err := json.MarshalIndent(effectiveConfig{
+		Config:            cfg,
+		ConfigFile:        configFile,
+		ActiveSources:     configuredSources(cfg),
+		EffectiveInterval: EffectiveInterval(cfg),
+	}, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
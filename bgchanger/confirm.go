@@ -0,0 +1,40 @@
+package bgchanger
+
+import (
+	"sync"
+	"time"
+)
+
+// confirmWindow is how long a destructive action waits for its confirmation.
+const confirmWindow = 3 * time.Second
+
+// Confirmation gates a destructive action behind a second request within a
+// short time window, so that a single accidental click does nothing.
+type Confirmation struct {
+	mu    sync.Mutex
+	armed time.Time
+}
+
+// Confirm reports whether the action is confirmed. The first call arms the
+// confirmation and returns false, a second call within the window returns
+// true. The zero value is ready to use.
+func (c *Confirmation) Confirm(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.armed.IsZero() && now.Sub(c.armed) <= confirmWindow {
+		c.armed = time.Time{}
+		return true
+	}
+	c.armed = now
+	return false
+}
+
+// Window returns how long Confirm waits for the second request.
+func (c *Confirmation) Window() time.Duration {
+	return confirmWindow
+}
+
+// NeedsConfirmation reports whether destructive actions must be confirmed.
+func NeedsConfirmation(cfg *Config) bool {
+	return cfg.ConfirmDestructive == nil || *cfg.ConfirmDestructive
+}
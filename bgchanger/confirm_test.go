@@ -0,0 +1,41 @@
+package bgchanger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmation(t *testing.T) {
+	var c Confirmation
+	now := time.Now()
+	if c.Confirm(now) {
+		t.Fatal("a single request was confirmed")
+	}
+	if !c.Confirm(now.Add(time.Second)) {
+		t.Fatal("a second request within the window was not confirmed")
+	}
+	// the confirmation is consumed
+	if c.Confirm(now.Add(2 * time.Second)) {
+		t.Fatal("a confirmation was used twice")
+	}
+	// too late, which arms it again
+	if c.Confirm(now.Add(2*time.Second + c.Window() + time.Millisecond)) {
+		t.Fatal("a request after the window was confirmed")
+	}
+	if !c.Confirm(now.Add(3*time.Second + c.Window())) {
+		t.Fatal("the late request did not arm the confirmation")
+	}
+}
+
+func TestNeedsConfirmation(t *testing.T) {
+	testEnv(t)
+	for config, want := range map[string]bool{
+		`{"pictures_dir": "/p"}`:                               true,
+		`{"pictures_dir": "/p", "confirm_destructive": true}`:  true,
+		`{"pictures_dir": "/p", "confirm_destructive": false}`: false,
+	} {
+		if got := NeedsConfirmation(testConfig(t, config)); got != want {
+			t.Errorf("%s: got %v, want %v", config, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"path/filepath"
+)
+
+// contact sheet defaults
+const (
+	DefaultContactSheetColumns = 6
+	DefaultContactSheetSize    = 200
+)
+
+// contactSheetPadding is the space in pixels around each thumbnail, and
+// contactSheetLabel the height of the file name below it.
+const (
+	contactSheetPadding = 8
+	contactSheetLabel   = glyphHeight + 6
+)
+
+// ContactSheet writes to out a PNG grid of the thumbnails of the candidates,
+// with the given number of columns, each thumbnail fitting in a square of
+// size pixels, with its file name below it.
+func ContactSheet(cfg *Config, out string, columns, size int) error {
+	if columns <= 0 || size <= 0 {
+		return fmt.Errorf("the columns and the thumbnail size must be positive")
+	}
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		return err
+	}
+	var (
+		thumbs []image.Image
+		names  []string
+	)
+	for _, p := range pictures {
+		if isVideo(p) {
+			continue
+		}
+		thumb, err := downscale(p, size)
+		if err != nil {
+			log.Printf("Warning: skipping '%s': %v", p, err)
+			continue
+		}
+		img, err := loadImage(thumb)
+		if err != nil {
+			log.Printf("Warning: skipping '%s': %v", p, err)
+			continue
+		}
+		thumbs = append(thumbs, img)
+		names = append(names, filepath.Base(p))
+	}
+	if len(thumbs) == 0 {
+		return fmt.Errorf("no pictures to put in the contact sheet")
+	}
+	sheet := composeContactSheet(thumbs, names, columns, size)
+	if err := writePNG(out, sheet); err != nil {
+		return fmt.Errorf("failed to write contact sheet: %w", err)
+	}
+	log.Printf("Wrote a contact sheet of %d pictures to '%s'", len(thumbs), out)
+	return nil
+}
+
+// composeContactSheet lays out the thumbnails in a grid of the given number
+// of columns, or fewer if there are fewer thumbnails, each centered in a cell
+// of size pixels over its name.
+func composeContactSheet(thumbs []image.Image, names []string, columns, size int) *image.RGBA {
+	if columns > len(thumbs) {
+		columns = len(thumbs)
+	}
+	rows := (len(thumbs) + columns - 1) / columns
+	cellW, cellH := size+2*contactSheetPadding, size+contactSheetLabel+2*contactSheetPadding
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*cellW, rows*cellH))
+	draw.Draw(sheet, sheet.Bounds(), &image.Uniform{color.RGBA{R: 32, G: 32, B: 32, A: 255}}, image.Point{}, draw.Src)
+	// as many characters as fit in the width of a thumbnail
+	maxChars := (size + 1) / (glyphWidth + 1)
+	for i, thumb := range thumbs {
+		x0, y0 := i%columns*cellW+contactSheetPadding, i/columns*cellH+contactSheetPadding
+		b := thumb.Bounds()
+		at := image.Pt(x0+(size-b.Dx())/2, y0+(size-b.Dy())/2)
+		draw.Draw(sheet, image.Rectangle{at, at.Add(b.Size())}, thumb, b.Min, draw.Src)
+		name := []rune(names[i])
+		if len(name) > maxChars && maxChars > 1 {
+			name = append(name[:maxChars-1], '~')
+		} else if len(name) > maxChars {
+			name = name[:maxChars]
+		}
+		label := string(name)
+		drawText(sheet, label, x0+(size-textWidth(label, 1))/2, y0+size+contactSheetLabel-glyphHeight-1, 1, color.RGBA{R: 220, G: 220, B: 220, A: 255}, 1)
+	}
+	return sheet
+}
@@ -0,0 +1,54 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestContactSheet(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	for i, c := range []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {R: 255, G: 255, A: 255}, {A: 255}} {
+		writeTestPicture(t, filepath.Join(dir, string(rune('a'+i))+".png"), 64, 32, c)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	sheet := filepath.Join(t.TempDir(), "sheet.png")
+	if err := ContactSheet(cfg, sheet, 3, 20); err != nil {
+		t.Fatal(err)
+	}
+	cellW, cellH := 20+2*contactSheetPadding, 20+contactSheetLabel+2*contactSheetPadding
+	if w, h := decodeSize(t, sheet); w != 3*cellW || h != 2*cellH {
+		t.Errorf("got a %dx%d contact sheet, want 3x2 cells of %dx%d", w, h, cellW, cellH)
+	}
+	img, err := loadImage(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 20x10 thumbnail of b.png is centered in the second cell
+	x, y := cellW+contactSheetPadding+10, contactSheetPadding+10
+	if r, g, b, _ := img.At(x, y).RGBA(); r>>8 > 32 || g>>8 < 200 || b>>8 > 32 {
+		t.Errorf("got %v at the center of the second thumbnail, want green", img.At(x, y))
+	}
+
+	// fewer pictures than columns make a single row
+	if err := ContactSheet(cfg, sheet, 10, 20); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, sheet); w != 5*cellW || h != cellH {
+		t.Errorf("got a %dx%d contact sheet, want 5x1 cells", w, h)
+	}
+}
+
+func TestContactSheetErrors(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	out := filepath.Join(t.TempDir(), "sheet.png")
+	if err := ContactSheet(cfg, out, 0, 20); err == nil {
+		t.Error("expected an error with no columns")
+	}
+	if err := ContactSheet(cfg, out, 3, 20); err == nil {
+		t.Error("expected an error without pictures")
+	}
+}
@@ -0,0 +1,100 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// controlRequests carries the requests of the control server to the main
+// loop, so that they don't race with the changes the loop makes.
+var controlRequests = make(chan func())
+
+// ControlRequests returns the channel of the control server requests, each to
+// be run by the main loop.
+func ControlRequests() <-chan func() {
+	return controlRequests
+}
+
+// inMainLoop runs f in the main loop and waits for it to return.
+func inMainLoop(f func()) {
+	done := make(chan struct{})
+	controlRequests <- func() {
+		defer close(done)
+		f()
+	}
+	<-done
+}
+
+// StartControlServer starts an HTTP server on the configured control address,
+// letting other programs drive the running instance.
+func StartControlServer(cfg *Config) {
+	mux := controlHandler(cfg)
+	log.Printf("Listening for control requests on %s", cfg.ControlAddr)
+	go func() {
+		if err := http.ListenAndServe(cfg.ControlAddr, mux); err != nil {
+			log.Printf("Error: control server failed: %v", err)
+		}
+	}()
+}
+
+// controlHandler returns the handler of the control server requests.
+func controlHandler(cfg *Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/change", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inMainLoop(func() { ManualChangeBG(cfg) })
+	})
+	mux.HandleFunc("/click", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		inMainLoop(func() { TrayClicked(cfg) })
+	})
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		var (
+			pictures []string
+			err      error
+		)
+		inMainLoop(func() { pictures, err = indexedCandidates(cfg) })
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if pictures == nil {
+			pictures = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(pictures); err != nil {
+			log.Printf("Error: failed to write candidate list: %v", err)
+		}
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(getStatus()); err != nil {
+			log.Printf("Error: failed to write status: %v", err)
+		}
+	})
+	mux.HandleFunc("/apply", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		index, err := strconv.Atoi(r.URL.Query().Get("index"))
+		if err != nil {
+			http.Error(w, "invalid index", http.StatusBadRequest)
+			return
+		}
+		inMainLoop(func() { err = ApplyIndex(cfg, index) })
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	})
+	return mux
+}
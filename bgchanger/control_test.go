@@ -0,0 +1,69 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestControlApplyIndex(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1h", "startup_delay": "0s", "max_candidates": 2}`, dir)
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		RunLoop(cfg, nil, quit)
+		close(done)
+	}()
+	defer func() {
+		quit <- os.Interrupt
+		<-done
+	}()
+	server := httptest.NewServer(controlHandler(cfg))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	err = json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != len(pictures) {
+		t.Fatalf("listed %v, want all of %v regardless of max_candidates", listed, pictures)
+	}
+
+	resp, err = http.Post(server.URL+"/apply?index=2", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("applying index 2: got %s", resp.Status)
+	}
+	if uri := setter.get("picture-uri"); !strings.HasSuffix(uri, listed[2]) {
+		t.Errorf("applied %s, want %s", uri, listed[2])
+	}
+
+	for _, index := range []string{"3", "-1", "x"} {
+		resp, err = http.Post(server.URL+"/apply?index="+index, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("applying index %s: got %s, want %d", index, resp.Status, http.StatusBadRequest)
+		}
+	}
+	if n := len(setter.values("picture-uri")); n != 1 {
+		t.Errorf("got %d changes, want 1", n)
+	}
+}
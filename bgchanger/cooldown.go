@@ -0,0 +1,92 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+// lastShown holds when each picture was last applied, by path, for
+// per_image_cooldown.
+var lastShown = struct {
+	sync.Mutex
+	loaded bool
+	times  map[string]time.Time
+}{}
+
+func lastShownFile() string {
+	return path.Join(configdir.LocalConfig(Progname), "last_shown.json")
+}
+
+// loadLastShownLocked reads the last shown file, if not done already. The
+// lastShown lock must be held.
+func loadLastShownLocked() {
+	if lastShown.loaded {
+		return
+	}
+	lastShown.loaded = true
+	lastShown.times = make(map[string]time.Time)
+	data, err := os.ReadFile(lastShownFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read last shown times: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &lastShown.times); err != nil {
+		log.Printf("Warning: cannot parse last shown times: %v", err)
+	}
+}
+
+// recordShown saves when the picture was applied, if per_image_cooldown is
+// set. Pictures whose cooldown is over are forgotten.
+func recordShown(cfg *Config, filename string, now time.Time) error {
+	if cfg.PerImageCooldown <= 0 {
+		return nil
+	}
+	lastShown.Lock()
+	defer lastShown.Unlock()
+	loadLastShownLocked()
+	for p, t := range lastShown.times {
+		if now.Sub(t) >= time.Duration(cfg.PerImageCooldown) {
+			delete(lastShown.times, p)
+		}
+	}
+	lastShown.times[filename] = now
+	data, err := json.MarshalIndent(lastShown.times, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal last shown times: %w", err)
+	}
+	if err := writeFileAtomic(lastShownFile(), data); err != nil {
+		return fmt.Errorf("failed to save last shown times: %w", err)
+	}
+	return nil
+}
+
+// filterCooldown removes the pictures applied less than per_image_cooldown
+// ago, whatever their weight, unless that would leave none.
+func filterCooldown(cfg *Config, pictures []string, now time.Time) []string {
+	if cfg.PerImageCooldown <= 0 {
+		return pictures
+	}
+	lastShown.Lock()
+	loadLastShownLocked()
+	var kept []string
+	for _, p := range pictures {
+		if t, ok := lastShown.times[p]; !ok || now.Sub(t) >= time.Duration(cfg.PerImageCooldown) {
+			kept = append(kept, p)
+		}
+	}
+	lastShown.Unlock()
+	if len(kept) == 0 {
+		debugf("All the pictures are in their cooldown, ignoring per_image_cooldown")
+		return pictures
+	}
+	return kept
+}
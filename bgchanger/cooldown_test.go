@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPerImageCooldown(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_image_cooldown": "1h"}`, dir)
+	// a favorite that would otherwise be picked about 94% of the time
+	if err := ratePicture(pictures[0], maxRating); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := recordShown(cfg, pictures[0], now.Add(-30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if p, err := pickFrom(cfg, append([]string(nil), pictures...), nil); err != nil || p == pictures[0] {
+			t.Fatalf("got %s, %v, want the favorite excluded during its cooldown", p, err)
+		}
+	}
+
+	// once the cooldown is over the favorite is back
+	if got := filterCooldown(cfg, pictures, now.Add(31*time.Minute)); !reflect.DeepEqual(got, pictures) {
+		t.Errorf("got %v after the cooldown, want all of %v", got, pictures)
+	}
+}
+
+func TestPerImageCooldownPersists(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_image_cooldown": "1h"}`, dir)
+	now := time.Now()
+	if err := recordShown(cfg, pictures[0], now); err != nil {
+		t.Fatal(err)
+	}
+	resetState()
+	if got := filterCooldown(cfg, pictures, now); !reflect.DeepEqual(got, pictures[1:]) {
+		t.Errorf("got %v after a restart, want %v", got, pictures[1:])
+	}
+	// never leaves no candidates
+	if err := recordShown(cfg, pictures[1], now); err != nil {
+		t.Fatal(err)
+	}
+	if got := filterCooldown(cfg, pictures, now); !reflect.DeepEqual(got, pictures) {
+		t.Errorf("got %v with all pictures in their cooldown, want all of them", got)
+	}
+}
+
+func TestPerImageCooldownAfterChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_image_cooldown": "1h"}`, dir)
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		ChangeBG(cfg)
+		uri := setter.get("picture-uri")
+		if seen[uri] {
+			t.Fatalf("got %s twice within per_image_cooldown", uri)
+		}
+		seen[uri] = true
+	}
+}
@@ -0,0 +1,56 @@
+package bgchanger
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// modeDaily picks the same picture for the whole day, and the same on every
+// machine with the same pictures and daily_salt.
+const modeDaily = "daily_deterministic"
+
+// dailyScore returns the score of a picture on the day of now. Pictures are
+// identified by their file name, which is the same on every machine even
+// when the pictures directory is not.
+func dailyScore(filename, salt string, now time.Time) uint64 {
+	sum := sha1.Sum([]byte(now.Format("2006-01-02") + "\x00" + salt + "\x00" + filepath.Base(filename)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// sortByDailyScore sorts the pictures by their score on the day of now, so
+// that the first one is the picture of the day. Since each picture is scored
+// on its own, adding or removing pictures only changes the pick when the
+// added one scores best, or when the removed one was the pick.
+func sortByDailyScore(pictures []string, salt string, now time.Time) {
+	scores := make(map[string]uint64, len(pictures))
+	for _, p := range pictures {
+		scores[p] = dailyScore(p, salt, now)
+	}
+	sort.SliceStable(pictures, func(i, j int) bool {
+		if scores[pictures[i]] != scores[pictures[j]] {
+			return scores[pictures[i]] < scores[pictures[j]]
+		}
+		return pictures[i] < pictures[j]
+	})
+}
+
+// untilNextDay returns the time from now until just after the next midnight,
+// when the picture of the day changes.
+func untilNextDay(now time.Time) time.Duration {
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	return midnight.Sub(now).Truncate(time.Second) + time.Second
+}
+
+// changeExclusions returns the pictures that a change must not pick. The
+// picture of the day is picked again until midnight, and the newest picture
+// until a newer one appears, even if they are shown.
+func changeExclusions(cfg *Config) []string {
+	if cfg.Mode == modeDaily || cfg.Mode == modeNewest {
+		return nil
+	}
+	return recentPictures()
+}
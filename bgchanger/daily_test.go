@@ -0,0 +1,116 @@
+package bgchanger
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// pictureOfTheDay returns the picture of the day of now among pictures.
+func pictureOfTheDay(pictures []string, salt string, now time.Time) string {
+	sorted := append([]string(nil), pictures...)
+	sortByDailyScore(sorted, salt, now)
+	return sorted[0]
+}
+
+func TestDailyDeterministic(t *testing.T) {
+	var pictures, elsewhere []string
+	for i := 0; i < 20; i++ {
+		pictures = append(pictures, fmt.Sprintf("/home/me/Pictures/%02d.jpg", i))
+		elsewhere = append(elsewhere, fmt.Sprintf("/mnt/sync/wallpapers/%02d.jpg", 19-i))
+	}
+	day := time.Date(2024, time.March, 14, 8, 0, 0, 0, time.Local)
+	pick := pictureOfTheDay(pictures, "", day)
+	if got := pictureOfTheDay(pictures, "", day.Add(15*time.Hour)); got != pick {
+		t.Errorf("got %s later on the same day, want %s", got, pick)
+	}
+	// another machine, with the pictures in another folder and order
+	if got := pictureOfTheDay(elsewhere, "", day); filepath.Base(got) != filepath.Base(pick) {
+		t.Errorf("got %s on another machine, want %s", got, filepath.Base(pick))
+	}
+
+	different := 0
+	for d := 1; d <= 10; d++ {
+		if pictureOfTheDay(pictures, "", day.AddDate(0, 0, d)) != pick {
+			different++
+		}
+	}
+	if different < 5 {
+		t.Errorf("got %d different pictures of the day out of 10 next days, want most of them", different)
+	}
+	salted := 0
+	for d := 0; d < 10; d++ {
+		now := day.AddDate(0, 0, d)
+		if pictureOfTheDay(pictures, "salt", now) != pictureOfTheDay(pictures, "", now) {
+			salted++
+		}
+	}
+	if salted < 5 {
+		t.Errorf("daily_salt changed the picture of %d out of 10 days, want most of them", salted)
+	}
+}
+
+func TestDailyCollectionChanges(t *testing.T) {
+	var pictures []string
+	for i := 0; i < 20; i++ {
+		pictures = append(pictures, fmt.Sprintf("/p/%02d.jpg", i))
+	}
+	day := time.Date(2024, time.July, 1, 12, 0, 0, 0, time.Local)
+	pick := pictureOfTheDay(pictures, "", day)
+	var others []string
+	for _, p := range pictures {
+		if p != pick {
+			others = append(others, p)
+		}
+	}
+	// removing another picture keeps the pick
+	if got := pictureOfTheDay(append([]string{pick}, others[1:]...), "", day); got != pick {
+		t.Errorf("got %s after removing %s, want %s", got, others[0], pick)
+	}
+	// removing the pick falls back to the next best
+	sorted := append([]string(nil), pictures...)
+	sortByDailyScore(sorted, "", day)
+	if got := pictureOfTheDay(others, "", day); got != sorted[1] {
+		t.Errorf("got %s after removing the pick, want the runner-up %s", got, sorted[1])
+	}
+}
+
+func TestDailyMode(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "daily_deterministic", "interval": "10m"}`, dir)
+	first, err := PickPicture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		ChangeBG(cfg)
+		if got := CurrentPicture(); got != first {
+			t.Fatalf("got %s, want the picture of the day %s", got, first)
+		}
+	}
+	// the next change comes just after midnight
+	if got, want := time.Duration(EffectiveInterval(cfg)), untilNextDay(time.Now()); got < want-2*time.Second || got > want {
+		t.Errorf("got interval %s, want %s until midnight", got, want)
+	}
+}
+
+func TestUntilNextDay(t *testing.T) {
+	now := time.Date(2024, time.December, 31, 23, 0, 0, 0, time.Local)
+	if got := untilNextDay(now); got != time.Hour+time.Second {
+		t.Errorf("got %s, want 1h0m1s", got)
+	}
+}
+
+func TestDailyValidate(t *testing.T) {
+	for _, c := range []string{
+		`{"pictures_dir": "/p", "mode": "daily_deterministic", "preload": true}`,
+		`{"pictures_dir": "/p", "mode": "daily_deterministic", "per_image_cooldown": "1h"}`,
+	} {
+		if _, err := ParseConfig([]byte(c)); err == nil {
+			t.Errorf("%s: expected an error", c)
+		}
+	}
+}
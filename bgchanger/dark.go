@@ -0,0 +1,120 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+)
+
+// dark variants, used for picture-uri-dark when there is no
+// dark_pictures_dir
+const (
+	darkVariantSame   = "same"
+	darkVariantDarken = "darken"
+)
+
+const defaultDarkenAmount = 0.3
+
+// darkenImage scales the brightness of img down by amount, between 0 and 1.
+func darkenImage(img image.Image, amount float64) *image.RGBA {
+	src := toRGBA(img)
+	b := src.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	f := 1 - amount
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := src.RGBAAt(b.Min.X+x, b.Min.Y+y)
+			out.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(c.R)*f + 0.5),
+				G: uint8(float64(c.G)*f + 0.5),
+				B: uint8(float64(c.B)*f + 0.5),
+				A: c.A,
+			})
+		}
+	}
+	return out
+}
+
+// darken returns the path of a cached darkened copy of the picture.
+func darken(filename string, amount float64) (string, error) {
+	cached, ok, err := cachedVariant("darkened", filename, fmt.Sprintf("dark%03.0f", amount*100))
+	if err != nil || ok {
+		return cached, err
+	}
+	img, err := loadImage(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := writeJPEG(cached, darkenImage(img, amount)); err != nil {
+		return "", fmt.Errorf("failed to write darkened picture: %w", err)
+	}
+	return cached, nil
+}
+
+// darkPicture returns the picture to use in dark mode, given the one picked
+// and the one applied in light mode, and whether there is one.
+func darkPicture(cfg *Config, filename, applied string) (string, bool, error) {
+	if cfg.DifferByTheme {
+		// a second pick, going through the same filters as the first one
+		p, err := PickPicture(cfg, append(recentPictures(), filename)...)
+		if err != nil {
+			return "", false, err
+		}
+		if isVideo(p) {
+			debugf("not using video '%s' in dark mode", p)
+			return "", false, nil
+		}
+		return preparePicture(cfg, p), true, nil
+	}
+	if cfg.DarkPicturesDir != "" {
+		// picked with the same filters as the light mode pictures
+		src := SourceConfig{Type: sourceDir, Path: cfg.DarkPicturesDir, Weight: 1}
+		pictures, err := scanSourceWithRetries(cfg, &src)
+		if err != nil {
+			return "", false, err
+		}
+		if len(pictures) == 0 {
+			return "", false, fmt.Errorf("no pictures found in dark_pictures_dir")
+		}
+		p, err := pickFrom(cfg, pictures, nil)
+		if err != nil {
+			return "", false, err
+		}
+		if isVideo(p) {
+			debugf("not using video '%s' in dark mode", p)
+			return "", false, nil
+		}
+		return preparePicture(cfg, p), true, nil
+	}
+	switch cfg.DarkVariant {
+	case darkVariantSame:
+		return applied, true, nil
+	case darkVariantDarken:
+		p, err := darken(applied, cfg.DarkenAmount)
+		return p, err == nil, err
+	}
+	return "", false, nil
+}
+
+// setDarkPicture sets picture-uri-dark, used by GNOME in dark mode, if
+// configured.
+func setDarkPicture(cfg *Config, filename, applied string) {
+	dark, ok, err := darkPicture(cfg, filename, applied)
+	if err != nil {
+		log.Printf("Error: cannot get the dark mode picture: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if from, _ := flipFade(true); cfg.Transition == transitionFade && from != "" && from != dark {
+		// switching to dark mode, from the light mode picture
+		playFade(cfg, "picture-uri-dark", from, dark)
+	}
+	if err := gsettingsSet(backgroundSchema, "picture-uri-dark", "file://"+dark); err != nil {
+		log.Printf("Error: failed to change dark mode background: %v", err)
+		return
+	}
+	debugf("dark mode background changed to '%s'", dark)
+}
@@ -0,0 +1,54 @@
+package bgchanger
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDarkenVariant(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	light := writeTestPicture(t, filepath.Join(dir, "light.png"), 16, 16, color.RGBA{200, 180, 160, 255})
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_variant": "darken", "darken_amount": 0.5}`, dir)
+	ChangeBG(cfg)
+	dark := strings.TrimPrefix(setter.get("picture-uri-dark"), "file://")
+	if dark == "" || dark == light {
+		t.Fatalf("got picture-uri-dark %q, want a darkened copy of %s", dark, light)
+	}
+	img, err := loadImage(dark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := img.At(8, 8).RGBA()
+	if r>>8 > 110 || g>>8 > 100 || b>>8 > 90 {
+		t.Errorf("got %d,%d,%d, want half of 200,180,160", r>>8, g>>8, b>>8)
+	}
+	// the darkened copy is cached
+	if again, err := darken(light, 0.5); err != nil || again != dark {
+		t.Errorf("got %s, %v, want the cached %s", again, err, dark)
+	}
+}
+
+func TestDarkPicturesDirFilters(t *testing.T) {
+	setter := testEnv(t)
+	dir, darkDir := t.TempDir(), t.TempDir()
+	writeTestPicture(t, filepath.Join(dir, "light.png"), 16, 16, color.RGBA{200, 200, 200, 255})
+	blocked := writeTestPicture(t, filepath.Join(darkDir, "blocked.png"), 16, 16, color.RGBA{10, 10, 10, 255})
+	allowed := writeTestPicture(t, filepath.Join(darkDir, "allowed.png"), 16, 16, color.RGBA{20, 20, 20, 255})
+	if err := os.WriteFile(filepath.Join(darkDir, "video.mp4"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q}`, dir, darkDir)
+	if err := blockPicture(cfg, blocked); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		ChangeBG(cfg)
+		if dark := setter.get("picture-uri-dark"); dark != "file://"+allowed {
+			t.Fatalf("got picture-uri-dark %s, want the only candidate %s", dark, allowed)
+		}
+	}
+}
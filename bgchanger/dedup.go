@@ -0,0 +1,54 @@
+package bgchanger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+)
+
+var contentHashCache = newFileCache(computeContentHash)
+
+// contentHash returns the hex-encoded SHA-256 of the file contents. Results
+// are cached by path and modification time.
+func contentHash(filename string) (string, error) {
+	return contentHashCache.get(filename)
+}
+
+func computeContentHash(filename string) (string, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dedupPictures keeps only the first of each group of pictures with identical
+// contents.
+func dedupPictures(pictures []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, p := range pictures {
+		h, err := contentHash(p)
+		if err != nil {
+			log.Printf("Warning: cannot hash '%s': %v", p, err)
+			unique = append(unique, p)
+			continue
+		}
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		unique = append(unique, p)
+	}
+	if n := len(pictures) - len(unique); n > 0 {
+		debugf("Collapsed %d duplicate pictures", n)
+	}
+	return unique
+}
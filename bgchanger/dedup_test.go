@@ -0,0 +1,43 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupIdenticalFiles(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	data, err := os.ReadFile(pictures[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := filepath.Join(dir, "copy.png")
+	if err := os.WriteFile(copied, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "dedup": true}`, dir)
+	scanned, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 2 || !contains(scanned, pictures[1]) {
+		t.Fatalf("got %v, want one of %s and %s, and %s", scanned, pictures[0], copied, pictures[1])
+	}
+	for i := 0; i < 20; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !contains(scanned, p) {
+			t.Fatalf("picked %s, a duplicate", p)
+		}
+	}
+
+	cfg = testConfig(t, `{"pictures_dir": %q}`, dir)
+	if scanned, err := ScanCandidates(cfg); err != nil || len(scanned) != 3 {
+		t.Errorf("got %v, %v without dedup, want all 3 pictures", scanned, err)
+	}
+}
@@ -0,0 +1,39 @@
+package bgchanger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDifferByTheme(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "differ_by_theme": true}`, dir)
+	// both picks go through the filters
+	if err := blockPicture(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		ChangeBG(cfg)
+		light := strings.TrimPrefix(setter.get("picture-uri"), "file://")
+		dark := strings.TrimPrefix(setter.get("picture-uri-dark"), "file://")
+		if light == "" || dark == "" || light == dark {
+			t.Fatalf("got picture-uri %q and picture-uri-dark %q, want two different pictures", light, dark)
+		}
+		if light == pictures[0] || dark == pictures[0] {
+			t.Fatalf("got the blocked %s as a background", pictures[0])
+		}
+	}
+}
+
+func TestDifferByThemeConflicts(t *testing.T) {
+	for _, tc := range []string{
+		`{"pictures_dir": "/p", "differ_by_theme": true, "dark_variant": "same"}`,
+		`{"pictures_dir": "/p", "differ_by_theme": true, "dark_pictures_dir": "/d"}`,
+	} {
+		if _, err := ParseConfig([]byte(tc)); err == nil {
+			t.Errorf("%s: got no error, want a conflicting settings error", tc)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bgchanger
+
+import (
+	"log"
+	"sort"
+)
+
+// modeDiscovery shows each picture once before showing any again, keeping
+// track of the pictures seen across restarts.
+const modeDiscovery = "discovery"
+
+// seenPictures returns the pictures seen since the last time all of them
+// had been.
+func seenPictures() map[string]bool {
+	seen := make(map[string]bool)
+	for _, p := range loadPersistedState().Seen {
+		seen[p] = true
+	}
+	return seen
+}
+
+// sortUnseenFirst moves the pictures that have not been seen yet, including
+// the ones added since, before the others, keeping their order otherwise.
+// When all of them have been seen, the seen pictures are forgotten and the
+// discovery starts over.
+func sortUnseenFirst(pictures []string) {
+	seen := seenPictures()
+	unseen := 0
+	for _, p := range pictures {
+		if !seen[p] {
+			unseen++
+		}
+	}
+	if unseen == 0 && len(seen) > 0 {
+		log.Printf("All %d pictures have been seen, starting over", len(pictures))
+		if err := updatePersistedState(func(st *persistedState) { st.Seen = nil }); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+	debugf("%d of %d pictures not seen yet", unseen, len(pictures))
+	sort.SliceStable(pictures, func(i, j int) bool { return !seen[pictures[i]] && seen[pictures[j]] })
+}
+
+// recordSeen marks the picture as seen in discovery mode.
+func recordSeen(cfg *Config, filename string) error {
+	if cfg.Mode != modeDiscovery {
+		return nil
+	}
+	return updatePersistedState(func(st *persistedState) {
+		for _, p := range st.Seen {
+			if p == filename {
+				return
+			}
+		}
+		st.Seen = append(st.Seen, filename)
+	})
+}
@@ -0,0 +1,65 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscovery(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 6)
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "discovery"}`, dir)
+	seen := make(map[string]bool)
+	for i := 0; i < len(pictures); i++ {
+		if i == 3 {
+			// a restart halfway through
+			resetState()
+		}
+		ChangeBG(cfg)
+		p := CurrentPicture()
+		if seen[p] {
+			t.Fatalf("change %d: %s shown again before the others %v", i, p, seen)
+		}
+		seen[p] = true
+	}
+	if len(loadPersistedState().Seen) != len(pictures) {
+		t.Errorf("got seen %v, want all the pictures", loadPersistedState().Seen)
+	}
+
+	// all seen, starting over
+	ChangeBG(cfg)
+	if st := loadPersistedState(); len(st.Seen) != 1 || st.Seen[0] != CurrentPicture() {
+		t.Errorf("got seen %v after starting over, want only %s", st.Seen, CurrentPicture())
+	}
+}
+
+func TestDiscoveryPrefersNewPictures(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "discovery"}`, dir)
+	for i := 0; i < 3; i++ {
+		ChangeBG(cfg)
+	}
+	added := filepath.Join(dir, "added.png")
+	if err := os.Rename(writeTestPictures(t, t.TempDir(), 1)[0], added); err != nil {
+		t.Fatal(err)
+	}
+	candidateCache.reset()
+	ChangeBG(cfg)
+	if got := CurrentPicture(); got != added {
+		t.Errorf("got %s, want the new unseen %s", got, added)
+	}
+}
+
+func TestDiscoveryOnlyInDiscoveryMode(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	ChangeBG(testConfig(t, `{"pictures_dir": %q}`, dir))
+	if seen := loadPersistedState().Seen; len(seen) != 0 {
+		t.Errorf("got seen %v outside of discovery mode", seen)
+	}
+}
@@ -0,0 +1,33 @@
+package bgchanger
+
+import "fmt"
+
+// downscale shrinks the picture so that neither side exceeds maxDim,
+// preserving its aspect ratio, and returns the path of the cached result.
+// Pictures that already fit are returned unchanged.
+func downscale(filename string, maxDim int) (string, error) {
+	w, h, err := imageSize(filename)
+	if err != nil {
+		return "", err
+	}
+	if w <= maxDim && h <= maxDim {
+		return filename, nil
+	}
+	cached, ok, err := cachedVariant("downscaled", filename, fmt.Sprintf("max%d", maxDim))
+	if err != nil || ok {
+		return cached, err
+	}
+	nw, nh := maxDim, h*maxDim/w
+	if h > w {
+		nw, nh = w*maxDim/h, maxDim
+	}
+	img, err := loadImage(filename)
+	if err != nil {
+		return "", err
+	}
+	debugf("downscaling '%s' from %dx%d to %dx%d", filename, w, h, nw, nh)
+	if err := writeJPEG(cached, resizeImage(img, clampInt(nw, 1, maxDim), clampInt(nh, 1, maxDim))); err != nil {
+		return "", fmt.Errorf("failed to write downscaled picture: %w", err)
+	}
+	return cached, nil
+}
@@ -0,0 +1,49 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownscale(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	large := writeTestPicture(t, filepath.Join(dir, "large.png"), 400, 100, color.RGBA{10, 20, 30, 255})
+	tall := writeTestPicture(t, filepath.Join(dir, "tall.png"), 50, 200, color.RGBA{10, 20, 30, 255})
+	small := writeTestPicture(t, filepath.Join(dir, "small.png"), 64, 64, color.RGBA{10, 20, 30, 255})
+
+	for _, tc := range []struct {
+		filename string
+		w, h     int
+	}{
+		{large, 100, 25},
+		{tall, 25, 100},
+	} {
+		scaled, err := downscale(tc.filename, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scaled == tc.filename {
+			t.Fatalf("%s was not downscaled", tc.filename)
+		}
+		if w, h, err := imageSize(scaled); err != nil || w != tc.w || h != tc.h {
+			t.Errorf("got %dx%d, %v for %s, want %dx%d", w, h, err, filepath.Base(tc.filename), tc.w, tc.h)
+		}
+		if again, err := downscale(tc.filename, 100); err != nil || again != scaled {
+			t.Errorf("got %s, %v the second time, want the cached %s", again, err, scaled)
+		}
+	}
+	if p, err := downscale(small, 100); err != nil || p != small {
+		t.Errorf("got %s, %v for a small picture, want it unchanged", p, err)
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "max_dimension": 100}`, dir)
+	if err := SetBackground(cfg, large); err != nil {
+		t.Fatal(err)
+	}
+	if uri := setter.get("picture-uri"); !strings.Contains(uri, "downscaled") {
+		t.Errorf("applied %s, want the downscaled copy", uri)
+	}
+}
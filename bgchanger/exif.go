@@ -0,0 +1,221 @@
+package bgchanger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const modeOnThisDay = "on_this_day"
+
+// EXIF tags
+const (
+	exifTagImageDescription = 0x010e
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+)
+
+// maxExifSize bounds the size of the EXIF segment that is read.
+const maxExifSize = 1 << 16
+
+var errNoExifDate = errors.New("no EXIF date")
+
+var exifDateCache = newFileCache(readExifDate)
+
+// exifDate returns the date a picture was taken, from its EXIF metadata.
+// Results are cached by path and modification time.
+func exifDate(filename string) (time.Time, error) {
+	return exifDateCache.get(filename)
+}
+
+// readExifDate reads the DateTimeOriginal EXIF tag of a JPEG file, or the
+// DateTime tag if that is missing.
+func readExifDate(filename string) (time.Time, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer fd.Close()
+	tiff, err := findExifSegment(bufio.NewReader(fd))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseExifDate(tiff)
+}
+
+// findExifSegment returns the TIFF data of the APP1 Exif segment of a JPEG
+// stream.
+func findExifSegment(r *bufio.Reader) ([]byte, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return nil, fmt.Errorf("not a JPEG file")
+	}
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, errNoExifDate
+		}
+		if hdr[0] != 0xff {
+			return nil, fmt.Errorf("invalid JPEG marker")
+		}
+		marker, size := hdr[1], int(binary.BigEndian.Uint16(hdr[2:]))-2
+		// the metadata segments come before the image data
+		if marker == 0xda || marker == 0xd9 || size < 0 {
+			return nil, errNoExifDate
+		}
+		if marker != 0xe1 || size > maxExifSize {
+			if _, err := r.Discard(size); err != nil {
+				return nil, errNoExifDate
+			}
+			continue
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, errNoExifDate
+		}
+		if len(data) > 6 && string(data[:6]) == "Exif\x00\x00" {
+			return data[6:], nil
+		}
+	}
+}
+
+// parseExifDate extracts the date from EXIF TIFF data.
+func parseExifDate(tiff []byte) (time.Time, error) {
+	if len(tiff) < 8 {
+		return time.Time{}, errNoExifDate
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, fmt.Errorf("invalid TIFF header")
+	}
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if off, ok := ifd0[exifTagExifIFD]; ok {
+		if v, ok := readIFD(tiff, order, off)[exifTagDateTimeOriginal]; ok {
+			if t, err := parseExifTime(tiff, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	if v, ok := ifd0[exifTagDateTime]; ok {
+		return parseExifTime(tiff, v)
+	}
+	return time.Time{}, errNoExifDate
+}
+
+// readIFD returns the value, or value offset, of each entry of the IFD at
+// the given offset.
+func readIFD(tiff []byte, order binary.ByteOrder, off uint32) map[uint16]uint32 {
+	entries := make(map[uint16]uint32)
+	if int64(off)+2 > int64(len(tiff)) {
+		return entries
+	}
+	n := int(order.Uint16(tiff[off:]))
+	for i := 0; i < n; i++ {
+		e := int64(off) + 2 + int64(i)*12
+		if e+12 > int64(len(tiff)) {
+			break
+		}
+		entries[order.Uint16(tiff[e:])] = order.Uint32(tiff[e+8:])
+	}
+	return entries
+}
+
+// readExifTitle reads the ImageDescription EXIF tag of a JPEG file, which
+// is empty if there is none.
+func readExifTitle(filename string) (string, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	tiff, err := findExifSegment(bufio.NewReader(fd))
+	if err != nil {
+		return "", nil
+	}
+	return parseExifTitle(tiff), nil
+}
+
+// parseExifTitle extracts the ImageDescription string from EXIF TIFF data.
+func parseExifTitle(tiff []byte) string {
+	if len(tiff) < 8 {
+		return ""
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return ""
+	}
+	off := int64(order.Uint32(tiff[4:]))
+	if off+2 > int64(len(tiff)) {
+		return ""
+	}
+	n := int(order.Uint16(tiff[off:]))
+	for i := 0; i < n; i++ {
+		e := off + 2 + int64(i)*12
+		if e+12 > int64(len(tiff)) {
+			break
+		}
+		if order.Uint16(tiff[e:]) != exifTagImageDescription {
+			continue
+		}
+		// strings of up to 4 bytes are stored in the entry itself
+		count := int64(order.Uint32(tiff[e+4:]))
+		start := e + 8
+		if count > 4 {
+			start = int64(order.Uint32(tiff[e+8:]))
+		}
+		if start+count > int64(len(tiff)) {
+			return ""
+		}
+		return strings.TrimSpace(strings.TrimRight(string(tiff[start:start+count]), "\x00"))
+	}
+	return ""
+}
+
+// parseExifTime parses the "YYYY:MM:DD HH:MM:SS" string at the given offset.
+func parseExifTime(tiff []byte, off uint32) (time.Time, error) {
+	const layout = "2006:01:02 15:04:05"
+	if int64(off)+int64(len(layout)) > int64(len(tiff)) {
+		return time.Time{}, errNoExifDate
+	}
+	return time.ParseInLocation(layout, string(tiff[off:off+uint32(len(layout))]), time.Local)
+}
+
+// filterOnThisDay returns the pictures taken on the same month and day as
+// now in any year, or all of them if none was.
+func filterOnThisDay(pictures []string, now time.Time) []string {
+	var matching []string
+	for _, p := range pictures {
+		t, err := exifDate(p)
+		if err != nil {
+			if err != errNoExifDate {
+				debugf("cannot read EXIF date of '%s': %v", p, err)
+			}
+			continue
+		}
+		if t.Month() == now.Month() && t.Day() == now.Day() {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		log.Printf("No pictures taken on %s, using any picture", now.Format("January 2"))
+		return pictures
+	}
+	return matching
+}
@@ -0,0 +1,102 @@
+package bgchanger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// writeExifJPEG writes a small JPEG with the given ASCII EXIF tags in IFD0,
+// such as exifTagDateTime or exifTagImageDescription.
+func writeExifJPEG(t *testing.T, filename string, tags map[uint16]string) string {
+	t.Helper()
+	var ids []int
+	for id := range tags {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	// TIFF header, then IFD0, then the strings
+	order := binary.LittleEndian
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	ifd := make([]byte, 2+12*len(ids)+4)
+	order.PutUint16(ifd, uint16(len(ids)))
+	var values []byte
+	next := uint32(len(tiff) + len(ifd))
+	for i, id := range ids {
+		v := tags[uint16(id)] + "\x00"
+		e := ifd[2+12*i:]
+		// type 2 is ASCII
+		order.PutUint16(e, uint16(id))
+		order.PutUint16(e[2:], 2)
+		order.PutUint32(e[4:], uint32(len(v)))
+		order.PutUint32(e[8:], next+uint32(len(values)))
+		values = append(values, v...)
+	}
+	tiff = append(append(tiff, ifd...), values...)
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte{0xff, 0xd8, 0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(app1)+2))
+	data = append(append(data, app1...), img.Bytes()[2:]...)
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestExifDate(t *testing.T) {
+	dir := t.TempDir()
+	p := writeExifJPEG(t, filepath.Join(dir, "a.jpg"), map[uint16]string{exifTagDateTime: "2019:03:14 15:09:26"})
+	got, err := exifDate(p)
+	if want := time.Date(2019, 3, 14, 15, 9, 26, 0, time.Local); err != nil || !got.Equal(want) {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+	plain := writeTestPictures(t, dir, 1)[0]
+	if _, err := exifDate(plain); err == nil {
+		t.Errorf("got an EXIF date for a PNG")
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	today := time.Now()
+	other := today.AddDate(0, 0, 3)
+	var matching []string
+	for i, year := range []int{2010, 2020} {
+		date := time.Date(year, today.Month(), today.Day(), 12, 0, 0, 0, time.Local).Format("2006:01:02 15:04:05")
+		name := filepath.Join(dir, string(rune('a'+i))+".jpg")
+		matching = append(matching, writeExifJPEG(t, name, map[uint16]string{exifTagDateTime: date}))
+	}
+	writeExifJPEG(t, filepath.Join(dir, "other.jpg"), map[uint16]string{exifTagDateTime: other.Format("2006:01:02 15:04:05")})
+	writeTestPictures(t, dir, 2)
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "on_this_day"}`, dir)
+	picked := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked[p] = true
+	}
+	if len(picked) != 2 || !picked[matching[0]] || !picked[matching[1]] {
+		t.Errorf("picked %v, want only %v", picked, matching)
+	}
+
+	// every picture is used if none was taken on this day
+	all := []string{matching[0], filepath.Join(dir, "0.png")}
+	if got := filterOnThisDay(all, other.AddDate(0, 1, 0)); len(got) != len(all) {
+		t.Errorf("got %v with no pictures taken on this day, want all of them", got)
+	}
+}
@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// explanation maps every picture seen while explaining to the reason it was
+// excluded, or to the empty string if it is a candidate. It is nil, and the
+// filters don't record anything, unless ExplainCandidates is running.
+var explanation map[string]string
+
+// excluded records that filename was excluded for the given reason. Only the
+// first reason is kept, since later filters never see the picture.
+func excluded(filename, reason string) {
+	if explanation == nil {
+		return
+	}
+	if _, ok := explanation[filename]; !ok {
+		explanation[filename] = reason
+	}
+}
+
+// explainStep records the pictures in before that a filter dropped from
+// after as excluded for the given reason.
+func explainStep(reason string, before, after []string) {
+	if explanation == nil {
+		return
+	}
+	kept := make(map[string]bool, len(after))
+	for _, p := range after {
+		kept[p] = true
+	}
+	for _, p := range before {
+		if !kept[p] {
+			excluded(p, reason)
+		}
+	}
+}
+
+// ExplainCandidates prints every picture found in the configured sources,
+// sorted, and whether it is a candidate or the reason it was excluded.
+func ExplainCandidates(cfg *Config) error {
+	explanation = make(map[string]string)
+	defer func() { explanation = nil }()
+	for _, src := range configuredSources(cfg) {
+		pictures, err := scanSource(cfg, &src)
+		if err != nil {
+			return err
+		}
+		pictures = filterCandidates(cfg, pictures, nil, time.Now())
+		for _, p := range pictures {
+			explanation[p] = ""
+		}
+	}
+	files := make([]string, 0, len(explanation))
+	for f := range explanation {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		if reason := explanation[f]; reason != "" {
+			fmt.Printf("excluded\t%s\t%s\n", f, reason)
+		} else {
+			fmt.Printf("included\t%s\n", f)
+		}
+	}
+	return nil
+}
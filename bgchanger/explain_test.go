@@ -0,0 +1,69 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplainCandidates(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 4)
+	if err := os.Mkdir(filepath.Join(dir, "sub.png"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a picture"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.png"), filepath.Join(dir, "broken.png")); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_image_cooldown": "1h"}`, dir)
+	if err := blockPicture(cfg, pictures[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordShown(cfg, pictures[2], time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// a cached listing must not hide the files left out of it
+	if _, err := ScanCandidates(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = ExplainCandidates(cfg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		pictures[0]:                      "included",
+		pictures[1]:                      "excluded blocked",
+		pictures[2]:                      "excluded shown within per_image_cooldown",
+		pictures[3]:                      "included",
+		filepath.Join(dir, "sub.png"):    "excluded directory",
+		filepath.Join(dir, "notes.txt"):  "excluded unsupported extension",
+		filepath.Join(dir, "broken.png"): "excluded broken symlink",
+	}
+	got := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			t.Fatalf("invalid line %q", line)
+		}
+		got[fields[1]] = strings.Join(append(fields[:1:1], fields[2:]...), " ")
+	}
+	for f, w := range want {
+		if got[f] != w {
+			t.Errorf("%s: got %q, want %q", filepath.Base(f), got[f], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d files explained, want %d:\n%s", len(got), len(want), out)
+	}
+	if explanation != nil {
+		t.Error("the explanation is still being recorded")
+	}
+}
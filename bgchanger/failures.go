@@ -0,0 +1,63 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// failures counts the consecutive failed changes, for failure_alert_after.
+var failures = struct {
+	sync.Mutex
+	count int
+	// paused is set when the automatic changes are paused after too many
+	// failures, until a change succeeds.
+	paused bool
+}{}
+
+// OnFailureAlert is called with the number of consecutive failures and the
+// last error when they reach failure_alert_after.
+var OnFailureAlert = func(count int, err error) {}
+
+// recordOutcome counts the consecutive failures, resetting them on success,
+// and alerts once when they reach failure_alert_after, pausing the automatic
+// changes if pause_on_failures is set.
+func recordOutcome(cfg *Config, err error) {
+	failures.Lock()
+	if err == nil {
+		if failures.paused {
+			log.Printf("Background change succeeded, resuming the automatic changes")
+		}
+		failures.count = 0
+		failures.paused = false
+		failures.Unlock()
+		return
+	}
+	failures.count++
+	count := failures.count
+	alert := cfg.FailureAlertAfter > 0 && count == cfg.FailureAlertAfter
+	if alert && cfg.PauseOnFailures {
+		failures.paused = true
+	}
+	failures.Unlock()
+	if !alert {
+		return
+	}
+	msg := fmt.Sprintf(Tr("changes_failing"), count, err)
+	log.Printf("Error: %s", msg)
+	if cfg.PauseOnFailures {
+		log.Printf("Pausing the automatic changes until a change succeeds")
+	}
+	if err := runCommand("notify-send", "--app-name="+Progname, "--urgency=critical", Progname, msg); err != nil {
+		debugf("cannot show the failure notification: %v", err)
+	}
+	OnFailureAlert(count, err)
+}
+
+// failuresPaused reports whether the automatic changes are paused after too
+// many failures.
+func failuresPaused() bool {
+	failures.Lock()
+	defer failures.Unlock()
+	return failures.paused
+}
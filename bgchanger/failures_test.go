@@ -0,0 +1,98 @@
+package bgchanger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFailureAlert(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "failure_alert_after": 3, "pause_on_failures": true}`, dir)
+	var notifications []string
+	runCommand = func(name string, args ...string) error {
+		if name == "notify-send" {
+			notifications = append(notifications, strings.Join(args, " "))
+		}
+		return nil
+	}
+	var alerts []int
+	old := OnFailureAlert
+	OnFailureAlert = func(count int, err error) { alerts = append(alerts, count) }
+	t.Cleanup(func() { OnFailureAlert = old })
+
+	setter.setErr(errors.New("broken session"))
+	for i := 0; i < 2; i++ {
+		ChangeBG(cfg)
+	}
+	if len(alerts) != 0 || len(notifications) != 0 || failuresPaused() {
+		t.Fatalf("alerted after 2 failures: %v, %v", alerts, notifications)
+	}
+	ChangeBG(cfg)
+	if len(alerts) != 1 || alerts[0] != 3 {
+		t.Fatalf("got alerts %v after 3 failures, want [3]", alerts)
+	}
+	if len(notifications) != 1 || !strings.Contains(notifications[0], "--urgency=critical") ||
+		!strings.Contains(notifications[0], "broken session") {
+		t.Errorf("got notifications %v, want a critical one with the error", notifications)
+	}
+	if !failuresPaused() || !getStatus().Paused {
+		t.Error("the automatic changes are not paused after the alert")
+	}
+	// the paused changes are skipped, without more alerts
+	AutoChangeBG(cfg)
+	ChangeBG(cfg)
+	if len(alerts) != 1 {
+		t.Errorf("got alerts %v, want a single one", alerts)
+	}
+
+	// a success resets the counter and resumes the changes
+	setter.setErr(nil)
+	ChangeBG(cfg)
+	if failuresPaused() {
+		t.Error("still paused after a success")
+	}
+	setter.setErr(errors.New("broken again"))
+	for i := 0; i < 2; i++ {
+		ChangeBG(cfg)
+	}
+	if len(alerts) != 1 {
+		t.Errorf("got alerts %v, the success did not reset the counter", alerts)
+	}
+	ChangeBG(cfg)
+	if len(alerts) != 2 || alerts[1] != 3 {
+		t.Errorf("got alerts %v, want a second alert after 3 new failures", alerts)
+	}
+}
+
+func TestFailureAlertWithoutPause(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "failure_alert_after": 1}`, dir)
+	alerted := false
+	old := OnFailureAlert
+	OnFailureAlert = func(int, error) { alerted = true }
+	t.Cleanup(func() { OnFailureAlert = old })
+	setter.setErr(errors.New("broken"))
+	ChangeBG(cfg)
+	if !alerted {
+		t.Error("no alert after the first failure")
+	}
+	if failuresPaused() {
+		t.Error("paused without pause_on_failures")
+	}
+}
+
+func TestFailureAlertValidate(t *testing.T) {
+	for _, c := range []string{
+		`{"pictures_dir": "/p", "failure_alert_after": -1}`,
+		`{"pictures_dir": "/p", "pause_on_failures": true}`,
+	} {
+		if _, err := ParseConfig([]byte(c)); err == nil {
+			t.Errorf("%s: expected an error", c)
+		}
+	}
+}
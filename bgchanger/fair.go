@@ -0,0 +1,96 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+const modeFair = "fair"
+
+// displayTimes holds how long each picture has been the background, by path.
+var displayTimes = struct {
+	sync.Mutex
+	loaded bool
+	times  map[string]time.Duration
+}{}
+
+func displayTimesFile() string {
+	return path.Join(configdir.LocalConfig(Progname), "display_times.json")
+}
+
+// loadDisplayTimesLocked reads the display times file, if not done already.
+// The displayTimes lock must be held.
+func loadDisplayTimesLocked() {
+	if displayTimes.loaded {
+		return
+	}
+	displayTimes.loaded = true
+	displayTimes.times = make(map[string]time.Duration)
+	data, err := os.ReadFile(displayTimesFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read display times: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &displayTimes.times); err != nil {
+		log.Printf("Warning: cannot parse display times: %v", err)
+	}
+}
+
+// addDisplayTime adds d to the display time of the given picture, and saves
+// the display times.
+func addDisplayTime(filename string, d time.Duration) error {
+	displayTimes.Lock()
+	defer displayTimes.Unlock()
+	loadDisplayTimesLocked()
+	displayTimes.times[filename] += d
+	data, err := json.MarshalIndent(displayTimes.times, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal display times: %w", err)
+	}
+	if err := writeFileAtomic(displayTimesFile(), data); err != nil {
+		return fmt.Errorf("failed to save display times: %w", err)
+	}
+	return nil
+}
+
+// sortByDisplayTime sorts the pictures from the one that has been the
+// background for the shortest time to the longest, in random order among
+// equals. Pictures that were never shown come first.
+func sortByDisplayTime(pictures []string) {
+	displayTimes.Lock()
+	loadDisplayTimesLocked()
+	times := make(map[string]time.Duration, len(pictures))
+	for _, p := range pictures {
+		times[p] = displayTimes.times[p]
+	}
+	displayTimes.Unlock()
+	rand.Shuffle(len(pictures), func(i, j int) { pictures[i], pictures[j] = pictures[j], pictures[i] })
+	sort.SliceStable(pictures, func(i, j int) bool { return times[pictures[i]] < times[pictures[j]] })
+}
+
+// SaveDisplayTime accounts the time the current picture has been the
+// background so far. It is done on every change, and should be done before
+// exiting.
+func SaveDisplayTime() {
+	state.Lock()
+	current, since := state.current, state.currentSince
+	state.currentSince = time.Now()
+	state.Unlock()
+	if current == "" {
+		return
+	}
+	if err := addDisplayTime(current, time.Since(since)); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
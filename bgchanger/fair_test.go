@@ -0,0 +1,64 @@
+package bgchanger
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// displayTimeVariance changes the background n times, showing each picture
+// for a random time, and returns the variance of the display times of the
+// pictures.
+func displayTimeVariance(t *testing.T, mode string, n int) float64 {
+	t.Helper()
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 8)
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": %q}`, dir, mode)
+	for i := 0; i < n; i++ {
+		ChangeBG(cfg)
+		state.Lock()
+		state.currentSince = state.currentSince.Add(-time.Duration(1+rand.Intn(10)) * time.Minute)
+		state.Unlock()
+	}
+	SaveDisplayTime()
+
+	displayTimes.Lock()
+	defer displayTimes.Unlock()
+	var sum, sumSquares float64
+	for _, p := range pictures {
+		m := displayTimes.times[p].Minutes()
+		sum += m
+		sumSquares += m * m
+	}
+	mean := sum / float64(len(pictures))
+	return sumSquares/float64(len(pictures)) - mean*mean
+}
+
+func TestFairModeEqualizesDisplayTime(t *testing.T) {
+	random := displayTimeVariance(t, "random", 200)
+	fair := displayTimeVariance(t, modeFair, 200)
+	if fair*4 > random {
+		t.Errorf("display time variance is %.1f in fair mode and %.1f in random mode, want it much lower", fair, random)
+	}
+}
+
+func TestFairModePrefersNewPictures(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	for _, p := range pictures[:2] {
+		if err := addDisplayTime(p, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": %q}`, dir, modeFair)
+	if p, err := PickPicture(cfg); err != nil || p != pictures[2] {
+		t.Errorf("picked %s, %v, want the picture never shown %s", p, err, pictures[2])
+	}
+	// the display times are persisted
+	resetState()
+	if p, err := PickPicture(cfg); err != nil || p != pictures[2] {
+		t.Errorf("picked %s, %v after a restart, want %s", p, err, pictures[2])
+	}
+}
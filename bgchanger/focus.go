@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+)
+
+// defaultFocusColor is the color shown in focus mode without focus_picture
+// or focus_color.
+const defaultFocusColor = "#2e3436"
+
+// FocusEnabled reports whether the focus mode is on. It is saved across
+// restarts.
+func FocusEnabled() bool {
+	return loadPersistedState().Focus
+}
+
+// setFocusBackground shows the minimal background of the focus mode.
+func setFocusBackground(cfg *Config) error {
+	if cfg.FocusPicture != "" {
+		return gsettingsSet(backgroundSchema, "picture-uri", "file://"+cfg.FocusPicture)
+	}
+	color := cfg.FocusColor
+	if color == "" {
+		color = defaultFocusColor
+	}
+	return setColors(color, color, "solid")
+}
+
+// SetFocus turns the focus mode on, showing a minimal background and pausing
+// the automatic changes, or off, going back to the exact background shown
+// before.
+func SetFocus(cfg *Config, enabled bool) error {
+	if enabled == FocusEnabled() {
+		return nil
+	}
+	if enabled {
+		previous := CurrentPicture()
+		if previous == "" {
+			previous = desktopPicture()
+		}
+		if err := updatePersistedState(func(st *persistedState) {
+			st.Focus, st.FocusPrevious = true, previous
+		}); err != nil {
+			return err
+		}
+		if err := setFocusBackground(cfg); err != nil {
+			return fmt.Errorf("failed to set focus background: %w", err)
+		}
+		log.Printf("Focus mode on")
+		return nil
+	}
+	previous := loadPersistedState().FocusPrevious
+	if err := updatePersistedState(func(st *persistedState) {
+		st.Focus, st.FocusPrevious = false, ""
+	}); err != nil {
+		return err
+	}
+	log.Printf("Focus mode off")
+	if cfg.FocusPicture == "" {
+		if err := restorePictureOptions(cfg); err != nil {
+			return err
+		}
+	}
+	if previous == "" {
+		ChangeBG(cfg)
+		return nil
+	}
+	return SetBackground(cfg, previous)
+}
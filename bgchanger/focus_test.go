@@ -0,0 +1,91 @@
+package bgchanger
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFocusMode(t *testing.T) {
+	setter := testEnv(t)
+	setter.preset(backgroundSchema, "picture-options", "'scaled'")
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "focus_color": "#101010"}`, dir)
+	if err := SetBackground(cfg, pictures[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFocus(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	if !FocusEnabled() || setter.get("picture-options") != "none" || setter.get("primary-color") != "#101010" {
+		t.Fatalf("got focus %v, picture-options %s and primary-color %s, want the focus color", FocusEnabled(), setter.get("picture-options"), setter.get("primary-color"))
+	}
+	// the automatic changes are paused
+	AutoChangeBG(cfg)
+	if uris := setter.values("picture-uri"); len(uris) != 1 {
+		t.Errorf("got picture-uri %v in focus mode, want no change", uris)
+	}
+
+	// the previous background is restored after a restart too
+	resetState()
+	if err := SetFocus(cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if FocusEnabled() {
+		t.Error("focus mode is still on")
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[1] {
+		t.Errorf("got picture-uri %s after focus mode, want %s", uri, pictures[1])
+	}
+	if options := setter.get("picture-options"); options != "scaled" {
+		t.Errorf("got picture-options %s after focus mode, want the previous scaled", options)
+	}
+}
+
+func TestFocusModePictureOptions(t *testing.T) {
+	setter := testEnv(t)
+	setter.preset(backgroundSchema, "picture-options", "'scaled'")
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q, "picture_options": "centered"}`, dir)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFocus(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFocus(cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if options := setter.get("picture-options"); options != "centered" {
+		t.Errorf("got picture-options %s after focus mode, want picture_options", options)
+	}
+	// the options GNOME had are restored once picture_options is removed
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q}`, dir))
+	if options := setter.get("picture-options"); options != "scaled" {
+		t.Errorf("got picture-options %s without picture_options, want the previous scaled", options)
+	}
+}
+
+func TestChangeOnStartInFocusMode(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "change_on_start": true, "startup_delay": "0s"}`, dir)
+	if err := SetFocus(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		RunLoop(cfg, nil, quit)
+		close(done)
+	}()
+	time.Sleep(200 * time.Millisecond)
+	quit <- os.Interrupt
+	<-done
+	if uris := setter.values("picture-uri"); len(uris) != 0 {
+		t.Errorf("changed the background to %v on start in focus mode", uris)
+	}
+}
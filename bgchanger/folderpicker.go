@@ -0,0 +1,107 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoFolderPicker is returned by ChoosePicturesDir when neither zenity nor
+// kdialog is installed.
+var ErrNoFolderPicker = errors.New("no folder picker found, install zenity or kdialog")
+
+// folderPickers are the commands showing a folder selection dialog, which
+// print the chosen folder, or exit with status 1 when cancelled.
+var folderPickers = []func(start, title string) []string{
+	func(start, title string) []string {
+		return []string{"zenity", "--file-selection", "--directory", "--title=" + title, "--filename=" + start + "/"}
+	},
+	func(start, title string) []string {
+		return []string{"kdialog", "--title", title, "--getexistingdirectory", start}
+	},
+}
+
+// pickFolder shows a folder selection dialog starting at start, and returns
+// the chosen folder, or false if the dialog was cancelled.
+func pickFolder(start string) (string, bool, error) {
+	for _, picker := range folderPickers {
+		args := picker(start, Tr("choose_folder"))
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		// no timeout, the dialog waits for the user
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to run %s: %w", args[0], err)
+		}
+		dir, ok := parsePickerOutput(string(out))
+		return dir, ok, nil
+	}
+	return "", false, ErrNoFolderPicker
+}
+
+// parsePickerOutput returns the folder printed by a folder picker, or false
+// if it printed none.
+func parsePickerOutput(out string) (string, bool) {
+	dir := strings.TrimRight(out, "\r\n")
+	return dir, dir != ""
+}
+
+// setPicturesDir sets the top-level pictures_dir in the config file to dir,
+// keeping the other settings, including the pictures_dir of the profiles, as
+// they are. Remote configs are refused, the file being only a cached copy.
+func setPicturesDir(configFile, dir string) error {
+	if isRemoteConfigFile(configFile) {
+		return fmt.Errorf("cannot set pictures_dir in a remote config")
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("failed to unmarshal config file: %w", err)
+	}
+	if settings == nil {
+		settings = make(map[string]json.RawMessage)
+	}
+	if settings["pictures_dir"], err = json.Marshal(dir); err != nil {
+		return err
+	}
+	if data, err = json.MarshalIndent(settings, "", "    "); err != nil {
+		return fmt.Errorf("failed to marshal config file: %w", err)
+	}
+	if err := writeFileAtomic(configFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// ChoosePicturesDir shows a folder selection dialog, and sets pictures_dir in
+// the config file to the chosen folder. It returns the reloaded config, or
+// nil if the dialog was cancelled.
+func ChoosePicturesDir(configFile string, cfg *Config) (*Config, error) {
+	if isRemoteConfigFile(configFile) {
+		return nil, fmt.Errorf("cannot set pictures_dir in a remote config")
+	}
+	dir, ok, err := pickFolder(cfg.PicturesDir)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if len(cfg.Sources) > 0 {
+		log.Printf("Warning: setting pictures_dir, but the pictures come from sources")
+	}
+	if err := setPicturesDir(configFile, dir); err != nil {
+		return nil, err
+	}
+	log.Printf("Pictures folder set to '%s'", dir)
+	return ReloadConfig(configFile)
+}
@@ -0,0 +1,136 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePickerOutput(t *testing.T) {
+	for out, want := range map[string]string{
+		"/home/me/Pictures\n":      "/home/me/Pictures",
+		"/home/me/My Pictures\r\n": "/home/me/My Pictures",
+		"/tmp":                     "/tmp",
+		"\n":                       "",
+		"":                         "",
+	} {
+		got, ok := parsePickerOutput(out)
+		if got != want || ok != (want != "") {
+			t.Errorf("%q: got %q, %v, want %q", out, got, ok, want)
+		}
+	}
+}
+
+func TestSetPicturesDir(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	// the profile comes first, and must keep its own pictures_dir
+	if err := os.WriteFile(configFile, []byte(`{
+    "profiles": {"work": {"pictures_dir": "/work"}},
+    "pictures_dir": "/old",
+    "interval": "10m"
+}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setPicturesDir(configFile, `/new "dir"`); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		PicturesDir string `json:"pictures_dir"`
+		Interval    string `json:"interval"`
+		Profiles    map[string]struct {
+			PicturesDir string `json:"pictures_dir"`
+		} `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid config written: %v\n%s", err, data)
+	}
+	if got.PicturesDir != `/new "dir"` || got.Interval != "10m" || got.Profiles["work"].PicturesDir != "/work" {
+		t.Errorf("got %+v, want only the top-level pictures_dir changed", got)
+	}
+
+	// without a pictures_dir yet
+	if err := os.WriteFile(configFile, []byte(`{"sources": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setPicturesDir(configFile, "/pictures"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PicturesDir != "/pictures" {
+		t.Errorf("got pictures_dir %q, want /pictures", cfg.PicturesDir)
+	}
+}
+
+func TestSetPicturesDirRemote(t *testing.T) {
+	testEnv(t)
+	cached := remoteConfigCache()
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cached, []byte(`{"pictures_dir": "/remote"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, configFile := range []string{cached, "https://example.com/config.json"} {
+		if err := setPicturesDir(configFile, "/local"); err == nil {
+			t.Errorf("%s: expected an error for a remote config", configFile)
+		}
+	}
+	if data, _ := os.ReadFile(cached); string(data) != `{"pictures_dir": "/remote"}` {
+		t.Errorf("the cached remote config was changed to %s", data)
+	}
+}
+
+// fakePicker installs a zenity printing out and exiting with status.
+func fakePicker(t *testing.T, out string, status int) {
+	t.Helper()
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	script := "#!/bin/sh\nprintf '%s\\n' '" + out + "'\nexit " + string(rune('0'+status)) + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "zenity"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChoosePicturesDir(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	chosen := filepath.Join(dir, "chosen")
+	writeTestPictures(t, chosen, 1)
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "/old"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakePicker(t, chosen, 0)
+	newCfg, err := ChoosePicturesDir(configFile, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newCfg == nil || newCfg.PicturesDir != chosen {
+		t.Fatalf("got %+v, want the config with pictures_dir %s", newCfg, chosen)
+	}
+
+	// cancelled
+	fakePicker(t, "", 1)
+	if newCfg, err := ChoosePicturesDir(configFile, cfg); newCfg != nil || err != nil {
+		t.Errorf("got %v, %v when cancelled, want nothing", newCfg, err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := ChoosePicturesDir(configFile, cfg); err != ErrNoFolderPicker {
+		t.Errorf("got %v without a picker, want ErrNoFolderPicker", err)
+	}
+}
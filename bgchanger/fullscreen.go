@@ -0,0 +1,28 @@
+package bgchanger
+
+import (
+	"regexp"
+	"strings"
+)
+
+var windowIDRegexp = regexp.MustCompile(`window id # (0x[0-9a-fA-F]+)`)
+
+// fullscreenActive reports whether the active window is fullscreen, according
+// to its _NET_WM_STATE. If it cannot be determined, it returns false.
+func fullscreenActive() bool {
+	out, err := commandOutput("xprop", "-root", "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		debugf("Cannot get the active window: %v", err)
+		return false
+	}
+	m := windowIDRegexp.FindStringSubmatch(string(out))
+	if m == nil || m[1] == "0x0" {
+		return false
+	}
+	out, err = commandOutput("xprop", "-id", m[1], "_NET_WM_STATE")
+	if err != nil {
+		debugf("Cannot get the state of window %s: %v", m[1], err)
+		return false
+	}
+	return strings.Contains(string(out), "_NET_WM_STATE_FULLSCREEN")
+}
@@ -0,0 +1,60 @@
+package bgchanger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeWindows makes xprop report an active window with the given state.
+func fakeWindows(state string) {
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		if name != "xprop" {
+			return nil, errNoDesktop
+		}
+		switch strings.Join(args, " ") {
+		case "-root _NET_ACTIVE_WINDOW":
+			return []byte("_NET_ACTIVE_WINDOW(WINDOW): window id # 0x3a00007\n"), nil
+		case "-id 0x3a00007 _NET_WM_STATE":
+			return []byte("_NET_WM_STATE(ATOM) = " + state + "\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected xprop %v", args)
+	}
+}
+
+func TestFullscreenActive(t *testing.T) {
+	testEnv(t)
+	if fullscreenActive() {
+		t.Error("fullscreen without a desktop")
+	}
+	fakeWindows("_NET_WM_STATE_FULLSCREEN, _NET_WM_STATE_FOCUSED")
+	if !fullscreenActive() {
+		t.Error("fullscreen window not detected")
+	}
+	fakeWindows("_NET_WM_STATE_MAXIMIZED_VERT, _NET_WM_STATE_MAXIMIZED_HORZ")
+	if fullscreenActive() {
+		t.Error("maximized window detected as fullscreen")
+	}
+}
+
+func TestSkipWhenFullscreen(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "skip_when_fullscreen": true}`, dir)
+	fakeWindows("_NET_WM_STATE_FULLSCREEN")
+	AutoChangeBG(cfg)
+	if n := len(setter.values("picture-uri")); n != 0 {
+		t.Fatalf("got %d changes with a fullscreen window, want none", n)
+	}
+	ManualChangeBG(cfg)
+	if n := len(setter.values("picture-uri")); n != 1 {
+		t.Fatalf("got %d manual changes with a fullscreen window, want 1", n)
+	}
+	resetState()
+	fakeWindows("")
+	AutoChangeBG(cfg)
+	if n := len(setter.values("picture-uri")); n != 2 {
+		t.Errorf("got %d changes without a fullscreen window, want 2", n)
+	}
+}
@@ -0,0 +1,69 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+)
+
+const modeGradient = "gradient"
+
+var hexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+var shadingTypes = map[string]bool{
+	"solid":      true,
+	"vertical":   true,
+	"horizontal": true,
+}
+
+// validateGradient checks the colors and shading type used by the gradient
+// mode.
+func validateGradient(cfg *Config) error {
+	if !hexColorRegexp.MatchString(cfg.PrimaryColor) {
+		return fmt.Errorf("invalid primary_color '%s', want #rrggbb", cfg.PrimaryColor)
+	}
+	if cfg.SecondaryColor != "" && !hexColorRegexp.MatchString(cfg.SecondaryColor) {
+		return fmt.Errorf("invalid secondary_color '%s', want #rrggbb", cfg.SecondaryColor)
+	}
+	if cfg.ColorShadingType != "" && !shadingTypes[cfg.ColorShadingType] {
+		return fmt.Errorf("invalid color_shading_type '%s', want solid, vertical or horizontal", cfg.ColorShadingType)
+	}
+	return nil
+}
+
+// setGradient replaces the background picture with the configured colors.
+func setGradient(cfg *Config) error {
+	secondary, shading := cfg.SecondaryColor, cfg.ColorShadingType
+	if secondary == "" {
+		secondary = cfg.PrimaryColor
+	}
+	if shading == "" {
+		shading = "solid"
+		if cfg.SecondaryColor != "" {
+			shading = "vertical"
+		}
+	}
+	if err := setColors(cfg.PrimaryColor, secondary, shading); err != nil {
+		return err
+	}
+	log.Printf("Background changed to %s gradient from %s to %s", shading, cfg.PrimaryColor, secondary)
+	return nil
+}
+
+// setColors hides the background picture, showing the given colors instead.
+// The picture-options are saved, to restore them with restorePictureOptions.
+func setColors(primary, secondary, shading string) error {
+	savePictureOptions()
+	settings := [][2]string{
+		{"picture-options", "none"},
+		{"primary-color", primary},
+		{"secondary-color", secondary},
+		{"color-shading-type", shading},
+	}
+	for _, s := range settings {
+		if err := gsettingsSet(backgroundSchema, s[0], s[1]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", s[0], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package bgchanger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGradientMode(t *testing.T) {
+	setter := testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/nonexistent", "mode": "gradient",
+		"primary_color": "#1d3557", "secondary_color": "#457B9D", "color_shading_type": "horizontal"}`)
+	ChangeBG(cfg)
+	want := []string{
+		backgroundSchema + " picture-options none",
+		backgroundSchema + " primary-color #1d3557",
+		backgroundSchema + " secondary-color #457B9D",
+		backgroundSchema + " color-shading-type horizontal",
+	}
+	if !reflect.DeepEqual(setter.sets, want) {
+		t.Errorf("got settings %q, want %q", setter.sets, want)
+	}
+
+	setter = testEnv(t)
+	cfg = testConfig(t, `{"pictures_dir": "/p", "mode": "gradient", "primary_color": "#abc"}`)
+	ChangeBG(cfg)
+	if s := setter.get("secondary-color"); s != "#abc" {
+		t.Errorf("got secondary-color %s, want the primary color", s)
+	}
+	if s := setter.get("color-shading-type"); s != "solid" {
+		t.Errorf("got color-shading-type %s, want solid", s)
+	}
+
+	for _, config := range []string{
+		`{"pictures_dir": "/p", "mode": "gradient"}`,
+		`{"pictures_dir": "/p", "mode": "gradient", "primary_color": "#12345"}`,
+		`{"pictures_dir": "/p", "mode": "gradient", "primary_color": "#123456", "secondary_color": "blue"}`,
+		`{"pictures_dir": "/p", "mode": "gradient", "primary_color": "#123456", "color_shading_type": "radial"}`,
+	} {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Errorf("%s accepted", config)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	backgroundSchema  = "org.gnome.desktop.background"
+	screensaverSchema = "org.gnome.desktop.screensaver"
+	interfaceSchema   = "org.gnome.desktop.interface"
+)
+
+// BackgroundSetter applies and reads desktop settings, like the background
+// picture.
+type BackgroundSetter interface {
+	Set(schema, key, value string) error
+	// Get returns the value of a setting in the format printed by gsettings
+	// get, e.g. 'prefer-dark' for a string.
+	Get(schema, key string) (string, error)
+}
+
+// GSettings is a BackgroundSetter using the gsettings command.
+type GSettings struct{}
+
+// Set implements BackgroundSetter.
+func (GSettings) Set(schema, key, value string) error {
+	return runCommand("gsettings", "set", schema, key, value)
+}
+
+// Get implements BackgroundSetter.
+func (GSettings) Get(schema, key string) (string, error) {
+	out, err := commandOutput("gsettings", "get", schema, key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Setter applies all the desktop settings. Replace it to apply them some other
+// way.
+var Setter BackgroundSetter = GSettings{}
+
+func gsettingsSet(schema, key, value string) error {
+	return Setter.Set(schema, key, value)
+}
+
+func gsettingsGet(schema, key string) (string, error) {
+	return Setter.Get(schema, key)
+}
+
+// CheckBackend returns an error explaining what's missing if the background
+// cannot be changed, i.e. if Setter is the default one and gsettings is not
+// installed, as on desktops other than GNOME.
+func CheckBackend() error {
+	if _, ok := Setter.(GSettings); !ok {
+		return nil
+	}
+	if _, err := exec.LookPath("gsettings"); err != nil {
+		return fmt.Errorf("gsettings was not found, %s needs GNOME to change the background", Progname)
+	}
+	return nil
+}
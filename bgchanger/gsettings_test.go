@@ -0,0 +1,31 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBackend(t *testing.T) {
+	testEnv(t)
+	// the fake setter of the tests replaces gsettings
+	if err := CheckBackend(); err != nil {
+		t.Errorf("got %v with a custom Setter, want no error", err)
+	}
+
+	Setter = GSettings{}
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	err := CheckBackend()
+	if err == nil || !strings.Contains(err.Error(), "gsettings was not found") {
+		t.Errorf("got %v without gsettings, want an error saying it is missing", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bin, "gsettings"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBackend(); err != nil {
+		t.Errorf("got %v with gsettings installed, want no error", err)
+	}
+}
@@ -0,0 +1,142 @@
+package bgchanger
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ChangeTicker returns a channel that fires at the effective interval, and a
+// function to stop it. A non-positive interval means "don't change
+// background", in which case the returned channel is nil and never fires.
+func ChangeTicker(cfg *Config) (<-chan time.Time, func()) {
+	scheduleNextChange(cfg)
+	interval := EffectiveInterval(cfg)
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	log.Printf("Changing background picture every %s", interval)
+	t := time.NewTicker(time.Duration(interval))
+	return t.C, t.Stop
+}
+
+// defaultStartupDelay gives the desktop shell some time to settle after login
+// before the first change, otherwise the change may not stick.
+const defaultStartupDelay = 3 * time.Second
+
+// StartupTimer returns a channel that fires once, after the configured
+// startup delay.
+func StartupTimer(cfg *Config) <-chan time.Time {
+	if d := time.Duration(*cfg.StartupDelay); d > 0 {
+		log.Printf("Waiting %s before starting", d)
+		return time.After(d)
+	}
+	return time.After(0)
+}
+
+// ChangeSignals returns a channel that receives SIGUSR1, which requests an
+// immediate background change.
+func ChangeSignals() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	return ch
+}
+
+// TrayAvailable reports whether there is a graphical session with a status
+// notifier host that the tray icon can attach to.
+func TrayAvailable() bool {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return false
+	}
+	out, err := commandOutput(
+		"gdbus", "call", "--session",
+		"--dest", "org.freedesktop.DBus",
+		"--object-path", "/org/freedesktop/DBus",
+		"--method", "org.freedesktop.DBus.NameHasOwner", "org.kde.StatusNotifierWatcher",
+	)
+	if err != nil {
+		// cannot tell, let systray try
+		return true
+	}
+	return strings.Contains(string(out), "true")
+}
+
+// RunHeadless changes the background at the configured interval and on
+// SIGUSR1, without a tray icon. It returns on SIGINT or SIGTERM.
+func RunHeadless(cfg *Config) {
+	log.Printf("Running headless, send SIGUSR1 to change background")
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	RunLoop(cfg, nil, quit)
+}
+
+// TrayEvent is an action of the tray menu, run by the main loop so that it
+// can use the config. It returns whether it may have changed the interval,
+// to restart the timer of the changes.
+type TrayEvent func() bool
+
+// OnIntervalChange is called by the main loop when the effective interval
+// changes, e.g. to update the menu.
+var OnIntervalChange = func() {}
+
+// RunLoop is the main loop, with or without a tray icon: it changes the
+// background at the configured interval and on SIGUSR1, follows the
+// watchers, and runs the control requests and the tray events, if any. The
+// config is only used from this loop. It returns when quit receives, never
+// if quit is nil.
+func RunLoop(cfg *Config, trayEvents <-chan TrayEvent, quit <-chan os.Signal) {
+	var (
+		timer     <-chan time.Time
+		stopTimer = func() {}
+		startup   = StartupTimer(cfg)
+		midnight  = CalendarTimer()
+	)
+	defer func() { stopTimer() }()
+	restartTimer := func() {
+		stopTimer()
+		timer, stopTimer = ChangeTicker(cfg)
+	}
+	sigs := ChangeSignals()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-startup:
+			if cfg.ChangeOnStart {
+				AutoChangeBG(cfg)
+			}
+			restartTimer()
+		case event := <-trayEvents:
+			if event() {
+				restartTimer()
+			}
+		case <-IntervalChanges():
+			restartTimer()
+			OnIntervalChange()
+		case <-DirectoryChanges():
+			ApplyDirectoryChange(cfg)
+		case <-PicturesBack():
+			ApplyPicturesBack(cfg)
+		case <-NetworkChanges():
+			ApplyNetworkChange(cfg)
+			restartTimer()
+		case <-ColorSchemeChanges():
+			ApplyColorSchemeChange(cfg)
+			restartTimer()
+		case <-midnight:
+			midnight = CalendarTimer()
+			if ApplyCalendarChange(cfg) {
+				restartTimer()
+			}
+		case <-timer:
+			AutoChangeBG(cfg)
+		case <-sigs:
+			ManualChangeBG(cfg)
+		case f := <-ControlRequests():
+			f()
+		}
+	}
+}
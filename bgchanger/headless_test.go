@@ -0,0 +1,80 @@
+package bgchanger
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+func TestRunHeadlessChangesAtInterval(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "20ms", "startup_delay": "0s"}`, dir)
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		RunLoop(cfg, nil, quit)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(setter.values("picture-uri")) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d changes, want at least 3", len(setter.values("picture-uri")))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	quit <- os.Interrupt
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the headless loop did not return on quit")
+	}
+	uris := setter.values("picture-uri")
+	for i := 1; i < len(uris); i++ {
+		if uris[i] == uris[i-1] {
+			t.Errorf("change %d applied %s again", i, uris[i])
+		}
+	}
+}
+
+func TestRunLoopTrayEvents(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	// no automatic changes, until a tray event sets an interval
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "0s", "startup_delay": "0s"}`, dir)
+	events := make(chan TrayEvent)
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		RunLoop(cfg, events, quit)
+		close(done)
+	}()
+	defer func() {
+		quit <- os.Interrupt
+		<-done
+	}()
+	events <- func() bool {
+		ManualChangeBG(cfg)
+		return false
+	}
+	// the loop takes the next event once done with the previous one
+	events <- func() bool { return false }
+	if n := len(setter.values("picture-uri")); n != 1 {
+		t.Fatalf("got %d changes after the tray event, want 1", n)
+	}
+	events <- func() bool {
+		cfg.Interval = xjson.Duration(20 * time.Millisecond)
+		return true
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(setter.values("picture-uri")) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("the timer was not restarted after the tray event changed the interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
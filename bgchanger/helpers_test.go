@@ -0,0 +1,260 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+// testSetter is a BackgroundSetter recording every setting in order.
+type testSetter struct {
+	sync.Mutex
+	sets []string
+	// presets are the values returned by Get for the settings not set yet,
+	// by schema and key
+	presets map[string]string
+	// err, if not nil, is returned by Set without recording the setting
+	err error
+}
+
+// Set implements BackgroundSetter.
+func (ts *testSetter) Set(schema, key, value string) error {
+	ts.Lock()
+	defer ts.Unlock()
+	if ts.err != nil {
+		return ts.err
+	}
+	ts.sets = append(ts.sets, schema+" "+key+" "+value)
+	return nil
+}
+
+// Get implements BackgroundSetter, returning the last value set, or else the
+// preset one.
+func (ts *testSetter) Get(schema, key string) (string, error) {
+	ts.Lock()
+	defer ts.Unlock()
+	for i := len(ts.sets) - 1; i >= 0; i-- {
+		if f := strings.SplitN(ts.sets[i], " ", 3); f[0] == schema && f[1] == key {
+			return f[2], nil
+		}
+	}
+	if value, ok := ts.presets[schema+" "+key]; ok {
+		return value, nil
+	}
+	return "", errNoDesktop
+}
+
+// preset makes Get return value for the setting until it is set.
+func (ts *testSetter) preset(schema, key, value string) {
+	ts.Lock()
+	defer ts.Unlock()
+	if ts.presets == nil {
+		ts.presets = make(map[string]string)
+	}
+	ts.presets[schema+" "+key] = value
+}
+
+// setErr makes Set fail with err, or succeed again if nil.
+func (ts *testSetter) setErr(err error) {
+	ts.Lock()
+	ts.err = err
+	ts.Unlock()
+}
+
+// values returns the values given to key, of any schema, in order.
+func (ts *testSetter) values(key string) []string {
+	return ts.schemaValues("", key)
+}
+
+// schemaValues returns the values given to key of the schema, or of any
+// schema if empty, in order.
+func (ts *testSetter) schemaValues(schema, key string) []string {
+	ts.Lock()
+	defer ts.Unlock()
+	var values []string
+	for _, s := range ts.sets {
+		if f := strings.SplitN(s, " ", 3); f[1] == key && (schema == "" || f[0] == schema) {
+			values = append(values, f[2])
+		}
+	}
+	return values
+}
+
+// get returns the last value given to key, or the empty string.
+func (ts *testSetter) get(key string) string {
+	values := ts.values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// testEnv points the config and cache directories at a temporary directory,
+// records the settings instead of applying them and makes the external
+// commands fail, as in the self-test. Everything is restored when the test
+// ends.
+func testEnv(t *testing.T) *testSetter {
+	t.Helper()
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	configdir.Refresh()
+	// the config directory exists once the config file has been loaded
+	if err := os.MkdirAll(configdir.LocalConfig(Progname), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setter := &testSetter{}
+	oldSetter, oldRun, oldOutput := Setter, runCommand, commandOutput
+	Setter = setter
+	runCommand = func(name string, args ...string) error { return errNoDesktop }
+	commandOutput = func(name string, args ...string) ([]byte, error) { return nil, errNoDesktop }
+	resetState()
+	t.Cleanup(func() {
+		Setter, runCommand, commandOutput = oldSetter, oldRun, oldOutput
+		resetState()
+	})
+	return setter
+}
+
+// resetState forgets the runtime state of the package, so that each test
+// starts like a new instance.
+func resetState() {
+	state.Lock()
+	state.current, state.previous = "", ""
+	state.sourceInterval, state.pictureDuration, state.candidateCount = 0, 0, 0
+	state.configFile, state.lastError = "", nil
+	state.Unlock()
+	candidateCache.reset()
+	resetS3Listings()
+	ratings.Lock()
+	ratings.loaded, ratings.scores = false, nil
+	ratings.Unlock()
+	comfort.Lock()
+	comfort.loaded, comfort.comfortState = false, comfortState{}
+	comfort.Unlock()
+	lastShown.Lock()
+	lastShown.loaded, lastShown.times = false, nil
+	lastShown.Unlock()
+	displayTimes.Lock()
+	displayTimes.loaded, displayTimes.times = false, nil
+	displayTimes.Unlock()
+	queue.Lock()
+	queue.pictures = nil
+	queue.Unlock()
+	failures.Lock()
+	failures.count, failures.paused = 0, false
+	failures.Unlock()
+	hold.Lock()
+	hold.until = hold.until.AddDate(-100, 0, 0)
+	hold.Unlock()
+	paused.Lock()
+	paused.enabled = false
+	paused.Unlock()
+	monitors.Lock()
+	monitors.pictures = nil
+	monitors.Unlock()
+	boost.Lock()
+	if boost.timer != nil {
+		boost.timer.Stop()
+	}
+	boost.timer, boost.until = nil, time.Time{}
+	boost.Unlock()
+	setWriteAllowlist(nil)
+	networkWatcher.Lock()
+	stopNetworkWatcherLocked()
+	networkWatcher.Unlock()
+	dirWatcher.Lock()
+	stopWatcherLocked()
+	dirWatcher.Unlock()
+	setSupportedExtensions(nil)
+	setLargeDirectory(0)
+	noPicturesPoll.Lock()
+	noPicturesPoll.polling, noPicturesPoll.fallbackShown = false, false
+	noPicturesPoll.Unlock()
+	accentSupport.once, accentSupport.available = sync.Once{}, false
+	svgSupport.once, svgSupport.available = sync.Once{}, false
+	invalidatePreload()
+	drain(intervalChanges)
+	drain(networkChanges)
+	drain(directoryChanges)
+	drain(picturesBack)
+	drain(colorSchemeChanges)
+}
+
+// drain empties a notification channel.
+func drain(ch chan struct{}) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
+
+// writeTestPicture writes a PNG of the given size and color.
+func writeTestPicture(t *testing.T, filename string, w, h int, c color.RGBA) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for p := 0; p < len(img.Pix); p += 4 {
+		img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = c.R, c.G, c.B, c.A
+	}
+	if err := writePNG(filename, img); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// writeTestPictures writes n small pictures named 0.png, 1.png... in dir and
+// returns their paths.
+func writeTestPictures(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	var pictures []string
+	for i := 0; i < n; i++ {
+		c := color.RGBA{R: uint8(i * 40), G: 100, B: uint8(255 - i*40), A: 255}
+		pictures = append(pictures, writeTestPicture(t, filepath.Join(dir, fmt.Sprintf("%d.png", i)), 16, 9, c))
+	}
+	return pictures
+}
+
+// testConfig parses a config, failing the test if it is invalid.
+func testConfig(t *testing.T, format string, v ...interface{}) *Config {
+	t.Helper()
+	cfg, err := ParseConfig([]byte(fmt.Sprintf(format, v...)))
+	if err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	return cfg
+}
+
+// captureStdout returns what f prints on the standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	f()
+	os.Stdout = stdout
+	w.Close()
+	return string(<-done)
+}
@@ -0,0 +1,117 @@
+package bgchanger
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// variantRegexp matches the resolution suffix of a picture variant, like the
+// "@2x" in beach@2x.jpg.
+var variantRegexp = regexp.MustCompile(`^(.+)@(\d+)x$`)
+
+// mutterScaleRegexp matches the scale of a logical monitor in the output of
+// org.gnome.Mutter.DisplayConfig.GetCurrentState, whose logical monitors are
+// (x, y, scale, transform, primary, monitors, properties).
+var mutterScaleRegexp = regexp.MustCompile(`\(-?\d+, -?\d+, ([0-9.]+), uint32 \d+, (?:true|false),`)
+
+// displayScale returns the scale factor of the display, from the
+// scaling-factor setting, or from the logical monitors of mutter when that is
+// 0, i.e. automatic.
+func displayScale() (float64, error) {
+	factor, err := gsettingsGet(interfaceSchema, "scaling-factor")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get scaling-factor: %w", err)
+	}
+	s := strings.TrimPrefix(factor, "uint32 ")
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return float64(n), nil
+	}
+	out, err := commandOutput("gdbus", "call", "--session",
+		"--dest", "org.gnome.Mutter.DisplayConfig",
+		"--object-path", "/org/gnome/Mutter/DisplayConfig",
+		"--method", "org.gnome.Mutter.DisplayConfig.GetCurrentState")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get display state: %w", err)
+	}
+	scale := 1.0
+	for _, m := range mutterScaleRegexp.FindAllStringSubmatch(string(out), -1) {
+		if f, err := strconv.ParseFloat(m[1], 64); err == nil && f > scale {
+			scale = f
+		}
+	}
+	return scale, nil
+}
+
+// splitVariant returns the name of the picture without its resolution
+// suffix and extension, and the suffix' scale, 1 if there's none.
+func splitVariant(filename string) (string, int) {
+	ext := filepath.Ext(filename)
+	stem := strings.TrimSuffix(filename, ext)
+	if m := variantRegexp.FindStringSubmatch(stem); m != nil {
+		if n, err := strconv.Atoi(m[2]); err == nil && n > 0 {
+			return m[1], n
+		}
+	}
+	return stem, 1
+}
+
+// pickVariants keeps one picture of each group of resolution variants, like
+// beach.jpg and beach@2x.jpg: on a display with a scale factor above 1, the
+// smallest variant at least as large as the scale, or else the largest one,
+// otherwise the plain one, or else the smallest.
+func pickVariants(pictures []string, scale float64) []string {
+	type variant struct {
+		filename string
+		scale    int
+	}
+	groups := make(map[string][]variant)
+	var order []string
+	for _, p := range pictures {
+		base, n := splitVariant(p)
+		if _, ok := groups[base]; !ok {
+			order = append(order, base)
+		}
+		groups[base] = append(groups[base], variant{p, n})
+	}
+	if len(order) == len(pictures) {
+		return pictures
+	}
+	kept := make([]string, 0, len(order))
+	for _, base := range order {
+		variants := groups[base]
+		sort.SliceStable(variants, func(i, j int) bool { return variants[i].scale < variants[j].scale })
+		best := variants[0]
+		if scale > 1 {
+			best = variants[len(variants)-1]
+			for _, v := range variants {
+				if float64(v.scale) >= scale {
+					best = v
+					break
+				}
+			}
+		}
+		kept = append(kept, best.filename)
+	}
+	return kept
+}
+
+// preferVariants is pickVariants for the scale of the current display.
+func preferVariants(pictures []string) []string {
+	scale := 1.0
+	for _, p := range pictures {
+		if _, n := splitVariant(p); n > 1 {
+			s, err := displayScale()
+			if err != nil {
+				debugf("cannot get display scale, assuming 1: %v", err)
+			} else {
+				scale = s
+			}
+			break
+		}
+	}
+	return pickVariants(pictures, scale)
+}
@@ -0,0 +1,57 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHiDPIPrefersVariant(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	base := writeTestPicture(t, filepath.Join(dir, "beach.png"), 16, 9, color.RGBA{R: 200, A: 255})
+	hidpi := writeTestPicture(t, filepath.Join(dir, "beach@2x.png"), 32, 18, color.RGBA{R: 200, A: 255})
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+
+	setter.preset(interfaceSchema, "scaling-factor", "uint32 2")
+	for i := 0; i < 10; i++ {
+		if p, err := PickPicture(cfg); err != nil || p != hidpi {
+			t.Fatalf("got %s, %v on a HiDPI display, want %s", p, err, hidpi)
+		}
+	}
+	setter.preset(interfaceSchema, "scaling-factor", "uint32 1")
+	for i := 0; i < 10; i++ {
+		if p, err := PickPicture(cfg); err != nil || p != base {
+			t.Fatalf("got %s, %v on a regular display, want %s", p, err, base)
+		}
+	}
+}
+
+func TestDisplayScaleFromMutter(t *testing.T) {
+	setter := testEnv(t)
+	setter.preset(interfaceSchema, "scaling-factor", "uint32 0")
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		return []byte(`(uint32 1, [], [(0, 0, 1.0, uint32 0, false, [], {}), (1920, 0, 1.75, uint32 0, true, [], {})], {})`), nil
+	}
+	if scale, err := displayScale(); err != nil || scale != 1.75 {
+		t.Errorf("got scale %v, %v, want the largest logical monitor scale 1.75", scale, err)
+	}
+}
+
+func TestPickVariants(t *testing.T) {
+	pictures := []string{"/p/a.jpg", "/p/a@2x.jpg", "/p/a@3x.jpg", "/p/b@2x.jpg", "/p/c.jpg"}
+	for _, tc := range []struct {
+		scale float64
+		want  []string
+	}{
+		{1, []string{"/p/a.jpg", "/p/b@2x.jpg", "/p/c.jpg"}},
+		{2, []string{"/p/a@2x.jpg", "/p/b@2x.jpg", "/p/c.jpg"}},
+		{2.5, []string{"/p/a@3x.jpg", "/p/b@2x.jpg", "/p/c.jpg"}},
+		{4, []string{"/p/a@3x.jpg", "/p/b@2x.jpg", "/p/c.jpg"}},
+	} {
+		if got := pickVariants(pictures, tc.scale); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("got %v at scale %v, want %v", got, tc.scale, tc.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package bgchanger
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// hold is when the automatic changes resume after a manual change, with
+// manual_hold.
+var hold = struct {
+	sync.Mutex
+	until time.Time
+}{}
+
+// OnHoldChange is called when a manual change starts holding the background,
+// and when the hold expires.
+var OnHoldChange = func() {}
+
+// Held returns until when the automatic changes are suspended after a manual
+// change, and whether they are now.
+func Held() (time.Time, bool) {
+	hold.Lock()
+	defer hold.Unlock()
+	return hold.until, time.Now().Before(hold.until)
+}
+
+// startManualHold suspends the automatic changes for manual_hold, if set.
+func startManualHold(cfg *Config) {
+	d := time.Duration(cfg.ManualHold)
+	if d <= 0 {
+		return
+	}
+	until := time.Now().Add(d)
+	hold.Lock()
+	hold.until = until
+	hold.Unlock()
+	log.Printf("Holding the background until %s", until.Format("15:04"))
+	OnHoldChange()
+	time.AfterFunc(d, func() {
+		hold.Lock()
+		// a later manual change extended the hold
+		expired := hold.until.Equal(until)
+		hold.Unlock()
+		if expired {
+			debugf("manual hold expired")
+			OnHoldChange()
+		}
+	})
+}
+
+// ManualChangeBG is ChangeBG, for the changes requested by the user, which
+// suspend the automatic changes for manual_hold when they succeed.
+func ManualChangeBG(cfg *Config) {
+	ChangeBG(cfg)
+	if getStatus().LastError == "" {
+		startManualHold(cfg)
+	}
+}
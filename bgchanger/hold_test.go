@@ -0,0 +1,73 @@
+package bgchanger
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManualHold(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "manual_hold": "200ms"}`, dir)
+	changes := make(chan struct{}, 4)
+	old := OnHoldChange
+	OnHoldChange = func() { changes <- struct{}{} }
+	t.Cleanup(func() { OnHoldChange = old })
+
+	// automatic changes don't hold
+	AutoChangeBG(cfg)
+	if _, held := Held(); held || len(setter.values("picture-uri")) != 1 {
+		t.Fatalf("got %d changes, held %v after an automatic change", len(setter.values("picture-uri")), held)
+	}
+
+	ManualChangeBG(cfg)
+	until, held := Held()
+	if !held || until.Before(time.Now()) || until.After(time.Now().Add(time.Second)) {
+		t.Fatalf("got held %v until %v after a manual change", held, until)
+	}
+	<-changes
+	AutoChangeBG(cfg)
+	if n := len(setter.values("picture-uri")); n != 2 {
+		t.Errorf("got %d changes, want the automatic one skipped during the hold", n)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the end of the hold was not notified")
+	}
+	if _, held := Held(); held {
+		t.Error("still held after manual_hold")
+	}
+	AutoChangeBG(cfg)
+	if n := len(setter.values("picture-uri")); n != 3 {
+		t.Errorf("got %d changes, want the automatic changes resumed", n)
+	}
+}
+
+func TestManualHoldFailedChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "manual_hold": "1h"}`, dir)
+	setter.setErr(errors.New("broken"))
+	ManualChangeBG(cfg)
+	if _, held := Held(); held {
+		t.Error("a failed manual change holds the background")
+	}
+}
+
+func TestManualHoldDisabled(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	ManualChangeBG(testConfig(t, `{"pictures_dir": %q}`, dir))
+	if _, held := Held(); held {
+		t.Error("held without manual_hold")
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "manual_hold": "-1m"}`)); err == nil {
+		t.Error("expected an error for a negative manual_hold")
+	}
+}
@@ -0,0 +1,67 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultHookTimeout = 30 * time.Second
+	defaultHookRetries = 5
+)
+
+// preChangeHookBudget bounds the time all the attempts of the pre-change hook
+// take together, as a change waits for them. It is a variable so that tests
+// can make it shorter.
+var preChangeHookBudget = 30 * time.Second
+
+// runHook runs a user-provided shell command with the picture path as its
+// first argument and in $BGCHANGER_IMAGE, killing it after the given timeout.
+// The hook runs in its own process group, so that the commands it started
+// are killed with it.
+func runHook(command, filename string, timeout time.Duration) error {
+	cmd := exec.Command("sh", "-c", command+` "$@"`, Progname, filename)
+	cmd.Env = append(os.Environ(), "BGCHANGER_IMAGE="+filename)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// postChangeHooks are the post-change hooks still running.
+var postChangeHooks sync.WaitGroup
+
+// startPostChangeHook runs the post-change hook in the background, so that a
+// slow or failing hook doesn't hold up the change.
+func startPostChangeHook(cfg *Config, filename string) {
+	postChangeHooks.Add(1)
+	go func() {
+		defer postChangeHooks.Done()
+		if err := runHook(cfg.PostChangeHook, filename, time.Duration(cfg.HookTimeout)); err != nil {
+			log.Printf("Error: post-change hook failed: %v", err)
+		}
+	}()
+}
+
+// WaitForHooks waits for the post-change hooks started so far to finish or
+// time out. The commands that exit right after a change, like -set and
+// -apply, call it so that their hook gets to run.
+func WaitForHooks() {
+	postChangeHooks.Wait()
+}
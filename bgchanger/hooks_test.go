@@ -0,0 +1,39 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostChangeHookGetsPicture(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	out := filepath.Join(t.TempDir(), "hook.out")
+	cfg := testConfig(t, `{"pictures_dir": %q, "post_change_hook": %q}`, dir, `printf '%s %s' "$1" "$BGCHANGER_IMAGE" >`+out+` #`)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	WaitForHooks()
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("the hook did not run: %v", err)
+	}
+	if want := pictures[0] + " " + pictures[0]; strings.TrimSpace(string(data)) != want {
+		t.Errorf("the hook got '%s', want '%s'", data, want)
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	start := time.Now()
+	err := runHook("sleep 10 #", "/picture.jpg", 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("the hook was killed after %s", d)
+	}
+}
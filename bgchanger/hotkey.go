@@ -0,0 +1,123 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	mediaKeysSchema        = "org.gnome.settings-daemon.plugins.media-keys"
+	customKeybindingSchema = "org.gnome.settings-daemon.plugins.media-keys.custom-keybinding"
+	// hotkeyPath is the path of the custom keybinding of the hotkey.
+	hotkeyPath = "/org/gnome/settings-daemon/plugins/media-keys/custom-keybindings/" + Progname + "/"
+)
+
+// hotkeyRegexp matches accelerators like "<Super><Shift>b".
+var hotkeyRegexp = regexp.MustCompile(`^(<[A-Za-z0-9_]+>)*[A-Za-z0-9_]+$`)
+
+func validateHotkey(hotkey string) error {
+	if hotkey != "" && !hotkeyRegexp.MatchString(hotkey) {
+		return fmt.Errorf("invalid hotkey '%s', want something like '<Super><Shift>b'", hotkey)
+	}
+	return nil
+}
+
+// parseStringList parses a GVariant string array as printed by gsettings,
+// like "['a', 'b']" or "@as []".
+func parseStringList(s string) []string {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "@as"))
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.Trim(strings.TrimSpace(item), `'"`); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// formatStringList formats a GVariant string array for gsettings.
+func formatStringList(list []string) string {
+	if len(list) == 0 {
+		return "@as []"
+	}
+	quoted := make([]string, len(list))
+	for i, item := range list {
+		quoted[i] = "'" + item + "'"
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// keybindingList returns the custom keybindings with the one of the hotkey
+// added or removed, and whether that changes them.
+func keybindingList(current []string, install bool) ([]string, bool) {
+	var list []string
+	found := false
+	for _, p := range current {
+		if p == hotkeyPath {
+			found = true
+			if !install {
+				continue
+			}
+		}
+		list = append(list, p)
+	}
+	if install && !found {
+		list = append(list, hotkeyPath)
+	}
+	return list, install != found
+}
+
+// hotkeySettings returns the settings of the custom keybinding running the
+// executable with -change on hotkey.
+func hotkeySettings(executable, hotkey string) [][2]string {
+	return [][2]string{
+		{"name", Progname},
+		{"command", quoteExec(executable) + " -change"},
+		{"binding", hotkey},
+	}
+}
+
+// SetupHotkey installs a GNOME custom keybinding that changes the background
+// on the configured hotkey, or removes it if there is no hotkey anymore.
+// Global key grabs are not possible on Wayland, so the keybinding is handled
+// by GNOME, which runs bgchanger -change.
+func SetupHotkey(cfg *Config) {
+	out, err := gsettingsGet(mediaKeysSchema, "custom-keybindings")
+	if err != nil {
+		if cfg.Hotkey != "" {
+			log.Printf("Warning: cannot set the hotkey, no GNOME custom keybindings: %v", err)
+		}
+		return
+	}
+	list, changed := keybindingList(parseStringList(out), cfg.Hotkey != "")
+	schema := customKeybindingSchema + ":" + hotkeyPath
+	if cfg.Hotkey != "" {
+		executable, err := os.Executable()
+		if err != nil {
+			log.Printf("Warning: cannot set the hotkey: failed to get executable path: %v", err)
+			return
+		}
+		for _, kv := range hotkeySettings(executable, cfg.Hotkey) {
+			if err := gsettingsSet(schema, kv[0], kv[1]); err != nil {
+				log.Printf("Warning: cannot set the hotkey: %v", err)
+				return
+			}
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := gsettingsSet(mediaKeysSchema, "custom-keybindings", formatStringList(list)); err != nil {
+		log.Printf("Warning: cannot update the custom keybindings: %v", err)
+		return
+	}
+	if cfg.Hotkey != "" {
+		log.Printf("Installed hotkey %s", cfg.Hotkey)
+	} else {
+		log.Printf("Removed the hotkey")
+	}
+}
@@ -0,0 +1,121 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestStringList(t *testing.T) {
+	for in, want := range map[string][]string{
+		"@as []":         nil,
+		"[]":             nil,
+		"['/a/', '/b/']": {"/a/", "/b/"},
+		`["/a/"]`:        {"/a/"},
+	} {
+		if got := parseStringList(in); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", in, got, want)
+		}
+	}
+	if got := formatStringList(nil); got != "@as []" {
+		t.Errorf("got %s for an empty list, want @as []", got)
+	}
+	if got := formatStringList([]string{"/a/", "/b/"}); got != "['/a/', '/b/']" {
+		t.Errorf("got %s, want ['/a/', '/b/']", got)
+	}
+}
+
+func TestKeybindingList(t *testing.T) {
+	other := "/org/gnome/settings-daemon/plugins/media-keys/custom-keybindings/custom0/"
+	for _, tc := range []struct {
+		current []string
+		install bool
+		want    []string
+		changed bool
+	}{
+		{nil, true, []string{hotkeyPath}, true},
+		{[]string{other}, true, []string{other, hotkeyPath}, true},
+		{[]string{other, hotkeyPath}, true, []string{other, hotkeyPath}, false},
+		{[]string{hotkeyPath, other}, false, []string{other}, true},
+		{[]string{other}, false, []string{other}, false},
+	} {
+		got, changed := keybindingList(tc.current, tc.install)
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) || changed != tc.changed {
+			t.Errorf("%v, install %v: got %v, %v, want %v, %v", tc.current, tc.install, got, changed, tc.want, tc.changed)
+		}
+	}
+}
+
+func TestHotkeySettings(t *testing.T) {
+	got := hotkeySettings("/opt/my apps/bgchanger", "<Super><Shift>b")
+	want := [][2]string{
+		{"name", Progname},
+		{"command", `"/opt/my apps/bgchanger" -change`},
+		{"binding", "<Super><Shift>b"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSetupHotkey(t *testing.T) {
+	setter := testEnv(t)
+	other := "/org/gnome/settings-daemon/plugins/media-keys/custom-keybindings/custom0/"
+	setter.preset(mediaKeysSchema, "custom-keybindings", "['"+other+"']")
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": "/p", "hotkey": "<Super>b"}`)
+	SetupHotkey(cfg)
+	schema := customKeybindingSchema + ":" + hotkeyPath
+	for key, want := range map[string]string{
+		"name":    Progname,
+		"command": quoteExec(executable) + " -change",
+		"binding": "<Super>b",
+	} {
+		if got := setter.schemaValues(schema, key); len(got) != 1 || got[0] != want {
+			t.Errorf("got %s %v, want %s", key, got, want)
+		}
+	}
+	if got := setter.schemaValues(mediaKeysSchema, "custom-keybindings"); len(got) != 1 || got[0] != "['"+other+"', '"+hotkeyPath+"']" {
+		t.Errorf("got custom-keybindings %v, want ours added", got)
+	}
+
+	// installing again doesn't touch the list
+	SetupHotkey(cfg)
+	if got := setter.schemaValues(mediaKeysSchema, "custom-keybindings"); len(got) != 1 {
+		t.Errorf("got custom-keybindings %v, want them unchanged", got)
+	}
+
+	SetupHotkey(testConfig(t, `{"pictures_dir": "/p"}`))
+	if got := setter.get("custom-keybindings"); got != "['"+other+"']" {
+		t.Errorf("got custom-keybindings %s, want ours removed", got)
+	}
+	if n := len(setter.schemaValues(schema, "binding")); n != 2 {
+		t.Errorf("the binding was set %d times while removing the hotkey", n)
+	}
+}
+
+func TestSetupHotkeyWithoutGNOME(t *testing.T) {
+	setter := testEnv(t)
+	SetupHotkey(testConfig(t, `{"pictures_dir": "/p", "hotkey": "<Super>b"}`))
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v without GNOME custom keybindings", setter.sets)
+	}
+}
+
+func TestHotkeyValidate(t *testing.T) {
+	for hotkey, valid := range map[string]bool{
+		"":                true,
+		"<Super><Shift>b": true,
+		"F12":             true,
+		"<Super>":         false,
+		"Super+b":         false,
+	} {
+		_, err := ParseConfig([]byte(fmt.Sprintf(`{"pictures_dir": "/p", "hotkey": %q}`, hotkey)))
+		if (err == nil) != valid {
+			t.Errorf("%q: got %v, want valid %v", hotkey, err, valid)
+		}
+	}
+}
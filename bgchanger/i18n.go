@@ -0,0 +1,71 @@
+package bgchanger
+
+import (
+	"embed"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+)
+
+// To add a translation, drop a <locale>.json file in the locales directory,
+// with the same keys as en.json. Missing keys fall back to English.
+//
+//go:embed locales/*.json
+var localesFS embed.FS
+
+const defaultLocale = "en"
+
+var translations = loadTranslations(detectLocale())
+
+// detectLocale returns the locale from the environment, e.g. "it_IT" for
+// LANG=it_IT.UTF-8.
+func detectLocale() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			v = strings.SplitN(v, ".", 2)[0]
+			return strings.SplitN(v, "@", 2)[0]
+		}
+	}
+	return defaultLocale
+}
+
+func readLocale(name string) map[string]string {
+	data, err := localesFS.ReadFile("locales/" + name + ".json")
+	if err != nil {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		log.Printf("Warning: invalid translation file for locale '%s': %v", name, err)
+		return nil
+	}
+	return labels
+}
+
+// loadTranslations returns the labels for the given locale, trying e.g.
+// "it_IT" then "it", layered over the English ones.
+func loadTranslations(locale string) map[string]string {
+	labels := readLocale(defaultLocale)
+	candidates := []string{locale}
+	if lang := strings.SplitN(locale, "_", 2)[0]; lang != locale {
+		candidates = append(candidates, lang)
+	}
+	for _, c := range candidates {
+		if l := readLocale(c); l != nil {
+			for k, v := range l {
+				labels[k] = v
+			}
+			break
+		}
+	}
+	return labels
+}
+
+// Tr returns the translated label for key.
+func Tr(key string) string {
+	if v, ok := translations[key]; ok {
+		return v
+	}
+	return key
+}
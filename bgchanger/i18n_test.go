@@ -0,0 +1,67 @@
+package bgchanger
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestLoadTranslations(t *testing.T) {
+	en := readLocale(defaultLocale)
+	if en["change"] != "Change background now" {
+		t.Fatalf("got English label %q", en["change"])
+	}
+	it := loadTranslations("it_IT")
+	if it["change"] == "" || it["change"] == en["change"] {
+		t.Errorf("got Italian label %q, want a translation", it["change"])
+	}
+	for _, locale := range []string{"xx_YY", "C", ""} {
+		if labels := loadTranslations(locale); labels["change"] != en["change"] {
+			t.Errorf("got %q for locale %q, want the English label", labels["change"], locale)
+		}
+	}
+
+	orig := translations
+	defer func() { translations = orig }()
+	translations = map[string]string{"change": "Cambia"}
+	if got := Tr("change"); got != "Cambia" {
+		t.Errorf("got %q, want Cambia", got)
+	}
+	if got := Tr("no_such_key"); got != "no_such_key" {
+		t.Errorf("got %q for a missing key, want the key", got)
+	}
+}
+
+func TestLocalesHaveEnglishKeys(t *testing.T) {
+	en := readLocale(defaultLocale)
+	files, err := fs.Glob(localesFS, "locales/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(f, "locales/"), ".json")
+		labels := readLocale(name)
+		if labels == nil {
+			t.Errorf("cannot read locale %s", name)
+			continue
+		}
+		for k := range labels {
+			if _, ok := en[k]; !ok {
+				t.Errorf("locale %s has key %s, which is not in %s.json", name, k, defaultLocale)
+			}
+		}
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "it_IT.UTF-8@euro")
+	if got := detectLocale(); got != "it_IT" {
+		t.Errorf("got %q, want it_IT", got)
+	}
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	if got := detectLocale(); got != "de_DE" {
+		t.Errorf("got %q, want de_DE with LC_ALL set", got)
+	}
+}
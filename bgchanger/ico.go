@@ -0,0 +1,147 @@
+package bgchanger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeICO, decodeICOConfig)
+}
+
+// icoEntry is an entry of the directory of an ICO file.
+type icoEntry struct {
+	width, height int
+	bpp           int
+	size, offset  uint32
+}
+
+// readICODirectory reads the directory of an ICO file, and returns its
+// largest image.
+func readICODirectory(data []byte) (icoEntry, error) {
+	if len(data) < 6 {
+		return icoEntry{}, fmt.Errorf("ico: short header")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return icoEntry{}, fmt.Errorf("ico: invalid directory")
+	}
+	var best icoEntry
+	for i := 0; i < count; i++ {
+		b := data[6+16*i : 6+16*(i+1)]
+		e := icoEntry{
+			width:  int(b[0]),
+			height: int(b[1]),
+			bpp:    int(binary.LittleEndian.Uint16(b[6:8])),
+			size:   binary.LittleEndian.Uint32(b[8:12]),
+			offset: binary.LittleEndian.Uint32(b[12:16]),
+		}
+		// 0 means 256
+		if e.width == 0 {
+			e.width = 256
+		}
+		if e.height == 0 {
+			e.height = 256
+		}
+		if area, bestArea := e.width*e.height, best.width*best.height; area > bestArea || (area == bestArea && e.bpp > best.bpp) {
+			best = e
+		}
+	}
+	if uint64(best.offset)+uint64(best.size) > uint64(len(data)) {
+		return icoEntry{}, fmt.Errorf("ico: image out of bounds")
+	}
+	return best, nil
+}
+
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	e, err := readICODirectory(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.RGBAModel, Width: e.width, Height: e.height}, nil
+}
+
+// decodeICO decodes the largest image of an ICO file, which is either a PNG
+// or a 24 or 32 bit bitmap.
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	e, err := readICODirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	frame := data[e.offset : e.offset+e.size]
+	if bytes.HasPrefix(frame, []byte("\x89PNG\r\n\x1a\n")) {
+		return png.Decode(bytes.NewReader(frame))
+	}
+	return decodeICOBitmap(frame)
+}
+
+// decodeICOBitmap decodes a BITMAPINFOHEADER bitmap as stored in ICO files:
+// bottom-up, with twice the height to account for the 1 bit AND mask that
+// follows the pixels.
+func decodeICOBitmap(frame []byte) (image.Image, error) {
+	if len(frame) < 40 {
+		return nil, fmt.Errorf("ico: short bitmap header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(frame[0:4]))
+	w := int(int32(binary.LittleEndian.Uint32(frame[4:8])))
+	h := int(int32(binary.LittleEndian.Uint32(frame[8:12]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(frame[14:16]))
+	compression := binary.LittleEndian.Uint32(frame[16:20])
+	if w <= 0 || h <= 0 || headerSize < 40 || headerSize > len(frame) {
+		return nil, fmt.Errorf("ico: invalid bitmap header")
+	}
+	if compression != 0 || (bpp != 24 && bpp != 32) {
+		return nil, fmt.Errorf("ico: unsupported %d bit bitmap with compression %d", bpp, compression)
+	}
+	stride := (w*bpp + 31) / 32 * 4
+	maskStride := (w + 31) / 32 * 4
+	pixels := frame[headerSize:]
+	if len(pixels) < stride*h {
+		return nil, fmt.Errorf("ico: short bitmap")
+	}
+	mask := pixels[stride*h:]
+	hasMask := len(mask) >= maskStride*h
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	anyAlpha := false
+	for y := 0; y < h; y++ {
+		row := pixels[(h-1-y)*stride:]
+		for x := 0; x < w; x++ {
+			p := row[x*bpp/8:]
+			c := color.NRGBA{R: p[2], G: p[1], B: p[0], A: 255}
+			if bpp == 32 {
+				c.A = p[3]
+				anyAlpha = anyAlpha || c.A != 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// the AND mask gives the transparency when there is no alpha channel
+	if hasMask && !anyAlpha {
+		for y := 0; y < h; y++ {
+			row := mask[(h-1-y)*maskStride:]
+			for x := 0; x < w; x++ {
+				c := img.NRGBAAt(x, y)
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					c.A = 0
+				} else {
+					c.A = 255
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
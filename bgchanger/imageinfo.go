@@ -0,0 +1,100 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// sampleSize is the number of pixels sampled along each side of an image
+// when computing its average color.
+const sampleSize = 64
+
+var averageColorCache = newFileCache(computeAverageColor)
+
+// averageColor returns the average color of the picture at filename. Results
+// are cached by path and modification time.
+func averageColor(filename string) (color.RGBA, error) {
+	return averageColorCache.get(filename)
+}
+
+// loadImage decodes the picture at filename. SVG pictures are rasterized
+// first.
+func loadImage(filename string) (image.Image, error) {
+	filename, err := rasterizedSVG(filename)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	img, _, err := image.Decode(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode '%s': %w", filename, err)
+	}
+	return img, nil
+}
+
+// imageSize returns the dimensions of the picture at filename, without
+// decoding all of it.
+func imageSize(filename string) (int, int, error) {
+	filename, err := rasterizedSVG(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer fd.Close()
+	c, _, err := image.DecodeConfig(fd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode '%s': %w", filename, err)
+	}
+	return c.Width, c.Height, nil
+}
+
+// computeAverageColor samples a sampleSize x sampleSize grid of the picture.
+// The whole picture is decoded first: image/jpeg and image/png cannot decode
+// at a reduced scale or only some of the pixels, so the sampling only saves
+// the averaging. The cache makes that a one time cost per picture.
+func computeAverageColor(filename string) (color.RGBA, error) {
+	img, err := loadImage(filename)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	b := img.Bounds()
+	stepX, stepY := b.Dx()/sampleSize, b.Dy()/sampleSize
+	if stepX < 1 {
+		stepX = 1
+	}
+	if stepY < 1 {
+		stepY = 1
+	}
+	var r, g, bl, n uint64
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r, g, bl, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), n+1
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}, fmt.Errorf("image '%s' is empty", filename)
+	}
+	return color.RGBA{
+		R: uint8(r / n >> 8),
+		G: uint8(g / n >> 8),
+		B: uint8(bl / n >> 8),
+		A: 0xff,
+	}, nil
+}
+
+// luminance returns the relative luminance of c, between 0 and 1.
+func luminance(c color.RGBA) float64 {
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
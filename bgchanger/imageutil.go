@@ -0,0 +1,158 @@
+package bgchanger
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path"
+
+	"github.com/kirsle/configdir"
+)
+
+// resizeImage scales img to w x h pixels with bilinear interpolation.
+func resizeImage(img image.Image, w, h int) *image.RGBA {
+	src := toRGBA(img)
+	sb := src.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	sx := float64(sb.Dx()) / float64(w)
+	sy := float64(sb.Dy()) / float64(h)
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0 := clampInt(int(fy), 0, sb.Dy()-1)
+		y1 := clampInt(y0+1, 0, sb.Dy()-1)
+		dy := fy - float64(y0)
+		if dy < 0 {
+			dy = 0
+		}
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0 := clampInt(int(fx), 0, sb.Dx()-1)
+			x1 := clampInt(x0+1, 0, sb.Dx()-1)
+			dx := fx - float64(x0)
+			if dx < 0 {
+				dx = 0
+			}
+			c00 := src.RGBAAt(sb.Min.X+x0, sb.Min.Y+y0)
+			c10 := src.RGBAAt(sb.Min.X+x1, sb.Min.Y+y0)
+			c01 := src.RGBAAt(sb.Min.X+x0, sb.Min.Y+y1)
+			c11 := src.RGBAAt(sb.Min.X+x1, sb.Min.Y+y1)
+			lerp := func(a, b, c, d uint8) uint8 {
+				top := float64(a)*(1-dx) + float64(b)*dx
+				bottom := float64(c)*(1-dx) + float64(d)*dx
+				return uint8(top*(1-dy) + bottom*dy + 0.5)
+			}
+			out.SetRGBA(x, y, color.RGBA{
+				R: lerp(c00.R, c10.R, c01.R, c11.R),
+				G: lerp(c00.G, c10.G, c01.G, c11.G),
+				B: lerp(c00.B, c10.B, c01.B, c11.B),
+				A: lerp(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+	return out
+}
+
+// cropToFill scales img to cover w x h pixels, preserving the aspect ratio,
+// and crops the center.
+func cropToFill(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	scale := float64(w) / float64(b.Dx())
+	if s := float64(h) / float64(b.Dy()); s > scale {
+		scale = s
+	}
+	sw, sh := int(float64(b.Dx())*scale+0.5), int(float64(b.Dy())*scale+0.5)
+	if sw < w {
+		sw = w
+	}
+	if sh < h {
+		sh = h
+	}
+	scaled := resizeImage(img, sw, sh)
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(out, out.Bounds(), scaled, image.Pt((sw-w)/2, (sh-h)/2), draw.Src)
+	return out
+}
+
+// fitWithBars scales img to fit in w x h pixels, preserving the aspect ratio,
+// and centers it over the given background color.
+func fitWithBars(img image.Image, w, h int, bg color.Color) *image.RGBA {
+	b := img.Bounds()
+	scale := float64(w) / float64(b.Dx())
+	if s := float64(h) / float64(b.Dy()); s < scale {
+		scale = s
+	}
+	sw, sh := int(float64(b.Dx())*scale+0.5), int(float64(b.Dy())*scale+0.5)
+	if sw < 1 {
+		sw = 1
+	}
+	if sh < 1 {
+		sh = 1
+	}
+	scaled := resizeImage(img, sw, sh)
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(out, out.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	offset := image.Pt((w-sw)/2, (h-sh)/2)
+	draw.Draw(out, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+	return out
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+	return out
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// writeJPEG writes img to filename atomically, as a cached file newer than
+// its source is trusted and a truncated one would never be regenerated.
+func writeJPEG(filename string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92}); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// cachedVariant returns the path in the cache subdirectory dir where a
+// processed variant of source, identified by variant, is stored. The
+// returned bool is true if the variant exists and is newer than the source.
+func cachedVariant(dir, source, variant string) (string, bool, error) {
+	return cachedFile(dir, source, variant, ".jpg")
+}
+
+// cachedFile is cachedVariant, for files with the given extension.
+func cachedFile(dir, source, variant, ext string) (string, bool, error) {
+	cacheDir := path.Join(configdir.LocalCache(Progname), dir)
+	if err := makeDirs(cacheDir); err != nil {
+		return "", false, fmt.Errorf("failed to create cache directory '%s': %w", cacheDir, err)
+	}
+	sum := sha1.Sum([]byte(source))
+	cached := path.Join(cacheDir, fmt.Sprintf("%s-%s%s", hex.EncodeToString(sum[:8]), variant, ext))
+	srcSt, err := os.Stat(source)
+	if err != nil {
+		return "", false, err
+	}
+	if st, err := os.Stat(cached); err == nil && st.ModTime().After(srcSt.ModTime()) {
+		return cached, true, nil
+	}
+	return cached, false, nil
+}
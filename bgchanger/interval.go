@@ -0,0 +1,159 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// intervalChanges is notified when the effective interval changes because
+// a picture from a source with a different interval was applied.
+var intervalChanges = make(chan struct{}, 1)
+
+// IntervalChanges returns a channel that receives a value when the effective
+// interval changes, at which point the change ticker should be restarted.
+func IntervalChanges() <-chan struct{} {
+	return intervalChanges
+}
+
+// AdaptiveConfig configures adaptive_interval, the scaling of the interval by
+// the number of candidates, so that small collections change less often.
+type AdaptiveConfig struct {
+	Enabled bool `json:"enabled"`
+	// Reference is the number of candidates for which the interval is not
+	// scaled.
+	Reference int            `json:"reference"`
+	Min       xjson.Duration `json:"min"`
+	Max       xjson.Duration `json:"max"`
+}
+
+const defaultAdaptiveReference = 100
+
+func (ac *AdaptiveConfig) setDefaults() {
+	if ac.Reference <= 0 {
+		ac.Reference = defaultAdaptiveReference
+	}
+}
+
+func (ac *AdaptiveConfig) validate() error {
+	if ac.Min < 0 || ac.Max < 0 {
+		return fmt.Errorf("adaptive_interval bounds cannot be negative")
+	}
+	if ac.Max > 0 && ac.Min > ac.Max {
+		return fmt.Errorf("adaptive_interval min cannot be greater than max")
+	}
+	return nil
+}
+
+// scale returns interval * reference / candidates, within the bounds.
+func (ac *AdaptiveConfig) scale(interval xjson.Duration, candidates int) xjson.Duration {
+	if candidates <= 0 {
+		return interval
+	}
+	scaled := xjson.Duration(int64(interval) * int64(ac.Reference) / int64(candidates))
+	if ac.Min > 0 && scaled < ac.Min {
+		scaled = ac.Min
+	}
+	if ac.Max > 0 && scaled > ac.Max {
+		scaled = ac.Max
+	}
+	return scaled
+}
+
+// EffectiveInterval returns the duration in the sidecar of the current
+// picture if it has one, otherwise the interval of its source if it has one,
+// otherwise the global interval, scaled by the number of candidates if
+// adaptive_interval is enabled. While boosted, it's the shorter boost
+// interval.
+func EffectiveInterval(cfg *Config) xjson.Duration {
+	state.Lock()
+	defer state.Unlock()
+	return effectiveIntervalLocked(cfg)
+}
+
+// effectiveIntervalLocked is EffectiveInterval, with the state lock held.
+func effectiveIntervalLocked(cfg *Config) xjson.Duration {
+	interval := cfg.Interval
+	if state.sourceInterval > 0 {
+		interval = state.sourceInterval
+	}
+	if interval > 0 && cfg.AdaptiveInterval.Enabled {
+		interval = accessibleInterval(cfg, cfg.AdaptiveInterval.scale(interval, state.candidateCount))
+	}
+	if state.pictureDuration > 0 {
+		interval = accessibleInterval(cfg, state.pictureDuration)
+	}
+	if boosted, ok := boostedInterval(cfg); ok && (interval <= 0 || boosted < interval) {
+		interval = boosted
+	}
+	return interval
+}
+
+// notifyIntervalChange notifies IntervalChanges if the effective interval is
+// not before anymore.
+func notifyIntervalChange(cfg *Config, before xjson.Duration) {
+	if after := EffectiveInterval(cfg); after != before {
+		log.Printf("Interval is now %s", after)
+		select {
+		case intervalChanges <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// updateCandidateCount counts the candidates for adaptive_interval.
+func updateCandidateCount(cfg *Config) {
+	if !cfg.AdaptiveInterval.Enabled {
+		return
+	}
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		log.Printf("Warning: cannot count candidates: %v", err)
+		return
+	}
+	setCandidateCount(cfg, len(pictures))
+}
+
+func setCandidateCount(cfg *Config, n int) {
+	before := EffectiveInterval(cfg)
+	state.Lock()
+	state.candidateCount = n
+	state.Unlock()
+	notifyIntervalChange(cfg, before)
+}
+
+// setActiveSource records the source of the current picture, and notifies
+// IntervalChanges if that changes the effective interval.
+func setActiveSource(cfg *Config, src *SourceConfig) {
+	before := EffectiveInterval(cfg)
+	state.Lock()
+	state.sourceInterval = src.Interval
+	state.Unlock()
+	notifyIntervalChange(cfg, before)
+}
+
+// setPictureDuration records the duration in the sidecar of the current
+// picture. Pictures with a duration always notify IntervalChanges, so that
+// the next change comes that long after them.
+func setPictureDuration(cfg *Config, filename string) {
+	before := EffectiveInterval(cfg)
+	d := xjson.Duration(readSidecar(filename).Duration)
+	if cfg.Mode == modeDaily {
+		// the picture of the day stays until midnight
+		d = xjson.Duration(untilNextDay(time.Now()))
+	}
+	state.Lock()
+	state.pictureDuration = d
+	state.Unlock()
+	if d <= 0 {
+		notifyIntervalChange(cfg, before)
+		return
+	}
+	log.Printf("Showing '%s' for %s", filename, d)
+	select {
+	case intervalChanges <- struct{}{}:
+	default:
+	}
+}
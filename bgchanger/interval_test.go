@@ -0,0 +1,75 @@
+package bgchanger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "30m",
+		"adaptive_interval": {"enabled": true, "reference": 100, "min": "10m", "max": "4h"}}`)
+	for _, tc := range []struct {
+		candidates int
+		want       time.Duration
+	}{
+		{0, 30 * time.Minute},
+		{100, 30 * time.Minute},
+		{50, time.Hour},
+		{200, 15 * time.Minute},
+		// clamped to the bounds
+		{3, 4 * time.Hour},
+		{1000, 10 * time.Minute},
+	} {
+		setCandidateCount(cfg, tc.candidates)
+		if got := time.Duration(EffectiveInterval(cfg)); got != tc.want {
+			t.Errorf("got interval %s with %d candidates, want %s", got, tc.candidates, tc.want)
+		}
+	}
+	small, large := 3, 300
+	setCandidateCount(cfg, small)
+	longer := EffectiveInterval(cfg)
+	setCandidateCount(cfg, large)
+	if shorter := EffectiveInterval(cfg); shorter >= longer {
+		t.Errorf("got %s with %d candidates and %s with %d, want a longer interval for fewer candidates", longer, small, shorter, large)
+	}
+
+	// the change ticker is restarted when the count changes the interval
+	drain(intervalChanges)
+	setCandidateCount(cfg, 50)
+	if len(intervalChanges) == 0 {
+		t.Error("the interval change was not notified")
+	}
+}
+
+func TestAdaptiveIntervalAccessibility(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "30m", "accessibility": {"enabled": true, "min_interval": "20m"},
+		"adaptive_interval": {"enabled": true, "min": "1m"}}`)
+	setCandidateCount(cfg, 1000)
+	if got := time.Duration(EffectiveInterval(cfg)); got != 20*time.Minute {
+		t.Errorf("got interval %s, want the accessibility min_interval", got)
+	}
+}
+
+func TestAdaptiveIntervalCountsCandidates(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 4)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1h", "adaptive_interval": {"enabled": true, "reference": 2}}`, dir)
+	ChangeBG(cfg)
+	if got := time.Duration(EffectiveInterval(cfg)); got != 30*time.Minute {
+		t.Errorf("got interval %s with 4 candidates and a reference of 2, want 30m", got)
+	}
+}
+
+func TestAdaptiveIntervalValidate(t *testing.T) {
+	for _, tc := range []string{
+		`{"pictures_dir": "/p", "adaptive_interval": {"enabled": true, "min": "-1m"}}`,
+		`{"pictures_dir": "/p", "adaptive_interval": {"enabled": true, "min": "2h", "max": "1h"}}`,
+	} {
+		if _, err := ParseConfig([]byte(tc)); err == nil {
+			t.Errorf("%s: got no error, want an invalid config", tc)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// actions of interval_click_action, run when the interval menu item is
+// clicked
+const (
+	intervalClickPause  = "pause"
+	intervalClickBoost  = "boost"
+	intervalClickChange = "change"
+)
+
+func validateIntervalClickAction(action string) error {
+	switch action {
+	case "", intervalClickPause, intervalClickBoost, intervalClickChange:
+		return nil
+	}
+	return fmt.Errorf("unknown interval_click_action '%s', want pause, boost or change", action)
+}
+
+// paused is whether the automatic changes are paused from the interval menu
+// item. It is not saved across restarts.
+var paused = struct {
+	sync.Mutex
+	enabled bool
+}{}
+
+// OnPauseChange is called with whether the automatic changes are paused,
+// when that changes.
+var OnPauseChange = func(paused bool) {}
+
+// Paused returns whether the automatic changes are paused.
+func Paused() bool {
+	paused.Lock()
+	defer paused.Unlock()
+	return paused.enabled
+}
+
+// setPaused pauses or resumes the automatic changes.
+func setPaused(enabled bool) {
+	paused.Lock()
+	changed := paused.enabled != enabled
+	paused.enabled = enabled
+	paused.Unlock()
+	if !changed {
+		return
+	}
+	if enabled {
+		log.Printf("Automatic changes paused")
+	} else {
+		log.Printf("Automatic changes resumed")
+	}
+	OnPauseChange(enabled)
+}
+
+// IntervalClicked runs the interval_click_action, when the interval menu
+// item is clicked.
+func IntervalClicked(cfg *Config) {
+	switch cfg.IntervalClickAction {
+	case intervalClickPause:
+		setPaused(!Paused())
+	case intervalClickBoost:
+		if _, boosted := Boosted(); boosted {
+			CancelBoost(cfg)
+		} else {
+			Boost(cfg)
+		}
+	case intervalClickChange:
+		ManualChangeBG(cfg)
+	}
+}
@@ -0,0 +1,84 @@
+package bgchanger
+
+import (
+	"testing"
+)
+
+func TestIntervalClickPause(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval_click_action": "pause"}`, dir)
+	var notified []bool
+	old := OnPauseChange
+	OnPauseChange = func(p bool) { notified = append(notified, p) }
+	t.Cleanup(func() { OnPauseChange = old })
+
+	IntervalClicked(cfg)
+	if !Paused() || !getStatus().Paused {
+		t.Fatal("not paused after a click")
+	}
+	AutoChangeBG(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v while paused", setter.sets)
+	}
+	IntervalClicked(cfg)
+	if Paused() {
+		t.Fatal("still paused after a second click")
+	}
+	AutoChangeBG(cfg)
+	if len(setter.values("picture-uri")) != 1 {
+		t.Errorf("got picture-uri %v, want a change after resuming", setter.values("picture-uri"))
+	}
+	if len(notified) != 2 || !notified[0] || notified[1] {
+		t.Errorf("OnPauseChange got %v, want [true false]", notified)
+	}
+
+	// without the pause action, nothing could resume the changes
+	IntervalClicked(cfg)
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q, "interval_click_action": "change"}`, dir))
+	if Paused() {
+		t.Error("still paused after the pause action was removed")
+	}
+}
+
+func TestIntervalClickBoost(t *testing.T) {
+	testEnv(t)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "1h", "interval_click_action": "boost"}`)
+	IntervalClicked(cfg)
+	if _, boosted := Boosted(); !boosted {
+		t.Fatal("not boosted after a click")
+	}
+	IntervalClicked(cfg)
+	if _, boosted := Boosted(); boosted {
+		t.Error("still boosted after a second click")
+	}
+}
+
+func TestIntervalClickChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval_click_action": "change", "manual_hold": "1h"}`, dir)
+	IntervalClicked(cfg)
+	if len(setter.values("picture-uri")) != 1 {
+		t.Errorf("got picture-uri %v, want a change", setter.values("picture-uri"))
+	}
+	if _, held := Held(); !held {
+		t.Error("the click is not a manual change holding the background")
+	}
+}
+
+func TestIntervalClickNone(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	IntervalClicked(cfg)
+	if _, boosted := Boosted(); len(setter.sets) != 0 || Paused() || boosted {
+		t.Error("a click without interval_click_action did something")
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "interval_click_action": "explode"}`)); err == nil {
+		t.Error("expected an error for an unknown interval_click_action")
+	}
+}
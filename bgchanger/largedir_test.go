@@ -0,0 +1,161 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+// writeEmptyPictures creates n empty pictures in dir, as their contents are
+// not read when listing.
+func writeEmptyPictures(t testing.TB, dir string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%05d.jpg", i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func cachedListing(dir string) (candidateListing, bool) {
+	candidateCache.mu.Lock()
+	defer candidateCache.mu.Unlock()
+	e, ok := candidateCache.entries[dir]
+	return e.value, ok
+}
+
+func TestLargeDirectorySamplesUniformly(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	const (
+		files = 200
+		limit = 10
+		runs  = 2000
+	)
+	writeEmptyPictures(t, dir, files)
+	setLargeDirectory(limit)
+	counts := make(map[string]int)
+	for i := 0; i < runs; i++ {
+		pictures, err := cachedCandidates(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pictures) != limit || !sort.StringsAreSorted(pictures) {
+			t.Fatalf("got %v, want %d sorted pictures", pictures, limit)
+		}
+		for _, p := range pictures {
+			counts[p]++
+		}
+	}
+	if len(counts) != files {
+		t.Errorf("sampled %d different pictures, want all %d", len(counts), files)
+	}
+	// each picture is expected runs*limit/files = 100 times, with a standard
+	// deviation of about 10
+	for p, n := range counts {
+		if n < 50 || n > 150 {
+			t.Errorf("sampled %s %d times, want about 100", filepath.Base(p), n)
+		}
+	}
+	if listing, ok := cachedListing(dir); !ok || !listing.large || listing.pictures != nil {
+		t.Errorf("got cached listing %+v, want the directory marked as large without its pictures", listing)
+	}
+
+	all, err := cachedCandidates(dir, allCandidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != files {
+		t.Errorf("got %d pictures with allCandidates, want all %d", len(all), files)
+	}
+}
+
+func TestSmallDirectoryIsCachedWhole(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeEmptyPictures(t, dir, 20)
+	setLargeDirectory(20)
+	pictures, err := cachedCandidates(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pictures) != 20 {
+		t.Fatalf("got %d pictures, want all 20", len(pictures))
+	}
+	if listing, ok := cachedListing(dir); !ok || listing.large || len(listing.pictures) != 20 {
+		t.Errorf("got cached listing %+v, want all 20 pictures", listing)
+	}
+
+	// lowering large_directory drops the cached listing
+	setLargeDirectory(10)
+	if _, ok := cachedListing(dir); ok {
+		t.Error("got a cached listing after changing large_directory")
+	}
+	if pictures, err := cachedCandidates(dir, 0); err != nil || len(pictures) != 10 {
+		t.Errorf("got %d pictures, %v, want a sample of 10", len(pictures), err)
+	}
+}
+
+func TestLargeDirectoryIndexedCandidates(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeEmptyPictures(t, dir, 30)
+	cfg := testConfig(t, `{"pictures_dir": %q, "large_directory": 10}`, dir)
+	setLargeDirectory(cfg.LargeDirectory)
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pictures) != 10 {
+		t.Errorf("scanned %d pictures, want a sample of 10", len(pictures))
+	}
+	pictures, err = indexedCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pictures) != 30 {
+		t.Errorf("got %d indexed pictures, want all 30", len(pictures))
+	}
+}
+
+func TestLargeDirectoryValidate(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "large_directory": -1}`)); err == nil {
+		t.Error("got no error for a negative large_directory")
+	}
+}
+
+// BenchmarkLargeDirectory lists directories of growing size with a
+// large_directory of 100. The memory kept by the listing, reported as
+// retained-B, stays the same however large the directory is.
+func BenchmarkLargeDirectory(b *testing.B) {
+	defer setLargeDirectory(0)
+	setLargeDirectory(100)
+	for _, files := range []int{1000, 4000, 16000} {
+		b.Run(fmt.Sprint(files), func(b *testing.B) {
+			dir := b.TempDir()
+			writeEmptyPictures(b, dir, files)
+			candidateCache.reset()
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			pictures, err := cachedCandidates(dir, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(pictures)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := cachedCandidates(dir, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc), "retained-B")
+		})
+	}
+}
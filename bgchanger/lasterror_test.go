@@ -0,0 +1,40 @@
+package bgchanger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLastErrorSurface(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	var reported []error
+	orig := OnLastErrorChange
+	defer func() { OnLastErrorChange = orig }()
+	OnLastErrorChange = func(err error) { reported = append(reported, err) }
+
+	setter.setErr(errors.New("gsettings is broken"))
+	ChangeBG(cfg)
+	if len(reported) != 1 || reported[0] == nil || !strings.Contains(reported[0].Error(), "gsettings is broken") {
+		t.Fatalf("got %v after a failure, want the error", reported)
+	}
+	if st := getStatus(); !strings.Contains(st.LastError, "gsettings is broken") {
+		t.Errorf("got last_error %q", st.LastError)
+	}
+	ChangeBG(cfg)
+	if len(reported) != 1 {
+		t.Errorf("got %v, want the same error reported once", reported)
+	}
+
+	setter.setErr(nil)
+	ChangeBG(cfg)
+	if len(reported) != 2 || reported[1] != nil {
+		t.Fatalf("got %v after a success, want the error cleared", reported)
+	}
+	if st := getStatus(); st.LastError != "" {
+		t.Errorf("got last_error %q after a success", st.LastError)
+	}
+}
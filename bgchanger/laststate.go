@@ -0,0 +1,125 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+// persistedState is the state saved across restarts.
+type persistedState struct {
+	LastApplied string `json:"last_applied"`
+	Profile     string `json:"profile,omitempty"`
+	Focus       bool   `json:"focus,omitempty"`
+	// FocusPrevious is the picture to go back to after the focus mode.
+	FocusPrevious string `json:"focus_previous,omitempty"`
+	// PictureOptions is the picture-options GNOME had before they were
+	// changed, to restore once they are not set anymore.
+	PictureOptions string `json:"picture_options,omitempty"`
+	// Playlist is how far the playlist has been played.
+	Playlist *playlistPosition `json:"playlist,omitempty"`
+	// UpdateCheck is when check_updates last looked for a new release, and
+	// NotifiedVersion the last release a notification was shown for.
+	UpdateCheck     time.Time `json:"update_check"`
+	NotifiedVersion string    `json:"notified_version,omitempty"`
+	// Queue is the queue of pictures, with persist_queue.
+	Queue []string `json:"queue,omitempty"`
+	// Seen are the pictures seen in discovery mode since the last time all
+	// of them had been.
+	Seen []string `json:"seen,omitempty"`
+}
+
+// persisted serializes the updates of the state file.
+var persisted sync.Mutex
+
+func stateFile() string {
+	return path.Join(configdir.LocalCache(Progname), "state.json")
+}
+
+// loadPersistedState reads the state saved across restarts.
+func loadPersistedState() persistedState {
+	var st persistedState
+	data, err := os.ReadFile(stateFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read state: %v", err)
+		}
+		return st
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		log.Printf("Warning: cannot parse state: %v", err)
+	}
+	return st
+}
+
+// updatePersistedState applies update to the state saved across restarts.
+func updatePersistedState(update func(*persistedState)) error {
+	persisted.Lock()
+	defer persisted.Unlock()
+	st := loadPersistedState()
+	update(&st)
+	data, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := makeDirs(path.Dir(stateFile())); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(stateFile(), data); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// saveLastApplied records the picture most recently applied, so that it is
+// not picked again right after a restart.
+func saveLastApplied(filename string) {
+	if err := updatePersistedState(func(st *persistedState) { st.LastApplied = filename }); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// desktopPicture returns the picture currently set in GNOME, if it is a
+// local file.
+func desktopPicture() string {
+	return gsettingsPicture("picture-uri")
+}
+
+// gsettingsPicture returns the picture set in the given key of the
+// background settings, if it is a local file.
+func gsettingsPicture(key string) string {
+	out, err := gsettingsGet(backgroundSchema, key)
+	if err != nil {
+		debugf("cannot get the current %s: %v", key, err)
+		return ""
+	}
+	u, err := url.Parse(strings.Trim(out, "'"))
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+// recentPictures returns the pictures that the next change should not pick
+// again: the one applied by this instance, or else the one applied before
+// the last restart, and the one GNOME is showing.
+func recentPictures() []string {
+	var recent []string
+	if current := CurrentPicture(); current != "" {
+		recent = append(recent, current)
+	} else if last := loadPersistedState().LastApplied; last != "" {
+		recent = append(recent, last)
+	}
+	if p := desktopPicture(); p != "" {
+		recent = append(recent, p)
+	}
+	return recent
+}
@@ -0,0 +1,26 @@
+package bgchanger
+
+import "testing"
+
+func TestLastAppliedSurvivesRestart(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadPersistedState().LastApplied; got != pictures[0] {
+		t.Fatalf("persisted %q, want %s", got, pictures[0])
+	}
+
+	// a new instance, while GNOME shows another picture
+	resetState()
+	setter.sets = nil
+	setter.preset(backgroundSchema, "picture-uri", "'file://"+pictures[1]+"'")
+	for i := 0; i < 20; i++ {
+		if p, err := PickPicture(cfg, changeExclusions(cfg)...); err != nil || p != pictures[2] {
+			t.Fatalf("picked %s, %v after a restart, want %s", p, err, pictures[2])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintCandidatesMatchesScan(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a picture"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	blocklist := filepath.Join(t.TempDir(), "blocklist")
+	cfg := testConfig(t, `{"pictures_dir": %q, "blocklist_file": %q}`, dir, blocklist)
+	hash, err := contentHash(pictures[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blocklist, []byte(hash+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{pictures[0], pictures[2]}
+	if strings.Join(scanned, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("scan got %v, want %v", scanned, want)
+	}
+
+	out := captureStdout(t, func() {
+		if err := PrintCandidates(cfg, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if got := strings.Fields(out); strings.Join(got, "\n") != strings.Join(scanned, "\n") {
+		t.Errorf("-list printed %v, want %v", got, scanned)
+	}
+
+	out = captureStdout(t, func() {
+		if err := PrintCandidates(cfg, true); err != nil {
+			t.Error(err)
+		}
+	})
+	var listed []string
+	if err := json.Unmarshal([]byte(out), &listed); err != nil {
+		t.Fatalf("-list -json printed invalid JSON: %v", err)
+	}
+	if strings.Join(listed, "\n") != strings.Join(scanned, "\n") {
+		t.Errorf("-list -json printed %v, want %v", listed, scanned)
+	}
+}
@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var ErrAlreadyRunning = errors.New("another instance is already running")
+
+// LockFile returns the path of the lock file held by the running instance.
+func LockFile() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return path.Join(dir, Progname+".lock")
+}
+
+// AcquireLock takes an exclusive lock on filename, and writes our PID into it.
+// It returns ErrAlreadyRunning if another process holds the lock. The lock is
+// held until ReleaseLock is called or the process exits.
+func AcquireLock(filename string) (*os.File, error) {
+	fd, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(fd.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		fd.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrAlreadyRunning
+		}
+		return nil, fmt.Errorf("failed to lock '%s': %w", filename, err)
+	}
+	if err := fd.Truncate(0); err == nil {
+		fmt.Fprintf(fd, "%d\n", os.Getpid())
+	}
+	return fd, nil
+}
+
+// ReleaseLock releases a lock taken with AcquireLock.
+func ReleaseLock(fd *os.File) {
+	if fd == nil {
+		return
+	}
+	syscall.Flock(int(fd.Fd()), syscall.LOCK_UN)
+	fd.Close()
+}
+
+// lockOwner returns the PID written in the lock file by the running instance.
+func lockOwner(filename string) (int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// SignalRunning asks the running instance to change background.
+func SignalRunning() error {
+	pid, err := lockOwner(LockFile())
+	if err != nil {
+		return fmt.Errorf("cannot find the running instance: %w", err)
+	}
+	return syscall.Kill(pid, syscall.SIGUSR1)
+}
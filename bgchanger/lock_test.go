@@ -0,0 +1,35 @@
+package bgchanger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), Progname+".lock")
+	first, err := AcquireLock(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcquireLock(filename); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("got %v while locked, want %v", err, ErrAlreadyRunning)
+	}
+	if pid, err := lockOwner(filename); err != nil || pid != os.Getpid() {
+		t.Errorf("got owner %d, %v, want %d", pid, err, os.Getpid())
+	}
+	ReleaseLock(first)
+	second, err := AcquireLock(filename)
+	if err != nil {
+		t.Fatalf("cannot lock again after releasing: %v", err)
+	}
+	ReleaseLock(second)
+}
+
+func TestLockFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got, want := LockFile(), "/run/user/1000/"+Progname+".lock"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
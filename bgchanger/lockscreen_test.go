@@ -0,0 +1,32 @@
+package bgchanger
+
+import "testing"
+
+func TestLockscreenIndependent(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "change_lockscreen": true, "lockscreen_independent": true}`, dir)
+	for i := 0; i < 5; i++ {
+		ChangeBG(cfg)
+	}
+	desktop := setter.schemaValues(backgroundSchema, "picture-uri")
+	lock := setter.schemaValues(screensaverSchema, "picture-uri")
+	if len(desktop) != 5 || len(lock) != 5 {
+		t.Fatalf("got %d desktop and %d lock screen changes, want 5 of each", len(desktop), len(lock))
+	}
+	for i := range desktop {
+		if desktop[i] == lock[i] {
+			t.Errorf("change %d applied %s to both the desktop and the lock screen", i, desktop[i])
+		}
+	}
+
+	setter = testEnv(t)
+	cfg = testConfig(t, `{"pictures_dir": %q, "change_lockscreen": true}`, dir)
+	ChangeBG(cfg)
+	desktop = setter.schemaValues(backgroundSchema, "picture-uri")
+	lock = setter.schemaValues(screensaverSchema, "picture-uri")
+	if len(desktop) != 1 || len(lock) != 1 || desktop[0] != lock[0] {
+		t.Errorf("got desktop %v and lock screen %v, want the same picture", desktop, lock)
+	}
+}
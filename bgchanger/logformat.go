@@ -0,0 +1,121 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// log formats
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logLevels maps the prefixes of the log messages to their level. Messages
+// without one of these prefixes are at the info level.
+var logLevels = []struct {
+	prefix, level string
+}{
+	{"Error: ", "error"},
+	{"Warning: ", "warning"},
+	{"Debug: ", "debug"},
+}
+
+// logFields are the fields of a log line in addition to its message, like
+// the chosen picture or the number of candidates.
+type logFields map[string]interface{}
+
+// jsonLog writes the log lines as JSON objects when log_format is json.
+var jsonLog = struct {
+	sync.Mutex
+	enabled bool
+	out     io.Writer
+}{out: os.Stderr}
+
+// setLogFormat switches the log output between text and JSON lines.
+func setLogFormat(format string) {
+	jsonLog.Lock()
+	defer jsonLog.Unlock()
+	enabled := format == logFormatJSON
+	if enabled == jsonLog.enabled {
+		return
+	}
+	jsonLog.enabled = enabled
+	if enabled {
+		log.SetFlags(0)
+		log.SetOutput(jsonLogWriter{})
+	} else {
+		log.SetFlags(log.LstdFlags)
+		log.SetOutput(jsonLog.out)
+	}
+}
+
+// logLevel returns the level of a log message, and the message without the
+// prefix of the level.
+func logLevel(msg string) (string, string) {
+	for _, l := range logLevels {
+		if strings.HasPrefix(msg, l.prefix) {
+			return l.level, strings.TrimPrefix(msg, l.prefix)
+		}
+	}
+	return "info", msg
+}
+
+// formatJSONLog returns a log line as a JSON object, with its level, time,
+// message and fields.
+func formatJSONLog(t time.Time, msg string, fields logFields) ([]byte, error) {
+	level, msg := logLevel(msg)
+	entry := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["level"] = level
+	entry["time"] = t.Format(time.RFC3339Nano)
+	entry["message"] = msg
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeJSONLog writes a log line as a JSON object. jsonLog must be locked.
+func writeJSONLog(msg string, fields logFields) {
+	data, err := formatJSONLog(time.Now(), msg, fields)
+	if err != nil {
+		// the fields cannot be marshalled, keep the message
+		data, _ = formatJSONLog(time.Now(), msg, nil)
+	}
+	_, _ = jsonLog.out.Write(data)
+}
+
+// jsonLogWriter is the output of the standard logger with log_format json,
+// writing each line as a JSON object.
+type jsonLogWriter struct{}
+
+func (jsonLogWriter) Write(p []byte) (int, error) {
+	jsonLog.Lock()
+	defer jsonLog.Unlock()
+	writeJSONLog(strings.TrimSuffix(string(p), "\n"), nil)
+	return len(p), nil
+}
+
+// logWith logs a message with fields, which are only written with log_format
+// json; in text the message alone is logged.
+func logWith(fields logFields, format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	jsonLog.Lock()
+	if !jsonLog.enabled {
+		jsonLog.Unlock()
+		log.Print(msg)
+		return
+	}
+	defer jsonLog.Unlock()
+	writeJSONLog(msg, fields)
+}
@@ -0,0 +1,125 @@
+package bgchanger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureJSONLog switches to log_format json for the test, and returns the
+// buffer the log lines are written to.
+func captureJSONLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	jsonLog.Lock()
+	jsonLog.out = &buf
+	jsonLog.Unlock()
+	setLogFormat(logFormatJSON)
+	t.Cleanup(func() {
+		jsonLog.Lock()
+		jsonLog.out = os.Stderr
+		jsonLog.Unlock()
+		setLogFormat(logFormatText)
+	})
+	return &buf
+}
+
+// jsonLogLines parses the log lines, failing if any is not a JSON object.
+func jsonLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("malformed log line %q: %v", line, err)
+		}
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestJSONLogChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "log_format": "json"}`, dir)
+	buf := captureJSONLog(t)
+	ChangeBG(cfg)
+	applied := strings.TrimPrefix(setter.get("picture-uri"), "file://")
+
+	var picked, changed map[string]interface{}
+	for _, entry := range jsonLogLines(t, buf) {
+		for _, field := range []string{"level", "time", "message"} {
+			if _, ok := entry[field].(string); !ok {
+				t.Errorf("got %v, want a string %s", entry, field)
+			}
+		}
+		if _, err := time.Parse(time.RFC3339Nano, entry["time"].(string)); err != nil {
+			t.Errorf("invalid time: %v", err)
+		}
+		msg := entry["message"].(string)
+		switch {
+		case strings.HasPrefix(msg, "Picked "):
+			picked = entry
+		case strings.HasPrefix(msg, "Background changed to "):
+			changed = entry
+		}
+	}
+	if picked == nil || changed == nil {
+		t.Fatalf("no pick or change logged:\n%s", buf)
+	}
+	if picked["level"] != "info" || !contains(pictures, picked["path"].(string)) ||
+		picked["candidates"] != float64(3) || picked["source"] == nil {
+		t.Errorf("got pick %v, want its path, 3 candidates and the source", picked)
+	}
+	if changed["path"] != picked["path"] || changed["applied"] != applied {
+		t.Errorf("got change %v, want the picked path and the applied %s", changed, applied)
+	}
+}
+
+func TestJSONLogLevels(t *testing.T) {
+	buf := captureJSONLog(t)
+	log.Printf("Warning: cannot do %s", "this")
+	log.Printf("Error: failed")
+	log.Printf("Just saying")
+	lines := jsonLogLines(t, buf)
+	want := [][2]string{{"warning", "cannot do this"}, {"error", "failed"}, {"info", "Just saying"}}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf)
+	}
+	for i, w := range want {
+		if lines[i]["level"] != w[0] || lines[i]["message"] != w[1] {
+			t.Errorf("got %v, want level %s and message %q", lines[i], w[0], w[1])
+		}
+	}
+}
+
+func TestTextLog(t *testing.T) {
+	var buf bytes.Buffer
+	jsonLog.Lock()
+	jsonLog.out = &buf
+	jsonLog.Unlock()
+	// switching to json and back to text restores the text output
+	setLogFormat(logFormatJSON)
+	setLogFormat(logFormatText)
+	t.Cleanup(func() {
+		jsonLog.Lock()
+		jsonLog.out = os.Stderr
+		jsonLog.Unlock()
+		log.SetOutput(os.Stderr)
+	})
+	logWith(logFields{"path": "/p/a.png"}, "Picked '%s'", "/p/a.png")
+	if out := buf.String(); strings.HasPrefix(out, "{") || !strings.HasSuffix(out, "Picked '/p/a.png'\n") || strings.Contains(out, `"path"`) {
+		t.Errorf("got %q, want a text line without the fields", out)
+	}
+}
+
+func TestLogFormatValidate(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "log_format": "xml"}`)); err == nil {
+		t.Error("expected an error for an unknown log_format")
+	}
+}
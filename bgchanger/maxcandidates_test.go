@@ -0,0 +1,55 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestListCandidatesSamplesUniformly(t *testing.T) {
+	dir := t.TempDir()
+	const (
+		files = 200
+		limit = 10
+		runs  = 2000
+	)
+	for i := 0; i < files; i++ {
+		// the contents are not read when listing
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%03d.jpg", i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	counts := make(map[string]int)
+	for i := 0; i < runs; i++ {
+		pictures, err := ListCandidates(dir, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pictures) != limit || !sort.StringsAreSorted(pictures) {
+			t.Fatalf("got %v, want %d sorted pictures", pictures, limit)
+		}
+		for _, p := range pictures {
+			counts[p]++
+		}
+	}
+	if len(counts) != files {
+		t.Errorf("sampled %d different pictures, want all %d", len(counts), files)
+	}
+	// each picture is expected runs*limit/files = 100 times, with a standard
+	// deviation of about 10
+	for p, n := range counts {
+		if n < 50 || n > 150 {
+			t.Errorf("sampled %s %d times, want about 100", filepath.Base(p), n)
+		}
+	}
+
+	all, err := ListCandidates(dir, files+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != files {
+		t.Errorf("got %d pictures with a limit above their number, want all %d", len(all), files)
+	}
+}
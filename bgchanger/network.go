@@ -0,0 +1,248 @@
+package bgchanger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// networkPollInterval is how often the network is checked for changes when
+// network_sources is set.
+var networkPollInterval = 15 * time.Second
+
+// NetworkSource maps a network, identified by the SSID of the wifi network or
+// by the MAC address of the default gateway, to the pictures directory or the
+// profile to use while connected to it.
+type NetworkSource struct {
+	SSID    string `json:"ssid"`
+	Gateway string `json:"gateway"`
+	Path    string `json:"path"`
+	Profile string `json:"profile"`
+}
+
+func (ns *NetworkSource) validate(profiles map[string]json.RawMessage) error {
+	if (ns.SSID == "") == (ns.Gateway == "") {
+		return fmt.Errorf("exactly one of ssid and gateway must be set")
+	}
+	if ns.Gateway != "" {
+		if _, err := net.ParseMAC(ns.Gateway); err != nil {
+			return fmt.Errorf("invalid gateway '%s': %w", ns.Gateway, err)
+		}
+	}
+	if (ns.Path == "") == (ns.Profile == "") {
+		return fmt.Errorf("exactly one of path and profile must be set")
+	}
+	if ns.Profile != "" {
+		if _, ok := profiles[ns.Profile]; !ok {
+			return fmt.Errorf("unknown profile '%s'", ns.Profile)
+		}
+	}
+	return nil
+}
+
+// networkState is the network the machine is connected to, with empty fields
+// when unknown.
+type networkState struct {
+	SSID    string
+	Gateway string
+}
+
+// detectNetwork returns the current network, from nmcli for the SSID and from
+// the kernel routing and ARP tables for the gateway. It is a variable so that
+// the network can be faked.
+var detectNetwork = func() networkState {
+	var st networkState
+	if out, err := commandOutput("nmcli", "-t", "-f", "ACTIVE,SSID", "device", "wifi"); err != nil {
+		debugf("cannot get the wifi network: %v", err)
+	} else {
+		st.SSID = activeSSID(string(out))
+	}
+	if gw, err := defaultGatewayMAC(); err != nil {
+		debugf("cannot get the default gateway: %v", err)
+	} else {
+		st.Gateway = gw
+	}
+	return st
+}
+
+// activeSSID returns the SSID of the active network in the terse output of
+// `nmcli -t -f ACTIVE,SSID device wifi`, in which colons are escaped.
+func activeSSID(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if ssid := strings.TrimPrefix(line, "yes:"); ssid != line {
+			return strings.ReplaceAll(ssid, `\:`, ":")
+		}
+	}
+	return ""
+}
+
+// defaultGatewayMAC returns the MAC address of the default gateway, looking
+// up its address in /proc/net/route and then its MAC in /proc/net/arp.
+func defaultGatewayMAC() (string, error) {
+	ip, err := defaultGateway("/proc/net/route")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return "", fmt.Errorf("failed to read ARP table: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(sc.Text())
+		if len(fields) >= 4 && fields[0] == ip.String() {
+			return fields[3], nil
+		}
+	}
+	return "", fmt.Errorf("gateway %s not in the ARP table", ip)
+}
+
+// defaultGateway returns the gateway of the default route in the routing
+// table at filename, where addresses are hex in host byte order.
+func defaultGateway(filename string) (net.IP, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing table: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// Iface, Destination, Gateway, ...
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		b, err := hex.DecodeString(fields[2])
+		if err != nil || len(b) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+		return ip, nil
+	}
+	return nil, fmt.Errorf("no default route")
+}
+
+// matchNetwork returns the first of the rules matching the network, or nil.
+func matchNetwork(rules []NetworkSource, st networkState) *NetworkSource {
+	for i := range rules {
+		r := &rules[i]
+		if r.SSID != "" && r.SSID == st.SSID {
+			return r
+		}
+		if r.Gateway != "" && st.Gateway != "" && strings.EqualFold(r.Gateway, st.Gateway) {
+			return r
+		}
+	}
+	return nil
+}
+
+// networkRule returns the network_sources entry for the current network, or
+// nil if none matches.
+func networkRule(rules []NetworkSource) *NetworkSource {
+	if len(rules) == 0 {
+		return nil
+	}
+	return matchNetwork(rules, detectNetwork())
+}
+
+// networkChanges is notified when the network changes to one matching a
+// different network_sources entry.
+var networkChanges = make(chan struct{}, 1)
+
+// NetworkChanges returns a channel that receives a value when the network
+// changes to one matching a different network_sources entry, at which point
+// ApplyNetworkChange should be called.
+func NetworkChanges() <-chan struct{} {
+	return networkChanges
+}
+
+// networkWatcher is the running network watcher, if any, with the rules it
+// watches.
+var networkWatcher = struct {
+	sync.Mutex
+	rules []NetworkSource
+	stop  chan struct{}
+}{}
+
+// StartNetworkWatcher checks the network periodically if network_sources is
+// set, and notifies NetworkChanges when another entry, or none, matches.
+// ApplyConfig calls it again, so that the watcher is started, restarted or
+// stopped when network_sources changes.
+func StartNetworkWatcher(cfg *Config) {
+	networkWatcher.Lock()
+	defer networkWatcher.Unlock()
+	if networkWatcher.stop != nil && reflect.DeepEqual(networkWatcher.rules, cfg.NetworkSources) {
+		return
+	}
+	stopNetworkWatcherLocked()
+	if len(cfg.NetworkSources) == 0 {
+		return
+	}
+	log.Printf("Watching the network for network_sources")
+	// the watcher has its own copy, as ApplyConfig replaces cfg while it runs
+	rules := append([]NetworkSource(nil), cfg.NetworkSources...)
+	stop := make(chan struct{})
+	networkWatcher.rules, networkWatcher.stop = rules, stop
+	go watchNetwork(rules, stop)
+}
+
+// stopNetworkWatcherLocked stops the network watcher, if running. The
+// networkWatcher lock must be held.
+func stopNetworkWatcherLocked() {
+	if networkWatcher.stop != nil {
+		close(networkWatcher.stop)
+	}
+	networkWatcher.rules, networkWatcher.stop = nil, nil
+}
+
+func watchNetwork(rules []NetworkSource, stop chan struct{}) {
+	t := time.NewTicker(networkPollInterval)
+	defer t.Stop()
+	last := networkRule(rules)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		r := networkRule(rules)
+		if r == last || (r != nil && last != nil && *r == *last) {
+			continue
+		}
+		last = r
+		select {
+		case networkChanges <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ApplyNetworkChange reloads the config, to switch to the profile of the new
+// network if any, and changes the background unless automatic changes are
+// not allowed right now, e.g. during quiet hours.
+func ApplyNetworkChange(cfg *Config) {
+	log.Printf("Network changed")
+	if configFile := getStatus().ConfigFile; configFile != "" {
+		newCfg, err := ReloadConfig(configFile)
+		if err != nil {
+			log.Printf("Error: not reloading the config for the new network: %v", err)
+		} else {
+			ApplyConfig(cfg, newCfg)
+		}
+	}
+	if changeAllowed(cfg) {
+		ChangeBG(cfg)
+	}
+}
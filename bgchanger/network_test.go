@@ -0,0 +1,197 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNetwork makes detectNetwork return the network set with the returned
+// function.
+func fakeNetwork(t *testing.T) func(networkState) {
+	t.Helper()
+	var (
+		mu sync.Mutex
+		st networkState
+	)
+	old := detectNetwork
+	detectNetwork = func() networkState {
+		mu.Lock()
+		defer mu.Unlock()
+		return st
+	}
+	t.Cleanup(func() { detectNetwork = old })
+	return func(n networkState) {
+		mu.Lock()
+		st = n
+		mu.Unlock()
+	}
+}
+
+func TestMatchNetwork(t *testing.T) {
+	rules := []NetworkSource{
+		{SSID: "home", Path: "/home"},
+		{Gateway: "aa:bb:cc:dd:ee:ff", Path: "/work"},
+	}
+	for _, tc := range []struct {
+		st   networkState
+		want string
+	}{
+		{networkState{SSID: "home"}, "/home"},
+		{networkState{SSID: "cafe", Gateway: "AA:BB:CC:DD:EE:FF"}, "/work"},
+		{networkState{SSID: "cafe", Gateway: "11:22:33:44:55:66"}, ""},
+		{networkState{}, ""},
+	} {
+		r := matchNetwork(rules, tc.st)
+		got := ""
+		if r != nil {
+			got = r.Path
+		}
+		if got != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.st, got, tc.want)
+		}
+	}
+}
+
+func TestActiveSSID(t *testing.T) {
+	out := "no:neighbours\nyes:my\\:net\nno:other\n"
+	if got := activeSSID(out); got != "my:net" {
+		t.Errorf("got %q, want my:net", got)
+	}
+	if got := activeSSID("no:neighbours\n"); got != "" {
+		t.Errorf("got %q without an active network", got)
+	}
+}
+
+func TestDefaultGateway(t *testing.T) {
+	route := filepath.Join(t.TempDir(), "route")
+	if err := os.WriteFile(route, []byte("Iface\tDestination\tGateway\tFlags\n"+
+		"wlan0\t0000A8C0\t00000000\t0001\n"+
+		"wlan0\t00000000\t0100A8C0\t0003\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ip, err := defaultGateway(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "192.168.0.1" {
+		t.Errorf("got gateway %s, want 192.168.0.1", ip)
+	}
+}
+
+func TestNetworkSources(t *testing.T) {
+	testEnv(t)
+	setNetwork := fakeNetwork(t)
+	dir := t.TempDir()
+	home := writeTestPictures(t, filepath.Join(dir, "home"), 1)
+	work := writeTestPictures(t, filepath.Join(dir, "work"), 1)
+	global := writeTestPictures(t, filepath.Join(dir, "global"), 1)
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "global", "network_sources": [
+		{"ssid": "home", "path": "home"},
+		{"gateway": "aa:bb:cc:dd:ee:ff", "profile": "work"}],
+		"profiles": {"work": {"pictures_dir": "work"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		st   networkState
+		want string
+	}{
+		{networkState{SSID: "home"}, home[0]},
+		{networkState{Gateway: "aa:bb:cc:dd:ee:ff"}, work[0]},
+		{networkState{SSID: "elsewhere"}, global[0]},
+	} {
+		setNetwork(tc.st)
+		cfg, err := ReloadConfig(configFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err := PickPicture(cfg); err != nil || p != tc.want {
+			t.Errorf("%+v: got %s, %v, want %s", tc.st, p, err, tc.want)
+		}
+	}
+}
+
+func TestNetworkWatcher(t *testing.T) {
+	testEnv(t)
+	setNetwork := fakeNetwork(t)
+	old := networkPollInterval
+	networkPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { networkPollInterval = old })
+
+	// not watching without network_sources, until a reload adds them
+	cfg := testConfig(t, `{"pictures_dir": "/p"}`)
+	StartNetworkWatcher(cfg)
+	if networkWatcher.stop != nil {
+		t.Fatal("watching the network without network_sources")
+	}
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": "/p", "network_sources": [{"ssid": "home", "path": "/home"}]}`))
+	networkWatcher.Lock()
+	stop := networkWatcher.stop
+	networkWatcher.Unlock()
+	if stop == nil {
+		t.Fatal("adding network_sources did not start the watcher")
+	}
+	// the watcher has its own rules, whatever happens to cfg
+	cfg.NetworkSources[0].SSID = "changed"
+
+	// let the watcher see the initial network first
+	time.Sleep(10 * networkPollInterval)
+	setNetwork(networkState{SSID: "home"})
+	select {
+	case <-networkChanges:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the network change was not notified")
+	}
+
+	// unchanged rules keep the running watcher
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": "/p", "network_sources": [{"ssid": "home", "path": "/home"}], "interval": "1h"}`))
+	networkWatcher.Lock()
+	same := networkWatcher.stop == stop
+	networkWatcher.Unlock()
+	if !same {
+		t.Error("the watcher was restarted without changes to network_sources")
+	}
+
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": "/p"}`))
+	networkWatcher.Lock()
+	running := networkWatcher.stop != nil
+	networkWatcher.Unlock()
+	if running {
+		t.Error("removing network_sources did not stop the watcher")
+	}
+	select {
+	case <-stop:
+	default:
+		t.Error("the previous watcher was not stopped")
+	}
+}
+
+func TestApplyNetworkChangeIsGated(t *testing.T) {
+	setter := testEnv(t)
+	fakeNetwork(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	now := time.Now()
+	cfg := testConfig(t, `{"pictures_dir": %q, "quiet_hours": [{"start": %q, "end": %q}]}`,
+		dir, now.Add(-time.Hour).Format("15:04"), now.Add(time.Hour).Format("15:04"))
+	ApplyNetworkChange(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v during quiet hours", setter.sets)
+	}
+
+	cfg = testConfig(t, `{"pictures_dir": %q}`, dir)
+	setPaused(true)
+	ApplyNetworkChange(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v while paused", setter.sets)
+	}
+	setPaused(false)
+	ApplyNetworkChange(cfg)
+	if uris := setter.values("picture-uri"); len(uris) != 1 || !strings.HasPrefix(uris[0], "file://"+dir) {
+		t.Errorf("got picture-uri %v, want a change", uris)
+	}
+}
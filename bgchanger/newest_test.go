@@ -0,0 +1,58 @@
+package bgchanger
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewestMode(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 4)
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// 2.png and 3.png are the newest, with the same time
+	for i, d := range []time.Duration{0, time.Hour, 2 * time.Hour, 2 * time.Hour} {
+		if err := os.Chtimes(pictures[i], base.Add(d), base.Add(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "newest"}`, dir)
+	for i := 0; i < 5; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != pictures[2] {
+			t.Fatalf("picked %s, want the newest %s, first by name", p, pictures[2])
+		}
+	}
+
+	later := base.Add(3 * time.Hour)
+	if err := os.Chtimes(pictures[0], later, later); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := PickPicture(cfg); err != nil || p != pictures[0] {
+		t.Errorf("picked %s, %v after touching it, want %s", p, err, pictures[0])
+	}
+}
+
+func TestNewestModeStaysOnNewest(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, p := range pictures {
+		mtime := base.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "newest"}`, dir)
+	for i := 0; i < 4; i++ {
+		ChangeBG(cfg)
+		if uri := setter.get("picture-uri"); uri != "file://"+pictures[2] {
+			t.Fatalf("change %d set %s, want the newest %s", i, uri, pictures[2])
+		}
+	}
+}
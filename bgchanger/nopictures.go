@@ -0,0 +1,131 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// noPicturesPollInterval is how often to look for pictures again after all
+// the directories turned out empty.
+var noPicturesPollInterval = 30 * time.Second
+
+// OnPicturesAvailableChange is called with false when all the pictures
+// directories, the dark one included, are empty and there is no
+// fallback_color, and with true once pictures are back.
+var OnPicturesAvailableChange = func(available bool) {}
+
+var noPicturesPoll = struct {
+	sync.Mutex
+	polling bool
+	// fallbackShown is set while fallback_color hides the picture
+	fallbackShown bool
+}{}
+
+// noPicturesAnywhere reports whether all the sources, and dark_pictures_dir
+// if set, have no candidates. Unlike a single empty directory, there's
+// nothing to fall back to then.
+func noPicturesAnywhere(cfg *Config) bool {
+	pictures, err := ScanCandidates(cfg)
+	if err != nil || len(pictures) > 0 {
+		return false
+	}
+	if cfg.DarkPicturesDir != "" {
+		dark, err := cachedCandidates(cfg.DarkPicturesDir, cfg.MaxCandidates)
+		if err != nil || len(dark) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// applyNoPictures handles a change with no pictures anywhere: it shows
+// fallback_color if set, otherwise it returns an error and polls for the
+// pictures to come back.
+func applyNoPictures(cfg *Config) error {
+	if cfg.FallbackColor != "" {
+		if err := setColors(cfg.FallbackColor, cfg.FallbackColor, "solid"); err != nil {
+			return fmt.Errorf("failed to set fallback_color: %w", err)
+		}
+		log.Printf("No pictures found, background set to %s", cfg.FallbackColor)
+		noPicturesPoll.Lock()
+		noPicturesPoll.fallbackShown = true
+		noPicturesPoll.Unlock()
+		return nil
+	}
+	noPicturesPoll.Lock()
+	defer noPicturesPoll.Unlock()
+	if !noPicturesPoll.polling {
+		noPicturesPoll.polling = true
+		OnPicturesAvailableChange(false)
+		go pollPictures(*cfg)
+	}
+	return fmt.Errorf("no pictures found in any of the pictures directories")
+}
+
+// picturesBack is notified when pollPictures finds pictures again.
+var picturesBack = make(chan struct{}, 1)
+
+// PicturesBack returns a channel that receives a value when pictures appear
+// again after there were none anywhere, at which point ApplyPicturesBack
+// should be called.
+func PicturesBack() <-chan struct{} {
+	return picturesBack
+}
+
+// pollPictures waits for pictures to appear again, and notifies
+// PicturesBack. It polls a copy of the config, as ApplyConfig replaces the
+// one of the main loop meanwhile.
+func pollPictures(cfg Config) {
+	t := time.NewTicker(noPicturesPollInterval)
+	defer t.Stop()
+	for range t.C {
+		if noPicturesAnywhere(&cfg) {
+			continue
+		}
+		select {
+		case picturesBack <- struct{}{}:
+		default:
+		}
+		return
+	}
+}
+
+// ApplyPicturesBack changes the background now that pictures are back,
+// unless automatic changes are not allowed right now. If a reload left the
+// current config without pictures, it keeps polling instead.
+func ApplyPicturesBack(cfg *Config) {
+	noPicturesPoll.Lock()
+	polling := noPicturesPoll.polling
+	noPicturesPoll.Unlock()
+	if !polling {
+		return
+	}
+	if noPicturesAnywhere(cfg) {
+		go pollPictures(*cfg)
+		return
+	}
+	noPicturesPoll.Lock()
+	noPicturesPoll.polling = false
+	noPicturesPoll.Unlock()
+	log.Printf("Pictures are available again")
+	OnPicturesAvailableChange(true)
+	if changeAllowed(cfg) {
+		ChangeBG(cfg)
+	}
+}
+
+// hideFallbackColor shows the picture again after fallback_color, if needed.
+func hideFallbackColor(cfg *Config) {
+	noPicturesPoll.Lock()
+	shown := noPicturesPoll.fallbackShown
+	noPicturesPoll.fallbackShown = false
+	noPicturesPoll.Unlock()
+	if !shown {
+		return
+	}
+	if err := restorePictureOptions(cfg); err != nil {
+		log.Printf("Error: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package bgchanger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoPicturesFallbackColor(t *testing.T) {
+	setter := testEnv(t)
+	dir, darkDir := t.TempDir(), t.TempDir()
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q, "fallback_color": "#102030"}`, dir, darkDir)
+	ChangeBG(cfg)
+	if got := setter.get("primary-color"); got != "#102030" {
+		t.Errorf("got primary-color %q, want fallback_color", got)
+	}
+	if got := setter.get("picture-options"); got != "none" {
+		t.Errorf("got picture-options %q, want none to show the color", got)
+	}
+
+	// the picture is shown again once there are pictures
+	pictures := writeTestPictures(t, dir, 1)
+	ChangeBG(cfg)
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[0] {
+		t.Errorf("got picture-uri %s, want %s", uri, pictures[0])
+	}
+	if got := setter.get("picture-options"); got == "none" {
+		t.Error("picture-options was not restored after fallback_color")
+	}
+}
+
+func TestNoPicturesAnywhere(t *testing.T) {
+	setter := testEnv(t)
+	old := noPicturesPollInterval
+	noPicturesPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { noPicturesPollInterval = old })
+	available := make(chan bool, 2)
+	OnPicturesAvailableChange = func(ok bool) { available <- ok }
+	t.Cleanup(func() { OnPicturesAvailableChange = func(bool) {} })
+
+	dir, darkDir := t.TempDir(), t.TempDir()
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q}`, dir, darkDir)
+	ChangeBG(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v, want none without pictures or fallback_color", setter.sets)
+	}
+	if ok := <-available; ok {
+		t.Fatal("got pictures available, want unavailable")
+	}
+	if st := getStatus(); !strings.Contains(st.LastError, "no pictures found in any") {
+		t.Errorf("got last error %q, want the both-empty error", st.LastError)
+	}
+	// a second change doesn't notify again
+	ChangeBG(cfg)
+	if len(available) != 0 {
+		t.Error("got a second notification")
+	}
+
+	pictures := writeTestPictures(t, dir, 1)
+	select {
+	case <-picturesBack:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the pictures coming back were not noticed")
+	}
+	// the main loop applies them
+	ApplyPicturesBack(cfg)
+	if ok := <-available; !ok {
+		t.Error("got pictures unavailable, want available once pictures are back")
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[0] {
+		t.Errorf("got picture-uri %s once pictures are back, want %s", uri, pictures[0])
+	}
+}
+
+func TestSingleEmptyDirectory(t *testing.T) {
+	setter := testEnv(t)
+	called := false
+	OnPicturesAvailableChange = func(bool) { called = true }
+	t.Cleanup(func() { OnPicturesAvailableChange = func(bool) {} })
+
+	dir, darkDir := t.TempDir(), t.TempDir()
+	writeTestPictures(t, darkDir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q, "fallback_color": "#102030"}`, dir, darkDir)
+	if noPicturesAnywhere(cfg) {
+		t.Error("got no pictures anywhere, but dark_pictures_dir has some")
+	}
+	ChangeBG(cfg)
+	if called {
+		t.Error("a single empty directory disabled the changes")
+	}
+	if got := setter.get("primary-color"); got != "" {
+		t.Errorf("got primary-color %q, want no fallback_color for a single empty directory", got)
+	}
+	if st := getStatus(); st.LastError == "" || strings.Contains(st.LastError, "any") {
+		t.Errorf("got last error %q, want the usual error for an empty directory", st.LastError)
+	}
+}
@@ -0,0 +1,122 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// defaultRejectsDir is where -organize moves the unwanted pictures, relative
+// to the directory they are in.
+const defaultRejectsDir = "rejects"
+
+// Move is a picture moved, or to be moved, by Organize.
+type Move struct {
+	From string
+	To   string
+}
+
+// localDirs returns the local picture directories that the user manages,
+// i.e. pictures_dir and the dir, time and calendar sources. Archives and
+// downloaded pictures are left out.
+func localDirs(cfg *Config) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	if !isArchive(cfg.PicturesDir) {
+		add(cfg.PicturesDir)
+	}
+	for _, src := range cfg.Sources {
+		if src.Type == sourceDir {
+			add(src.Path)
+		}
+	}
+	for _, tp := range cfg.TimeSources {
+		for _, src := range tp.Sources {
+			if src.Type == sourceDir {
+				add(src.Path)
+			}
+		}
+	}
+	for _, dir := range cfg.CalendarSources {
+		add(dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// rejects returns the moves that put the blocked pictures, and the ones with
+// the lowest possible rating, into the rejects directory.
+func rejects(cfg *Config) ([]Move, error) {
+	blocked, err := blocklistCache.get(blocklistFile(cfg))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	var moves []Move
+	for _, dir := range localDirs(cfg) {
+		pictures, err := ListCandidates(dir, 0)
+		if err != nil {
+			return nil, err
+		}
+		rejectsDir := cfg.RejectsDir
+		if !filepath.IsAbs(rejectsDir) {
+			rejectsDir = filepath.Join(dir, rejectsDir)
+		}
+		for _, p := range pictures {
+			reject := getRating(p) <= -maxRating
+			if !reject && len(blocked) > 0 {
+				h, err := contentHash(p)
+				if err != nil {
+					log.Printf("Warning: cannot hash '%s': %v", p, err)
+					continue
+				}
+				reject = blocked[h]
+			}
+			if reject {
+				moves = append(moves, Move{From: p, To: filepath.Join(rejectsDir, filepath.Base(p))})
+			}
+		}
+	}
+	return moves, nil
+}
+
+// Organize moves the blocked pictures, and the ones with the lowest possible
+// rating, into the rejects_dir subdirectory of their directory, and returns
+// what it moved. Unless apply is true, nothing is moved and the returned
+// moves are the ones that would be done. Existing files are never
+// overwritten.
+func Organize(cfg *Config, apply bool) ([]Move, error) {
+	moves, err := rejects(cfg)
+	if err != nil || !apply {
+		return moves, err
+	}
+	var done []Move
+	for _, m := range moves {
+		if err := checkWrite(m.From); err != nil {
+			return done, err
+		}
+		if err := checkWrite(m.To); err != nil {
+			return done, err
+		}
+		if err := os.MkdirAll(filepath.Dir(m.To), 0755); err != nil {
+			return done, fmt.Errorf("failed to create rejects directory: %w", err)
+		}
+		if _, err := os.Lstat(m.To); err == nil {
+			log.Printf("Warning: not moving '%s', '%s' already exists", m.From, m.To)
+			continue
+		}
+		if err := os.Rename(m.From, m.To); err != nil {
+			return done, fmt.Errorf("failed to move '%s': %w", m.From, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
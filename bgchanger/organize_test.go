@@ -0,0 +1,92 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOrganize(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 4)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := blockPicture(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := ratePicture(pictures[1], -maxRating); err != nil {
+		t.Fatal(err)
+	}
+	// low, but not the lowest rating
+	if err := ratePicture(pictures[2], -1); err != nil {
+		t.Fatal(err)
+	}
+	rejectsDir := filepath.Join(dir, defaultRejectsDir)
+	want := []Move{
+		{From: pictures[0], To: filepath.Join(rejectsDir, "0.png")},
+		{From: pictures[1], To: filepath.Join(rejectsDir, "1.png")},
+	}
+
+	// dry-run by default
+	moves, err := Organize(cfg, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("got moves %v, want %v", moves, want)
+	}
+	for _, p := range pictures {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("dry-run moved %s: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(rejectsDir); !os.IsNotExist(err) {
+		t.Errorf("dry-run created the rejects directory: %v", err)
+	}
+
+	moves, err = Organize(cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("got moves %v, want %v", moves, want)
+	}
+	for _, m := range want {
+		if _, err := os.Stat(m.From); !os.IsNotExist(err) {
+			t.Errorf("%s was not moved: %v", m.From, err)
+		}
+		if _, err := os.Stat(m.To); err != nil {
+			t.Errorf("%s is missing: %v", m.To, err)
+		}
+	}
+	for _, p := range pictures[2:] {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was moved: %v", p, err)
+		}
+	}
+}
+
+func TestOrganizeKeepsExistingRejects(t *testing.T) {
+	testEnv(t)
+	dir, rejectsDir := t.TempDir(), t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "rejects_dir": %q}`, dir, rejectsDir)
+	if err := ratePicture(pictures[0], -maxRating); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(rejectsDir, "0.png")
+	if err := os.WriteFile(existing, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	moves, err := Organize(cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 0 {
+		t.Errorf("got moves %v, want none over an existing file", moves)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep" {
+		t.Errorf("the existing reject was overwritten")
+	}
+}
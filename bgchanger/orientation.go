@@ -0,0 +1,99 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// pictureOptions are the values of the picture-options GNOME setting.
+var pictureOptions = []string{"none", "wallpaper", "centered", "scaled", "stretched", "zoom", "spanned"}
+
+func validatePictureOptions(name, options string) error {
+	if options == "" || contains(pictureOptions, options) {
+		return nil
+	}
+	return fmt.Errorf("invalid %s '%s', want one of %v", name, options, pictureOptions)
+}
+
+// orientationOptions returns the picture-options for the picture, from
+// portrait_options or landscape_options depending on its orientation, or
+// else picture_options. Square pictures count as landscape. When only one
+// of the orientations has options, the other one goes back to zoom. It
+// returns false if none of them is configured, and picture-options is left
+// as it is.
+func orientationOptions(cfg *Config, filename string) (string, bool) {
+	if !orientationConfigured(cfg) {
+		return "", false
+	}
+	if cfg.PortraitOptions == "" && cfg.LandscapeOptions == "" {
+		return cfg.PictureOptions, cfg.PictureOptions != ""
+	}
+	fallback := cfg.PictureOptions
+	if fallback == "" {
+		fallback = "zoom"
+	}
+	w, h, err := imageSize(filename)
+	if err != nil {
+		log.Printf("Warning: cannot get the orientation of '%s': %v", filename, err)
+		return fallback, true
+	}
+	options := cfg.LandscapeOptions
+	if h > w {
+		options = cfg.PortraitOptions
+	}
+	if options == "" {
+		options = fallback
+	}
+	return options, true
+}
+
+// orientationConfigured returns whether picture-options is set from the
+// config, with picture_options, landscape_options or portrait_options.
+func orientationConfigured(cfg *Config) bool {
+	return cfg.PictureOptions != "" || cfg.LandscapeOptions != "" || cfg.PortraitOptions != ""
+}
+
+// defaultPictureOptions is the picture-options of GNOME by default.
+const defaultPictureOptions = "zoom"
+
+// savePictureOptions remembers the current picture-options before they are
+// changed, unless they were already saved, so that restorePictureOptions can
+// go back to them.
+func savePictureOptions() {
+	if loadPersistedState().PictureOptions != "" {
+		return
+	}
+	out, err := gsettingsGet(backgroundSchema, "picture-options")
+	if err != nil {
+		debugf("cannot get picture-options: %v", err)
+		return
+	}
+	options := strings.Trim(out, "'")
+	if !contains(pictureOptions, options) {
+		return
+	}
+	if err := updatePersistedState(func(st *persistedState) { st.PictureOptions = options }); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// restorePictureOptions sets picture-options back to picture_options if
+// configured, or else to their value saved before they were changed, which
+// is then forgotten.
+func restorePictureOptions(cfg *Config) error {
+	if cfg.PictureOptions != "" {
+		if err := gsettingsSet(backgroundSchema, "picture-options", cfg.PictureOptions); err != nil {
+			return fmt.Errorf("failed to reset picture-options: %w", err)
+		}
+		return nil
+	}
+	options := loadPersistedState().PictureOptions
+	if options == "" {
+		options = defaultPictureOptions
+	}
+	if err := gsettingsSet(backgroundSchema, "picture-options", options); err != nil {
+		return fmt.Errorf("failed to reset picture-options: %w", err)
+	}
+	return updatePersistedState(func(st *persistedState) { st.PictureOptions = "" })
+}
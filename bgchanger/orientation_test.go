@@ -0,0 +1,85 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrientationOptions(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	landscape := filepath.Join(dir, "landscape.png")
+	portrait := filepath.Join(dir, "portrait.png")
+	square := filepath.Join(dir, "square.png")
+	writeTestPicture(t, landscape, 16, 9, color.RGBA{R: 255, A: 255})
+	writeTestPicture(t, portrait, 9, 16, color.RGBA{G: 255, A: 255})
+	writeTestPicture(t, square, 10, 10, color.RGBA{B: 255, A: 255})
+	for _, tc := range []struct {
+		config  string
+		picture string
+		want    string
+	}{
+		{`"portrait_options": "centered", "landscape_options": "stretched"`, portrait, "centered"},
+		{`"portrait_options": "centered", "landscape_options": "stretched"`, landscape, "stretched"},
+		{`"portrait_options": "centered", "landscape_options": "stretched"`, square, "stretched"},
+		// the orientation without options goes back to picture_options, or
+		// else to zoom
+		{`"portrait_options": "centered", "picture_options": "scaled"`, landscape, "scaled"},
+		{`"portrait_options": "centered"`, landscape, "zoom"},
+		{`"landscape_options": "wallpaper"`, portrait, "zoom"},
+		{`"picture_options": "scaled"`, portrait, "scaled"},
+	} {
+		setter.preset(backgroundSchema, "picture-options", "'none'")
+		cfg := testConfig(t, `{"pictures_dir": %q, `+tc.config+`}`, dir)
+		if err := SetBackground(cfg, tc.picture); err != nil {
+			t.Fatal(err)
+		}
+		if got := setter.get("picture-options"); got != tc.want {
+			t.Errorf("%s: got picture-options %s for %s, want %s", tc.config, got, filepath.Base(tc.picture), tc.want)
+		}
+	}
+}
+
+func TestOrientationOptionsUnset(t *testing.T) {
+	setter := testEnv(t)
+	setter.preset(backgroundSchema, "picture-options", "'scaled'")
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if options := setter.values("picture-options"); len(options) != 0 {
+		t.Errorf("got picture-options %v without any options configured, want them left as they are", options)
+	}
+}
+
+func TestOrientationOptionsRestored(t *testing.T) {
+	setter := testEnv(t)
+	setter.preset(backgroundSchema, "picture-options", "'scaled'")
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q, "landscape_options": "centered"}`, dir)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if options := setter.get("picture-options"); options != "centered" {
+		t.Fatalf("got picture-options %s, want landscape_options", options)
+	}
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q}`, dir))
+	if options := setter.get("picture-options"); options != "scaled" {
+		t.Errorf("got picture-options %s without landscape_options, want the previous scaled", options)
+	}
+}
+
+func TestOrientationOptionsValidate(t *testing.T) {
+	for _, key := range []string{"picture_options", "landscape_options", "portrait_options"} {
+		if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "` + key + `": "fill"}`)); err == nil {
+			t.Errorf("got no error for an invalid %s", key)
+		}
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "portrait_options": "centered", "landscape_options": "zoom"}`)); err != nil {
+		t.Errorf("got %v for valid options", err)
+	}
+}
@@ -0,0 +1,134 @@
+package bgchanger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// xrandrMonitorRegexp matches the geometry of each connected display in the
+// output of xrandr.
+var xrandrMonitorRegexp = regexp.MustCompile(`(?m) connected (?:primary )?(\d+)x(\d+)\+(\d+)\+(\d+)`)
+
+// monitors is the picture shown on each display with per_monitor, in the
+// order of xrandr.
+var monitors = struct {
+	sync.Mutex
+	pictures []string
+}{}
+
+// monitorRects returns the geometry of the connected displays.
+func monitorRects() ([]image.Rectangle, error) {
+	out, err := commandOutput("xrandr", "--current")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run xrandr: %w", err)
+	}
+	return parseMonitors(string(out)), nil
+}
+
+// parseMonitors extracts the geometry of the connected displays from the
+// output of xrandr.
+func parseMonitors(out string) []image.Rectangle {
+	var rects []image.Rectangle
+	for _, m := range xrandrMonitorRegexp.FindAllStringSubmatch(out, -1) {
+		var v [4]int
+		for i := range v {
+			v[i], _ = strconv.Atoi(m[i+1])
+		}
+		rects = append(rects, image.Rect(v[2], v[3], v[2]+v[0], v[3]+v[1]))
+	}
+	return rects
+}
+
+// pickForMonitors returns n pictures, first and then one for each other
+// display. Each pick excludes the pictures already picked for the other
+// displays and the ones they showed until now, and only repeats them when
+// there are not enough candidates.
+func pickForMonitors(cfg *Config, first string, n int) []string {
+	monitors.Lock()
+	previous := append([]string(nil), monitors.pictures...)
+	monitors.Unlock()
+	picked := []string{first}
+	for len(picked) < n {
+		p, err := PickPicture(cfg, append(append([]string(nil), picked...), previous...)...)
+		if err == nil && contains(picked, p) {
+			// not enough candidates to also skip the previous ones
+			p, err = PickPicture(cfg, picked...)
+		}
+		if err != nil {
+			log.Printf("Warning: cannot pick a picture for display %d, repeating one: %v", len(picked)+1, err)
+			p = first
+		}
+		if contains(picked, p) {
+			debugf("not enough candidates for a different picture on display %d", len(picked)+1)
+		}
+		picked = append(picked, p)
+	}
+	monitors.Lock()
+	monitors.pictures = picked
+	monitors.Unlock()
+	return picked
+}
+
+// spanMonitors composes a picture spanning all the displays, with applied,
+// the prepared filename, on the first one and a different picture on each
+// other one, and returns the path of the cached result. With a single
+// display it returns applied.
+func spanMonitors(cfg *Config, filename, applied string) (string, error) {
+	rects, err := monitorRects()
+	if err != nil {
+		return "", err
+	}
+	if len(rects) < 2 {
+		return applied, nil
+	}
+	pictures := pickForMonitors(cfg, filename, len(rects))
+	prepared := []string{applied}
+	for _, p := range pictures[1:] {
+		prepared = append(prepared, preparePicture(cfg, p))
+	}
+	key := fmt.Sprintf("%v|%s", rects, strings.Join(prepared, "|"))
+	sum := sha1.Sum([]byte(key))
+	cached, ok, err := cachedVariant("spanned", applied, "span"+hex.EncodeToString(sum[:4]))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		img, err := composeMonitors(rects, prepared)
+		if err != nil {
+			return "", err
+		}
+		if err := writeJPEG(cached, img); err != nil {
+			return "", fmt.Errorf("failed to write spanned picture: %w", err)
+		}
+	}
+	log.Printf("Spanning %d displays with %s", len(rects), strings.Join(pictures, ", "))
+	return cached, nil
+}
+
+// composeMonitors draws each picture cropped to fill its display, in an
+// image covering all the displays.
+func composeMonitors(rects []image.Rectangle, pictures []string) (*image.RGBA, error) {
+	bounds := rects[0]
+	for _, r := range rects[1:] {
+		bounds = bounds.Union(r)
+	}
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for i, r := range rects {
+		img, err := loadImage(pictures[i])
+		if err != nil {
+			return nil, err
+		}
+		filled := cropToFill(img, r.Dx(), r.Dy())
+		dst := r.Sub(bounds.Min)
+		draw.Draw(out, dst, filled, filled.Bounds().Min, draw.Src)
+	}
+	return out, nil
+}
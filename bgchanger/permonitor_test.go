@@ -0,0 +1,149 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testXrandr = `Screen 0: minimum 8 x 8, current 96 x 18, maximum 32767 x 32767
+DP-1 connected primary 32x18+0+0 (normal left inverted right x axis y axis) 600mm x 340mm
+DP-2 connected 32x18+32+0 (normal left inverted right x axis y axis) 600mm x 340mm
+HDMI-1 disconnected (normal left inverted right x axis y axis)
+DP-3 connected 32x18+64+0 (normal left inverted right x axis y axis) 600mm x 340mm
+`
+
+func TestParseMonitors(t *testing.T) {
+	got := parseMonitors(testXrandr)
+	want := []image.Rectangle{image.Rect(0, 0, 32, 18), image.Rect(32, 0, 64, 18), image.Rect(64, 0, 96, 18)}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// writeColoredPictures writes n pictures of distinct colors in dir, and
+// returns them by color.
+func writeColoredPictures(t *testing.T, dir string, n int) map[color.RGBA]string {
+	t.Helper()
+	byColor := make(map[color.RGBA]string)
+	for i := 0; i < n; i++ {
+		c := color.RGBA{R: uint8(40 * i), G: 255 - uint8(40*i), B: 128, A: 255}
+		byColor[c] = writeTestPicture(t, filepath.Join(dir, fmt.Sprintf("%d.png", i)), 32, 18, c)
+	}
+	return byColor
+}
+
+// spannedPictures returns the picture shown on each display of testXrandr in
+// the applied spanned picture, by their colors.
+func spannedPictures(t *testing.T, setter *testSetter, byColor map[color.RGBA]string) []string {
+	t.Helper()
+	img, err := loadImage(strings.TrimPrefix(setter.get("picture-uri"), "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shown []string
+	for _, r := range parseMonitors(testXrandr) {
+		got := img.At(r.Min.X+r.Dx()/2, r.Min.Y+r.Dy()/2)
+		var closest string
+		best := -1
+		for c, p := range byColor {
+			cr, cg, cb, _ := got.RGBA()
+			d := abs(int(cr>>8)-int(c.R)) + abs(int(cg>>8)-int(c.G)) + abs(int(cb>>8)-int(c.B))
+			if best < 0 || d < best {
+				closest, best = p, d
+			}
+		}
+		shown = append(shown, closest)
+	}
+	return shown
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestPerMonitorDistinct(t *testing.T) {
+	setter := testEnv(t)
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		if name == "xrandr" {
+			return []byte(testXrandr), nil
+		}
+		return nil, errNoDesktop
+	}
+	dir := t.TempDir()
+	byColor := writeColoredPictures(t, dir, 6)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_monitor": true}`, dir)
+	var before []string
+	for i := 0; i < 5; i++ {
+		ChangeBG(cfg)
+		if got := setter.get("picture-options"); got != "spanned" {
+			t.Fatalf("got picture-options %q, want spanned", got)
+		}
+		shown := spannedPictures(t, setter, byColor)
+		seen := make(map[string]bool)
+		for _, p := range shown {
+			if seen[p] {
+				t.Fatalf("change %d: got %v, a picture is on two displays", i, shown)
+			}
+			seen[p] = true
+		}
+		if shown[0] != CurrentPicture() {
+			t.Errorf("change %d: got %s on the first display, want the current %s", i, shown[0], CurrentPicture())
+		}
+		// with 6 candidates, the other displays don't show the pictures
+		// of the previous change again
+		for _, p := range shown[1:] {
+			if contains(before, p) {
+				t.Errorf("change %d: %s is shown again right after, on %v then %v", i, p, before, shown)
+			}
+		}
+		before = shown
+	}
+}
+
+func TestPerMonitorScarce(t *testing.T) {
+	setter := testEnv(t)
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		if name == "xrandr" {
+			return []byte(testXrandr), nil
+		}
+		return nil, errNoDesktop
+	}
+	dir := t.TempDir()
+	byColor := writeColoredPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_monitor": true}`, dir)
+	ChangeBG(cfg)
+	shown := spannedPictures(t, setter, byColor)
+	if shown[0] == shown[1] {
+		t.Errorf("got %v, want different pictures on the first two displays", shown)
+	}
+	if shown[2] != shown[0] && shown[2] != shown[1] {
+		t.Errorf("got %v, want a repeat on the third display", shown)
+	}
+}
+
+func TestPerMonitorSingleDisplay(t *testing.T) {
+	setter := testEnv(t)
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		if name == "xrandr" {
+			return []byte("DP-1 connected primary 32x18+0+0 (normal)\n"), nil
+		}
+		return nil, errNoDesktop
+	}
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q, "per_monitor": true}`, dir)
+	ChangeBG(cfg)
+	if got := setter.get("picture-uri"); got != "file://"+pictures[0] {
+		t.Errorf("got picture-uri %s, want the picture itself", got)
+	}
+	if got := setter.get("picture-options"); got != "zoom" {
+		t.Errorf("got picture-options %q, want zoom", got)
+	}
+}
@@ -0,0 +1,490 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultExtensions are the file extensions of the candidates, unless
+// supported_extensions is set.
+var defaultExtensions = []string{"png", "jpg", "mp4", "webm"}
+
+// knownExtensions are the extensions that supported_extensions can list.
+var knownExtensions = map[string]bool{
+	"png": true, "jpg": true, "jpeg": true, "mp4": true, "webm": true, "svg": true, "ico": true,
+}
+
+var supportedExtensions = struct {
+	sync.Mutex
+	exts []string
+}{exts: defaultExtensions}
+
+// setSupportedExtensions sets the extensions of the candidates, or the
+// default ones if exts is empty.
+func setSupportedExtensions(exts []string) {
+	if len(exts) == 0 {
+		exts = defaultExtensions
+	}
+	supportedExtensions.Lock()
+	changed := strings.Join(exts, ",") != strings.Join(supportedExtensions.exts, ",")
+	supportedExtensions.exts = exts
+	supportedExtensions.Unlock()
+	if changed {
+		candidateCache.reset()
+	}
+	// SVG pictures need rsvg-convert, so tell right away if it is missing
+	if contains(exts, "svg") {
+		svgAvailable()
+	}
+}
+
+func validateExtensions(exts []string) error {
+	for i, ext := range exts {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if !knownExtensions[ext] {
+			return fmt.Errorf("unsupported extension '%s' in supported_extensions", exts[i])
+		}
+		exts[i] = ext
+	}
+	return nil
+}
+
+// selection modes
+const (
+	modeRandom     = "random"
+	modeBrightness = "brightness"
+	modeNewest     = "newest"
+)
+
+// defaultLargeDirectory is the default large_directory: directories with
+// more pictures than that are sampled rather than kept whole in memory.
+const defaultLargeDirectory = 50000
+
+// largeDirectory is the configured large_directory, accessed atomically as
+// reloading the config changes it while other goroutines scan.
+var largeDirectory = int64(defaultLargeDirectory)
+
+// setLargeDirectory sets large_directory, using the default if n is not
+// positive.
+func setLargeDirectory(n int) {
+	if n <= 0 {
+		n = defaultLargeDirectory
+	}
+	if atomic.SwapInt64(&largeDirectory, int64(n)) != int64(n) {
+		candidateCache.reset()
+	}
+}
+
+func getLargeDirectory() int {
+	return int(atomic.LoadInt64(&largeDirectory))
+}
+
+// failed scans are retried, see scanSourceWithRetries
+const (
+	defaultScanRetries    = 2
+	defaultScanRetryDelay = time.Second
+)
+
+// Debug enables debug logging.
+var Debug bool
+
+// sourceCandidates lists the pictures offered by a source. It is a variable
+// so that tests can make the scans fail.
+var sourceCandidates = (*SourceConfig).candidates
+
+// ListCandidates returns the sorted absolute paths of the pictures and videos
+// in dirname that the picker can choose from. If limit is positive and there
+// are more pictures than that, a uniformly random subset of limit pictures is
+// returned. The directory is read in batches and sampled as it is read, so
+// that only limit pictures are kept in memory however large it is.
+func ListCandidates(dirname string, limit int) ([]string, error) {
+	pictures, _, err := listCandidates(dirname, limit)
+	return pictures, err
+}
+
+// listCandidates is ListCandidates, also returning the number of pictures in
+// dirname, which is more than len(pictures) if they were sampled.
+func listCandidates(dirname string, limit int) ([]string, int, error) {
+	absdir, err := filepath.Abs(dirname)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get absolute path of '%s': %w", dirname, err)
+	}
+	d, err := os.Open(absdir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read directory '%s': %w", dirname, err)
+	}
+	defer d.Close()
+	var (
+		pictures []string
+		seen     int
+	)
+	for {
+		// read the directory in batches, so that huge directories don't have
+		// to be loaded in memory all at once
+		entries, err := d.ReadDir(1024)
+		for _, e := range entries {
+			p := path.Join(absdir, e.Name())
+			if e.IsDir() {
+				excluded(p, "directory")
+				continue
+			}
+			if !hasSupportedExtension(e.Name()) {
+				excluded(p, "unsupported extension")
+				continue
+			}
+			// only symlinks need an extra stat, the type of regular entries
+			// is already known from ReadDir
+			if e.Type()&os.ModeSymlink != 0 && !isReadableFile(p) {
+				debugf("Skipping '%s', it does not point to a readable file", p)
+				excluded(p, "broken symlink")
+				continue
+			}
+			seen++
+			if limit <= 0 || len(pictures) < limit {
+				pictures = append(pictures, p)
+			} else if j := rand.Intn(seen); j < limit {
+				// reservoir sampling
+				excluded(pictures[j], "not sampled")
+				pictures[j] = p
+			} else {
+				excluded(p, "not sampled")
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read directory '%s': %w", dirname, err)
+		}
+	}
+	if limit > 0 && seen > limit {
+		debugf("Sampled %d of %d pictures", limit, seen)
+	}
+	sort.Strings(pictures)
+	return pictures, seen, nil
+}
+
+// isReadableFile reports whether filename, following symlinks, is a regular
+// file that can be opened.
+func isReadableFile(filename string) bool {
+	st, err := os.Stat(filename)
+	if err != nil || !st.Mode().IsRegular() {
+		return false
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	fd.Close()
+	return true
+}
+
+func hasSupportedExtension(filename string) bool {
+	supportedExtensions.Lock()
+	defer supportedExtensions.Unlock()
+	for _, ext := range supportedExtensions.exts {
+		if strings.HasSuffix(strings.ToLower(filename), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanCandidates returns the pictures of all the sources that the picker can
+// choose from, after applying the configured filters.
+func ScanCandidates(cfg *Config) ([]string, error) {
+	var pictures []string
+	for _, src := range configuredSources(cfg) {
+		p, err := scanSource(cfg, &src)
+		if err != nil {
+			return nil, err
+		}
+		pictures = append(pictures, p...)
+	}
+	sort.Strings(pictures)
+	return pictures, nil
+}
+
+// indexedCandidates is like ScanCandidates, but lists every picture, ignoring
+// max_candidates and large_directory, so that the index of a picture, as
+// listed by -list and used by -apply, doesn't depend on a random sample.
+func indexedCandidates(cfg *Config) ([]string, error) {
+	full := *cfg
+	full.MaxCandidates = allCandidates
+	return ScanCandidates(&full)
+}
+
+// scanSource returns the pictures of the source that the picker can choose
+// from, after applying the configured filters.
+func scanSource(cfg *Config, src *SourceConfig) ([]string, error) {
+	pictures, err := sourceCandidates(src, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Mode == modeSeasonal {
+		pictures = append(pictures, seasonalCandidates(cfg, src, time.Now())...)
+	}
+	before := pictures
+	pictures = preferVariants(pictures)
+	explainStep("another resolution of the picture is preferred", before, pictures)
+	before = pictures
+	pictures = filterByAge(cfg, pictures, time.Now())
+	explainStep("outside max_age/min_age", before, pictures)
+	if cfg.Dedup {
+		before = pictures
+		pictures = dedupPictures(pictures)
+		explainStep("duplicate of another picture", before, pictures)
+	}
+	if cfg.VideoBackend == "" {
+		before = pictures
+		pictures = filterVideos(pictures)
+		explainStep("video without video_backend", before, pictures)
+	}
+	before = pictures
+	pictures = filterBlocked(cfg, pictures)
+	explainStep("blocked", before, pictures)
+	return pictures, nil
+}
+
+// scanSourceWithRetries is like scanSource, but retries failed scans, to get
+// past transient errors like a network mount coming back. A source that is
+// scanned fine but is empty is not retried.
+func scanSourceWithRetries(cfg *Config, src *SourceConfig) ([]string, error) {
+	pictures, err := scanSource(cfg, src)
+	retries := defaultScanRetries
+	if cfg.ScanRetries != nil {
+		retries = *cfg.ScanRetries
+	}
+	for i := 0; err != nil && i < retries; i++ {
+		log.Printf("Warning: cannot scan %s, retrying in %s: %v", src.String(), cfg.ScanRetryDelay, err)
+		time.Sleep(time.Duration(cfg.ScanRetryDelay))
+		pictures, err = scanSource(cfg, src)
+	}
+	return pictures, err
+}
+
+// debugf logs a message if debug logging is enabled.
+func debugf(format string, v ...interface{}) {
+	if Debug {
+		log.Printf("Debug: "+format, v...)
+	}
+}
+
+// sortByNewest sorts the pictures from the most recently modified to the
+// least recently modified, breaking ties by name.
+func sortByNewest(pictures []string) {
+	modTimes := make(map[string]time.Time, len(pictures))
+	for _, p := range pictures {
+		if mtime, err := pictureModTime(p); err == nil {
+			modTimes[p] = mtime
+		}
+	}
+	sort.SliceStable(pictures, func(i, j int) bool {
+		ti, tj := modTimes[pictures[i]], modTimes[pictures[j]]
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return pictures[i] < pictures[j]
+	})
+}
+
+// excludePictures removes the excluded pictures from the candidates, unless
+// that would leave none.
+func excludePictures(pictures, exclude []string) []string {
+	if len(exclude) == 0 {
+		return pictures
+	}
+	var kept []string
+	for _, p := range pictures {
+		excluded := false
+		for _, e := range exclude {
+			if p == e {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return pictures
+	}
+	return kept
+}
+
+// PickPicture picks a random source, and a random picture from it other than
+// the excluded ones. If a source has no pictures the next one is tried.
+func PickPicture(cfg *Config, exclude ...string) (string, error) {
+	p, _, err := pickPicture(cfg, exclude)
+	return p, err
+}
+
+// pickPicture is like PickPicture, but also returns the source of the
+// picture.
+func pickPicture(cfg *Config, exclude []string) (string, *SourceConfig, error) {
+	var lastErr error
+	for _, src := range orderSources(configuredSources(cfg)) {
+		pictures, err := scanSourceWithRetries(cfg, &src)
+		if err == nil && len(pictures) == 0 {
+			err = fmt.Errorf("no pictures found")
+		}
+		if err == nil {
+			var p string
+			if p, err = pickFrom(cfg, pictures, exclude); err == nil {
+				logSelection(cfg, p, len(pictures), &src)
+				logWith(logFields{"path": p, "candidates": len(pictures), "source": src.String()},
+					"Picked '%s' out of %d candidates from %s", p, len(pictures), src.String())
+				return p, &src, nil
+			}
+		}
+		log.Printf("Warning: cannot pick a picture from %s: %v", src.String(), err)
+		lastErr = err
+	}
+	return "", nil, lastErr
+}
+
+// pickFrom picks a random picture among the given ones other than the
+// excluded ones, preferring the ones that match the configured mode and
+// letting the pre-change hook, if any, reject candidates until one is
+// approved or the retries are exhausted.
+func pickFrom(cfg *Config, pictures, exclude []string) (string, error) {
+	pictures = filterCandidates(cfg, pictures, exclude, time.Now())
+	switch cfg.Mode {
+	case modeNewest:
+		sortByNewest(pictures)
+	case modeFair:
+		sortByDisplayTime(pictures)
+	case modeDaily:
+		sortByDailyScore(pictures, cfg.DailySalt, time.Now())
+	case modeDiscovery:
+		weightedShuffle(pictures, pictureWeight(cfg))
+		sortUnseenFirst(pictures)
+	case modeAccent:
+		weightedShuffle(pictures, pictureWeight(cfg))
+		if target, err := accentColor(cfg); err != nil {
+			log.Printf("Warning: %v, picking a random picture", err)
+		} else {
+			sortByColor(pictures, target)
+		}
+	default:
+		weightedShuffle(pictures, pictureWeight(cfg))
+	}
+	// the listing may be cached from before a symlink target went away, so
+	// check the pictures that are about to be used
+	for len(pictures) > 0 && !fetchedReadable(pictures[0]) {
+		debugf("Skipping '%s', it is not a readable file anymore", pictures[0])
+		pictures = pictures[1:]
+	}
+	if len(pictures) == 0 {
+		return "", fmt.Errorf("no readable pictures found")
+	}
+	if cfg.PreChangeHook == "" {
+		return pictures[0], nil
+	}
+	attempts := defaultHookRetries + 1
+	if cfg.HookRetries != nil {
+		attempts = *cfg.HookRetries + 1
+	}
+	if attempts > len(pictures) {
+		attempts = len(pictures)
+	}
+	// the hook runs on the main loop, so all the attempts together get at
+	// most preChangeHookBudget
+	deadline := time.Now().Add(preChangeHookBudget)
+	for i, p := range pictures[:attempts] {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", fmt.Errorf("pre-change hook rejected %d candidates in %s", i, preChangeHookBudget)
+		}
+		if !fetchedReadable(p) {
+			continue
+		}
+		timeout := time.Duration(cfg.HookTimeout)
+		if timeout > remaining {
+			timeout = remaining
+		}
+		if err := runHook(cfg.PreChangeHook, p, timeout); err != nil {
+			log.Printf("Warning: pre-change hook rejected '%s': %v", p, err)
+			continue
+		}
+		return p, nil
+	}
+	return "", fmt.Errorf("pre-change hook rejected %d candidates", attempts)
+}
+
+// fetchedReadable downloads p if it is a bucket object, and reports whether
+// it is then a readable file.
+func fetchedReadable(p string) bool {
+	if err := fetchRemote(p); err != nil {
+		log.Printf("Warning: %v", err)
+		return false
+	}
+	return isReadableFile(p)
+}
+
+// filterCandidates applies the filters that depend on the moment of the
+// pick, rather than on the scanned source, to pictures.
+func filterCandidates(cfg *Config, pictures, exclude []string, now time.Time) []string {
+	before := pictures
+	pictures = excludePictures(pictures, exclude)
+	explainStep("currently shown", before, pictures)
+	before = pictures
+	pictures = filterCooldown(cfg, pictures, now)
+	explainStep("shown within per_image_cooldown", before, pictures)
+	if cfg.AspectRatio != "" {
+		before = pictures
+		pictures = filterByAspectRatio(cfg, pictures)
+		explainStep("aspect ratio does not match", before, pictures)
+	}
+	switch cfg.Mode {
+	case modeOnThisDay:
+		before = pictures
+		pictures = filterOnThisDay(pictures, now)
+		explainStep("not taken on this day", before, pictures)
+	case modeBrightness:
+		before = pictures
+		pictures = filterByBrightness(&cfg.Brightness, pictures, now)
+		explainStep("brightness does not match the time of day", before, pictures)
+	case modeSeasonal:
+		before = pictures
+		pictures = filterBySeason(cfg, pictures, now)
+		explainStep("not for the current season", before, pictures)
+	}
+	return pictures
+}
+
+// PrintCandidates prints the pictures the picker can choose from, either one
+// per line or as a JSON array.
+func PrintCandidates(cfg *Config, asJSON bool) error {
+	pictures, err := indexedCandidates(cfg)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		if pictures == nil {
+			pictures = []string{}
+		}
+		data, err := json.MarshalIndent(pictures, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal candidates: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	for _, p := range pictures {
+		fmt.Println(p)
+	}
+	return nil
+}
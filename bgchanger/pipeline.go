@@ -0,0 +1,46 @@
+package bgchanger
+
+import (
+	"log"
+	"time"
+)
+
+// preparePicture runs the configured processing steps on the picture, and
+// returns the path of the file to actually apply. Steps that fail are skipped.
+func preparePicture(cfg *Config, filename string) string {
+	original := filename
+	if p, err := rasterize(filename); err != nil {
+		log.Printf("Warning: cannot rasterize '%s': %v", filename, err)
+	} else {
+		filename = p
+	}
+	if cfg.MaxDimension > 0 {
+		if p, err := downscale(filename, cfg.MaxDimension); err != nil {
+			log.Printf("Warning: cannot downscale '%s': %v", filename, err)
+		} else {
+			filename = p
+		}
+	}
+	if cfg.Prescale {
+		if p, err := prescale(cfg, filename); err != nil {
+			log.Printf("Warning: cannot prescale '%s': %v", filename, err)
+		} else {
+			filename = p
+		}
+	}
+	if cfg.Tint.Enabled {
+		if p, err := tint(cfg, filename, time.Now()); err != nil {
+			log.Printf("Warning: cannot tint '%s': %v", filename, err)
+		} else {
+			filename = p
+		}
+	}
+	if cfg.Caption.Enabled {
+		if p, err := caption(cfg, original, filename); err != nil {
+			log.Printf("Warning: cannot caption '%s': %v", filename, err)
+		} else {
+			filename = p
+		}
+	}
+	return filename
+}
@@ -0,0 +1,140 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Playlist is an ordered list of pictures to show, read from the file named
+// by the playlist setting.
+type Playlist struct {
+	Entries []PlaylistEntry `json:"entries"`
+	// Loop starts the playlist over after the last entry. Otherwise the
+	// playlist stops there, and the pictures are picked as usual.
+	Loop bool `json:"loop"`
+	// RandomBetween is how many usual picks to make between two entries.
+	RandomBetween int `json:"random_between"`
+}
+
+// PlaylistEntry is a picture of a playlist. Relative paths are relative to
+// the playlist file.
+type PlaylistEntry struct {
+	Picture string `json:"picture"`
+	// Repeat is for how many changes the picture stays, 1 if unset.
+	Repeat int `json:"repeat"`
+}
+
+// playlistPosition is how far a playlist has been played.
+type playlistPosition struct {
+	File string `json:"file"`
+	// Modified is the modification time of the playlist file, so that
+	// editing the playlist starts it over.
+	Modified time.Time `json:"modified"`
+	Entry    int       `json:"entry"`
+	// Shown is how many times the current entry has been applied, and
+	// Random how many usual picks have been made after it.
+	Shown  int  `json:"shown"`
+	Random int  `json:"random"`
+	Done   bool `json:"done,omitempty"`
+}
+
+// readPlaylist reads and validates a playlist file, and returns it together
+// with its modification time.
+func readPlaylist(filename string) (*Playlist, time.Time, error) {
+	st, err := os.Stat(filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read playlist: %w", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read playlist: %w", err)
+	}
+	var pl Playlist
+	if err := json.Unmarshal(data, &pl); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse playlist '%s': %w", filename, err)
+	}
+	if len(pl.Entries) == 0 {
+		return nil, time.Time{}, fmt.Errorf("playlist '%s' has no entries", filename)
+	}
+	if pl.RandomBetween < 0 {
+		return nil, time.Time{}, fmt.Errorf("random_between cannot be negative")
+	}
+	for i := range pl.Entries {
+		e := &pl.Entries[i]
+		if e.Picture == "" {
+			return nil, time.Time{}, fmt.Errorf("entry %d of playlist '%s' has no picture", i, filename)
+		}
+		if e.Repeat < 0 {
+			return nil, time.Time{}, fmt.Errorf("entry %d of playlist '%s' has a negative repeat", i, filename)
+		}
+		if e.Repeat == 0 {
+			e.Repeat = 1
+		}
+		if !filepath.IsAbs(e.Picture) {
+			e.Picture = filepath.Join(filepath.Dir(filename), e.Picture)
+		}
+	}
+	return &pl, st.ModTime(), nil
+}
+
+// next advances pos and returns the picture to show. It returns an empty
+// picture when it's the turn of a usual pick, and false once a playlist that
+// doesn't loop is over.
+func (pl *Playlist) next(pos *playlistPosition) (string, bool) {
+	for !pos.Done {
+		if pos.Entry >= len(pl.Entries) {
+			if !pl.Loop {
+				pos.Done = true
+				break
+			}
+			pos.Entry = 0
+		}
+		e := pl.Entries[pos.Entry]
+		if pos.Shown < e.Repeat {
+			pos.Shown++
+			return e.Picture, true
+		}
+		lastEntry := pos.Entry == len(pl.Entries)-1
+		if pos.Random < pl.RandomBetween && (pl.Loop || !lastEntry) {
+			pos.Random++
+			return "", true
+		}
+		pos.Entry++
+		pos.Shown, pos.Random = 0, 0
+	}
+	return "", false
+}
+
+// nextPlaylistPicture advances the configured playlist, saving its position
+// across restarts, and returns the picture to show. It returns an empty
+// picture for a usual pick, and false when the playlist is over or cannot be
+// read.
+func nextPlaylistPicture(cfg *Config) (string, bool) {
+	pl, modified, err := readPlaylist(cfg.Playlist)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return "", false
+	}
+	var (
+		picture string
+		ok      bool
+	)
+	err = updatePersistedState(func(st *persistedState) {
+		if st.Playlist == nil || st.Playlist.File != cfg.Playlist || !st.Playlist.Modified.Equal(modified) {
+			st.Playlist = &playlistPosition{File: cfg.Playlist, Modified: modified}
+		}
+		wasDone := st.Playlist.Done
+		picture, ok = pl.next(st.Playlist)
+		if !ok && !wasDone {
+			log.Printf("Playlist '%s' is over", cfg.Playlist)
+		}
+	})
+	if err != nil {
+		log.Printf("Warning: cannot save the playlist position: %v", err)
+	}
+	return picture, ok
+}
@@ -0,0 +1,103 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// playPlaylist returns the pictures of n changes with the given playlist,
+// with "-" for a usual pick and "." once the playlist is over.
+func playPlaylist(t *testing.T, pl string, n int) []string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "playlist.json")
+	if err := os.WriteFile(filename, []byte(pl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": "/p", "playlist": %q}`, filename)
+	var got []string
+	for i := 0; i < n; i++ {
+		p, ok := nextPlaylistPicture(cfg)
+		switch {
+		case !ok:
+			got = append(got, ".")
+		case p == "":
+			got = append(got, "-")
+		default:
+			got = append(got, strings.TrimPrefix(p, "/p/"))
+		}
+	}
+	return got
+}
+
+func TestPlaylist(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		playlist string
+		want     []string
+	}{
+		{
+			"repeat",
+			`{"entries": [{"picture": "/p/a.jpg", "repeat": 2}, {"picture": "/p/b.jpg"}]}`,
+			[]string{"a.jpg", "a.jpg", "b.jpg", ".", "."},
+		},
+		{
+			"loop",
+			`{"entries": [{"picture": "/p/a.jpg"}, {"picture": "/p/b.jpg", "repeat": 2}], "loop": true}`,
+			[]string{"a.jpg", "b.jpg", "b.jpg", "a.jpg", "b.jpg"},
+		},
+		{
+			"random between",
+			`{"entries": [{"picture": "/p/a.jpg"}, {"picture": "/p/b.jpg"}], "random_between": 2}`,
+			[]string{"a.jpg", "-", "-", "b.jpg", "."},
+		},
+		{
+			"random between with loop",
+			`{"entries": [{"picture": "/p/a.jpg"}, {"picture": "/p/b.jpg"}], "random_between": 1, "loop": true}`,
+			[]string{"a.jpg", "-", "b.jpg", "-", "a.jpg"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testEnv(t)
+			if got := playPlaylist(t, tc.playlist, len(tc.want)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlaylistChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	filename := filepath.Join(dir, "playlist.json")
+	if err := os.WriteFile(filename, []byte(`{"entries": [{"picture": "2.png"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "playlist": %q}`, dir, filename)
+	ChangeBG(cfg)
+	if uri := setter.get("picture-uri"); uri != "file://"+pictures[2] {
+		t.Errorf("got picture-uri %s, want the playlist entry relative to the playlist", uri)
+	}
+	// once the playlist stops, the pictures are picked as usual
+	ChangeBG(cfg)
+	if uris := setter.values("picture-uri"); len(uris) != 2 {
+		t.Errorf("got picture-uri %v, want a usual pick after the playlist", uris)
+	}
+}
+
+func TestPlaylistPositionPersists(t *testing.T) {
+	testEnv(t)
+	filename := filepath.Join(t.TempDir(), "playlist.json")
+	if err := os.WriteFile(filename, []byte(`{"entries": [{"picture": "/p/a.jpg"}, {"picture": "/p/b.jpg"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": "/p", "playlist": %q}`, filename)
+	nextPlaylistPicture(cfg)
+	resetState()
+	if p, _ := nextPlaylistPicture(cfg); p != "/p/b.jpg" {
+		t.Errorf("got %s after a restart, want the next entry", p)
+	}
+}
@@ -0,0 +1,64 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreChangeHookRejectsFirstCandidate(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	rejected := filepath.Join(t.TempDir(), "rejected")
+	// rejects the first candidate, remembering it, and approves the next one
+	hook := `if [ -e ` + rejected + ` ]; then exit 0; fi; printf '%s' "$1" >` + rejected + `; exit 1 #`
+	cfg := testConfig(t, `{"pictures_dir": %q, "pre_change_hook": %q}`, dir, hook)
+	picked, err := PickPicture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(rejected)
+	if err != nil {
+		t.Fatalf("the hook was not run: %v", err)
+	}
+	if string(data) == picked {
+		t.Errorf("picked '%s', which the hook rejected", picked)
+	}
+	if !contains(pictures, string(data)) || !contains(pictures, picked) {
+		t.Errorf("the hook rejected '%s' and approved '%s', want candidates", data, picked)
+	}
+}
+
+func TestPreChangeHookZeroRetries(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	cfg := testConfig(t, `{"pictures_dir": %q, "pre_change_hook": "exit 1 #", "pre_change_hook_retries": 0}`, dir)
+	if _, err := PickPicture(cfg); err == nil || !strings.Contains(err.Error(), "rejected 1 candidates") {
+		t.Errorf("got error %v, want a single rejected candidate", err)
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "pre_change_hook_retries": -1}`)); err == nil {
+		t.Error("negative pre_change_hook_retries accepted")
+	}
+}
+
+func TestPreChangeHookBudget(t *testing.T) {
+	testEnv(t)
+	old := preChangeHookBudget
+	preChangeHookBudget = 300 * time.Millisecond
+	t.Cleanup(func() { preChangeHookBudget = old })
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 10)
+	// each attempt would take the whole hook_timeout
+	cfg := testConfig(t, `{"pictures_dir": %q, "pre_change_hook": "sleep 10 #", "hook_timeout": "1s", "pre_change_hook_retries": 8}`, dir)
+	start := time.Now()
+	if _, err := PickPicture(cfg); err == nil {
+		t.Error("got a picture approved by a hanging hook")
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("the pre-change hook held the change for %s, want at most about %s", d, preChangeHookBudget)
+	}
+}
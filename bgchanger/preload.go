@@ -0,0 +1,88 @@
+package bgchanger
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// preloaded is the picture picked ahead of the next change, already
+// validated and processed, so that applying it is instant.
+var preloaded = struct {
+	sync.Mutex
+	// generation is bumped to invalidate preloads, including running ones
+	generation int
+	filename   string
+	src        *SourceConfig
+}{}
+
+// invalidatePreload discards the preloaded picture, if any.
+func invalidatePreload() {
+	preloaded.Lock()
+	preloaded.generation++
+	preloaded.filename, preloaded.src = "", nil
+	preloaded.Unlock()
+}
+
+// preloadNext picks the picture for the next change in the background, and
+// runs the processing pipeline on it so that its results are cached. The
+// goroutine works on a copy of cfg, as ApplyConfig replaces it meanwhile, and
+// a reload bumps the generation so that the pick is thrown away.
+func preloadNext(cfg *Config) {
+	preloaded.Lock()
+	preloaded.generation++
+	generation := preloaded.generation
+	preloaded.filename, preloaded.src = "", nil
+	preloaded.Unlock()
+	snapshot := *cfg
+	exclude := changeExclusions(cfg)
+	go func() {
+		cfg := &snapshot
+		filename, src, err := pickPicture(cfg, exclude)
+		if err != nil {
+			log.Printf("Warning: cannot preload the next picture: %v", err)
+			return
+		}
+		if !isVideo(filename) {
+			if _, _, err := imageSize(filename); err != nil {
+				log.Printf("Warning: not preloading invalid picture: %v", err)
+				return
+			}
+			preparePicture(cfg, filename)
+		}
+		preloaded.Lock()
+		defer preloaded.Unlock()
+		if preloaded.generation != generation {
+			return
+		}
+		preloaded.filename, preloaded.src = filename, src
+		debugf("preloaded '%s'", filename)
+	}()
+}
+
+// takePreloaded returns the preloaded picture and its source, if there is
+// one and it is still a candidate.
+func takePreloaded(cfg *Config) (string, *SourceConfig, bool) {
+	preloaded.Lock()
+	filename, src := preloaded.filename, preloaded.src
+	preloaded.generation++
+	preloaded.filename, preloaded.src = "", nil
+	preloaded.Unlock()
+	if filename == "" {
+		return "", nil, false
+	}
+	if _, err := os.Stat(filename); err != nil {
+		return "", nil, false
+	}
+	pictures, err := scanSource(cfg, src)
+	if err != nil {
+		return "", nil, false
+	}
+	for _, p := range pictures {
+		if p == filename {
+			return filename, src, true
+		}
+	}
+	debugf("preloaded '%s' is not a candidate anymore", filename)
+	return "", nil, false
+}
@@ -0,0 +1,89 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitPreloaded waits for the background preload to finish, and returns the
+// preloaded picture.
+func waitPreloaded(t *testing.T) string {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		preloaded.Lock()
+		filename := preloaded.filename
+		preloaded.Unlock()
+		if filename != "" {
+			return filename
+		}
+	}
+	t.Fatal("nothing was preloaded")
+	return ""
+}
+
+func TestPreloadedPictureIsApplied(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q, "preload": true}`, dir)
+	ChangeBG(cfg)
+	for i := 0; i < 5; i++ {
+		next := waitPreloaded(t)
+		if next == CurrentPicture() {
+			t.Fatalf("preloaded the current picture %s", next)
+		}
+		ChangeBG(cfg)
+		if cur := CurrentPicture(); cur != next {
+			t.Fatalf("applied %s, want the preloaded %s", cur, next)
+		}
+	}
+}
+
+func TestPreloadInvalidated(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q, "preload": true}`, dir)
+	ChangeBG(cfg)
+	// the preloaded picture is not a candidate anymore
+	next := waitPreloaded(t)
+	if err := os.Remove(next); err != nil {
+		t.Fatal(err)
+	}
+	candidateCache.reset()
+	ChangeBG(cfg)
+	if cur := CurrentPicture(); cur == next {
+		t.Errorf("applied the removed %s", next)
+	}
+	// nor is a preload discarded by invalidatePreload
+	waitPreloaded(t)
+	invalidatePreload()
+	if _, _, ok := takePreloaded(cfg); ok {
+		t.Error("got a preloaded picture after invalidating it")
+	}
+}
+
+func TestPreloadDuringReload(t *testing.T) {
+	testEnv(t)
+	dir, other := t.TempDir(), t.TempDir()
+	writeTestPictures(t, dir, 5)
+	writeTestPictures(t, other, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q, "preload": true}`, dir)
+	ChangeBG(cfg)
+	// the preload works on its own copy of the config, and its pick from the
+	// old pictures_dir is thrown away
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q, "preload": true}`, other))
+	time.Sleep(100 * time.Millisecond)
+	preloaded.Lock()
+	filename := preloaded.filename
+	preloaded.Unlock()
+	if filename != "" {
+		t.Errorf("got preloaded %s after the reload, want the pick thrown away", filename)
+	}
+	ChangeBG(cfg)
+	if next := waitPreloaded(t); filepath.Dir(next) != other {
+		t.Errorf("preloaded %s, want a picture of the new pictures_dir", next)
+	}
+}
@@ -0,0 +1,76 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image/color"
+	"regexp"
+	"strconv"
+)
+
+const (
+	prescaleCrop = "crop"
+	prescaleFit  = "fit"
+)
+
+var (
+	xrandrPrimaryRegexp   = regexp.MustCompile(`(?m) connected primary (\d+)x(\d+)\+`)
+	xrandrConnectedRegexp = regexp.MustCompile(`(?m) connected (?:primary )?(\d+)x(\d+)\+`)
+)
+
+// displayResolution returns the resolution of the primary display.
+func displayResolution() (int, int, error) {
+	out, err := commandOutput("xrandr", "--current")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to run xrandr: %w", err)
+	}
+	return parseXrandr(string(out))
+}
+
+// parseXrandr extracts the resolution of the primary display, or of the first
+// connected one if none is primary, from the output of xrandr.
+func parseXrandr(out string) (int, int, error) {
+	m := xrandrPrimaryRegexp.FindStringSubmatch(out)
+	if m == nil {
+		m = xrandrConnectedRegexp.FindStringSubmatch(out)
+	}
+	if m == nil {
+		return 0, 0, fmt.Errorf("no connected display found")
+	}
+	w, _ := strconv.Atoi(m[1])
+	h, _ := strconv.Atoi(m[2])
+	return w, h, nil
+}
+
+// prescale resizes the picture to the resolution of the primary display,
+// either cropping it or fitting it with bars, and returns the path of the
+// cached result.
+func prescale(cfg *Config, filename string) (string, error) {
+	w, h, err := displayResolution()
+	if err != nil {
+		return "", err
+	}
+	mode := cfg.PrescaleMode
+	if mode == "" {
+		mode = prescaleCrop
+	}
+	cached, ok, err := cachedVariant("prescaled", filename, fmt.Sprintf("%dx%d-%s", w, h, mode))
+	if err != nil || ok {
+		return cached, err
+	}
+	img, err := loadImage(filename)
+	if err != nil {
+		return "", err
+	}
+	if b := img.Bounds(); b.Dx() == w && b.Dy() == h {
+		return filename, nil
+	}
+	if mode == prescaleFit {
+		err = writeJPEG(cached, fitWithBars(img, w, h, color.Black))
+	} else {
+		err = writeJPEG(cached, cropToFill(img, w, h))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write prescaled picture: %w", err)
+	}
+	return cached, nil
+}
@@ -0,0 +1,93 @@
+package bgchanger
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// halves returns a w x h image, red on the left half and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, image.Rect(0, 0, w/2, h), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(w/2, 0, w, h), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestCropToFill(t *testing.T) {
+	out := cropToFill(halves(160, 90), 40, 40)
+	if s := out.Bounds().Size(); s != image.Pt(40, 40) {
+		t.Fatalf("got size %v, want 40x40", s)
+	}
+	// the center is kept, so both halves are still there
+	if c := out.RGBAAt(2, 20); c.R < 200 || c.B > 50 {
+		t.Errorf("got %v on the left, want red", c)
+	}
+	if c := out.RGBAAt(37, 20); c.B < 200 || c.R > 50 {
+		t.Errorf("got %v on the right, want blue", c)
+	}
+}
+
+func TestFitWithBars(t *testing.T) {
+	out := fitWithBars(halves(160, 90), 40, 40, color.Black)
+	if s := out.Bounds().Size(); s != image.Pt(40, 40) {
+		t.Fatalf("got size %v, want 40x40", s)
+	}
+	// the picture is 40x23, with bars above and below
+	if c := out.RGBAAt(20, 2); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("got %v in the top bar, want black", c)
+	}
+	if c := out.RGBAAt(5, 20); c.R < 200 {
+		t.Errorf("got %v in the picture, want red", c)
+	}
+}
+
+func TestParseXrandr(t *testing.T) {
+	out := `Screen 0: minimum 320 x 200, current 4480 x 1440, maximum 16384 x 16384
+HDMI-1 connected 1920x1080+2560+0 (normal left inverted right x axis y axis) 527mm x 296mm
+DP-1 connected primary 2560x1440+0+0 (normal left inverted right x axis y axis) 597mm x 336mm
+DP-2 disconnected (normal left inverted right x axis y axis)
+`
+	if w, h, err := parseXrandr(out); err != nil || w != 2560 || h != 1440 {
+		t.Errorf("got %dx%d, %v, want the primary 2560x1440", w, h, err)
+	}
+	if w, h, err := parseXrandr("HDMI-1 connected 1920x1080+0+0 (normal)\n"); err != nil || w != 1920 || h != 1080 {
+		t.Errorf("got %dx%d, %v, want 1920x1080 without a primary display", w, h, err)
+	}
+	if _, _, err := parseXrandr("DP-2 disconnected (normal)\n"); err == nil {
+		t.Error("no error without a connected display")
+	}
+}
+
+func TestPrescale(t *testing.T) {
+	testEnv(t)
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		return []byte("DP-1 connected primary 32x32+0+0 (normal)\n"), nil
+	}
+	picture := writeTestPicture(t, filepath.Join(t.TempDir(), "wide.png"), 64, 16, color.RGBA{0, 128, 0, 255})
+	for _, mode := range []string{"crop", "fit"} {
+		cfg := testConfig(t, `{"pictures_dir": "/p", "prescale": true, "prescale_mode": %q}`, mode)
+		cached, err := prescale(cfg, picture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd, err := os.Open(cached)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ic, _, err := image.DecodeConfig(fd)
+		fd.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ic.Width != 32 || ic.Height != 32 {
+			t.Errorf("%s: got %dx%d, want 32x32", mode, ic.Width, ic.Height)
+		}
+		if again, err := prescale(cfg, picture); err != nil || again != cached {
+			t.Errorf("%s: got %s, %v the second time, want the cached %s", mode, again, err, cached)
+		}
+	}
+}
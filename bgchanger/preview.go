@@ -0,0 +1,79 @@
+package bgchanger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// previewTimeout is how long the preview notification waits for its Apply
+// action.
+const previewTimeout = 2 * time.Minute
+
+// waitNotification shows a notification with notify-send, waiting up to
+// previewTimeout for one of its actions, and returns the output, i.e. the name
+// of the action chosen. It is a variable like commandOutput, which cannot be
+// used as the notification outlives the command timeout.
+var waitNotification = func(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "notify-send", append([]string{"--wait"}, args...)...).Output()
+}
+
+// peekNext returns the picture the next change will apply, without applying
+// it: the first queued one if any, otherwise the preloaded one if any,
+// otherwise a new pick that is then kept as the preloaded one.
+func peekNext(cfg *Config) (string, error) {
+	if filename, ok := peekQueue(cfg); ok {
+		return filename, nil
+	}
+	preloaded.Lock()
+	filename := preloaded.filename
+	preloaded.Unlock()
+	if filename != "" {
+		return filename, nil
+	}
+	filename, src, err := pickPicture(cfg, changeExclusions(cfg))
+	if err != nil {
+		return "", err
+	}
+	if !isVideo(filename) {
+		preparePicture(cfg, filename)
+	}
+	preloaded.Lock()
+	// also discards a running preload, which would replace this pick
+	preloaded.generation++
+	preloaded.filename, preloaded.src = filename, src
+	preloaded.Unlock()
+	return filename, nil
+}
+
+// PreviewNext shows a notification with the picture the next change will
+// apply, and an action to apply it right away.
+func PreviewNext(cfg *Config) error {
+	filename, err := peekNext(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot get the next picture: %w", err)
+	}
+	icon := filename
+	if !isVideo(filename) {
+		icon = preparePicture(cfg, filename)
+	}
+	go func() {
+		out, err := waitNotification("--app-name="+Progname, "--icon="+icon,
+			"--action=apply="+Tr("apply"), Tr("preview_next"), filename)
+		if err != nil {
+			debugf("preview notification failed: %v", err)
+			return
+		}
+		if strings.TrimSpace(string(out)) == "apply" {
+			// cfg and the change state belong to the main loop
+			inMainLoop(func() { ManualChangeBG(cfg) })
+		}
+	}()
+	log.Printf("Next background will be '%s'", filename)
+	return nil
+}
@@ -0,0 +1,75 @@
+package bgchanger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewNext(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	notifications := make(chan []string, 1)
+	old := waitNotification
+	waitNotification = func(args ...string) ([]byte, error) {
+		notifications <- args
+		return nil, nil
+	}
+	t.Cleanup(func() { waitNotification = old })
+
+	if err := PreviewNext(cfg); err != nil {
+		t.Fatal(err)
+	}
+	var args []string
+	select {
+	case args = <-notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v, want the preview to apply nothing", setter.sets)
+	}
+	next := args[len(args)-1]
+	if !strings.HasPrefix(next, dir) {
+		t.Fatalf("got notification %v, want the next candidate", args)
+	}
+	// previewing again shows the same picture
+	if again, err := peekNext(cfg); err != nil || again != next {
+		t.Errorf("got %s, %v, want the previewed %s", again, err, next)
+	}
+	// and the next change applies it
+	ChangeBG(cfg)
+	if uri := setter.get("picture-uri"); uri != "file://"+next {
+		t.Errorf("got picture-uri %s, want the previewed %s", uri, next)
+	}
+}
+
+func TestPreviewApply(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 5)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	old := waitNotification
+	waitNotification = func(args ...string) ([]byte, error) { return []byte("apply\n"), nil }
+	t.Cleanup(func() { waitNotification = old })
+
+	next, err := peekNext(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := PreviewNext(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// the change is applied by the main loop
+	select {
+	case f := <-ControlRequests():
+		f()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the Apply action did not reach the main loop")
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+next {
+		t.Errorf("got picture-uri %s after the Apply action, want %s", uri, next)
+	}
+}
@@ -0,0 +1,70 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+func TestPrintConfig(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "default", "interval": "5m", "profile": "night",
+		"accessibility": {"enabled": true, "min_interval": "10m"},
+		"profiles": {"night": {"pictures_dir": "night", "interval": "1h"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configFile, cfg, err := LoadConfigFrom(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var printErr error
+	out := captureStdout(t, func() { printErr = PrintConfig(configFile, cfg) })
+	if printErr != nil {
+		t.Fatal(printErr)
+	}
+	var got struct {
+		Config
+		ConfigFile        string         `json:"config_file"`
+		ActiveSources     []SourceConfig `json:"active_sources"`
+		EffectiveInterval xjson.Duration `json:"effective_interval"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+
+	night := filepath.Join(dir, "night")
+	if got.ConfigFile != configFile {
+		t.Errorf("got config_file %q, want %q", got.ConfigFile, configFile)
+	}
+	// the profile is applied, and the relative paths resolved
+	if got.PicturesDir != night {
+		t.Errorf("got pictures_dir %q, want the resolved path of the profile %q", got.PicturesDir, night)
+	}
+	if time.Duration(got.Interval) != time.Hour {
+		t.Errorf("got interval %s, want the one of the profile", time.Duration(got.Interval))
+	}
+	// the defaults are filled in
+	if time.Duration(got.WatchGrace) != defaultWatchGrace {
+		t.Errorf("got watch_grace %s, want the default", time.Duration(got.WatchGrace))
+	}
+	if !reflect.DeepEqual(got.SupportedExtensions, defaultExtensions) {
+		t.Errorf("got supported_extensions %v, want the defaults", got.SupportedExtensions)
+	}
+	if got.ConfirmDestructive == nil || !*got.ConfirmDestructive {
+		t.Error("got confirm_destructive unset, want the default true")
+	}
+	// and so are the derived values
+	if len(got.ActiveSources) != 1 || got.ActiveSources[0].Path != night {
+		t.Errorf("got active_sources %+v, want the profile pictures_dir", got.ActiveSources)
+	}
+	if time.Duration(got.EffectiveInterval) != time.Hour {
+		t.Errorf("got effective_interval %s, want 1h", time.Duration(got.EffectiveInterval))
+	}
+}
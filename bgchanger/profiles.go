@@ -0,0 +1,67 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// applyProfile overlays the settings of the active profile on the config.
+// The active profile is the one of the network_sources entry matching the
+// current network, or else the one last chosen from the menu, or else the one
+// named by the profile setting.
+func applyProfile(cfg *Config) error {
+	if len(cfg.Profiles) == 0 {
+		cfg.Profile = ""
+		return nil
+	}
+	name := cfg.Profile
+	if chosen := loadPersistedState().Profile; chosen != "" {
+		if _, ok := cfg.Profiles[chosen]; ok {
+			name = chosen
+		} else {
+			log.Printf("Warning: profile '%s' does not exist anymore", chosen)
+		}
+	}
+	if r := networkRule(cfg.NetworkSources); r != nil && r.Profile != "" {
+		name = r.Profile
+	}
+	if name == "" {
+		return nil
+	}
+	overlay, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile '%s'", name)
+	}
+	// only the settings present in the profile are replaced
+	if err := json.Unmarshal(overlay, cfg); err != nil {
+		return fmt.Errorf("invalid profile '%s': %w", name, err)
+	}
+	cfg.Profile = name
+	return nil
+}
+
+// ProfileNames returns the sorted names of the configured profiles.
+func ProfileNames(cfg *Config) []string {
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchProfile makes name the active profile, saving the choice across
+// restarts, and returns the config file reloaded with it.
+func SwitchProfile(configFile, name string) (*Config, error) {
+	if err := updatePersistedState(func(st *persistedState) { st.Profile = name }); err != nil {
+		return nil, err
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Switched to profile '%s'", cfg.Profile)
+	return cfg, nil
+}
@@ -0,0 +1,50 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSwitchProfile(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	work := writeTestPictures(t, filepath.Join(dir, "work"), 1)
+	personal := writeTestPictures(t, filepath.Join(dir, "personal"), 1)
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "work", "interval": "5m", "profile": "work", "profiles": {
+		"work": {"pictures_dir": "work"},
+		"personal": {"pictures_dir": "personal", "interval": "1h"}
+	}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ReloadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(ProfileNames(cfg), ","); names != "personal,work" {
+		t.Errorf("got profiles %s", names)
+	}
+	if p, err := PickPicture(cfg); err != nil || p != work[0] {
+		t.Errorf("picked %s, %v, want %s", p, err, work[0])
+	}
+
+	cfg, err = SwitchProfile(configFile, "personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "personal" || time.Duration(EffectiveInterval(cfg)) != time.Hour {
+		t.Errorf("got profile %s with interval %s, want personal and 1h", cfg.Profile, EffectiveInterval(cfg))
+	}
+	if p, err := PickPicture(cfg); err != nil || p != personal[0] {
+		t.Errorf("picked %s, %v, want %s", p, err, personal[0])
+	}
+
+	// the choice is kept across restarts
+	resetState()
+	if cfg, err = ReloadConfig(configFile); err != nil || cfg.Profile != "personal" {
+		t.Errorf("got profile %s, %v after a restart, want personal", cfg.Profile, err)
+	}
+}
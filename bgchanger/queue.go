@@ -0,0 +1,112 @@
+package bgchanger
+
+import (
+	"log"
+	"sync"
+)
+
+// queue is the list of pictures lined up from the menu, which the next
+// changes apply in order before going back to the usual selection.
+var queue = struct {
+	sync.Mutex
+	pictures []string
+}{}
+
+// OnQueueChange is called with the number of queued pictures when it
+// changes.
+var OnQueueChange = func(length int) {}
+
+// RestoreQueue loads the queue saved by a previous run, if persist_queue is
+// set.
+func RestoreQueue(cfg *Config) {
+	if !cfg.PersistQueue {
+		return
+	}
+	pictures := loadPersistedState().Queue
+	queue.Lock()
+	queue.pictures = pictures
+	queue.Unlock()
+	if len(pictures) > 0 {
+		log.Printf("Restored %d queued pictures", len(pictures))
+	}
+	OnQueueChange(len(pictures))
+}
+
+// QueueCurrent adds the current picture at the end of the queue.
+func QueueCurrent(cfg *Config) {
+	filename := CurrentPicture()
+	if filename == "" {
+		log.Printf("No background was set yet, nothing to queue")
+		return
+	}
+	queue.Lock()
+	queue.pictures = append(queue.pictures, filename)
+	n := len(queue.pictures)
+	queue.Unlock()
+	log.Printf("Queued '%s', %d pictures in the queue", filename, n)
+	queueChanged(cfg)
+}
+
+// ClearQueue removes all the queued pictures.
+func ClearQueue(cfg *Config) {
+	queue.Lock()
+	queue.pictures = nil
+	queue.Unlock()
+	log.Printf("Queue cleared")
+	queueChanged(cfg)
+}
+
+// QueueLength returns the number of queued pictures.
+func QueueLength() int {
+	queue.Lock()
+	defer queue.Unlock()
+	return len(queue.pictures)
+}
+
+// peekQueue returns the first queued picture that still exists, dropping the
+// ones before it that don't.
+func peekQueue(cfg *Config) (string, bool) {
+	queue.Lock()
+	dropped := false
+	for len(queue.pictures) > 0 && !isReadableFile(queue.pictures[0]) {
+		log.Printf("Warning: dropping '%s' from the queue, it cannot be read", queue.pictures[0])
+		queue.pictures = queue.pictures[1:]
+		dropped = true
+	}
+	var filename string
+	if len(queue.pictures) > 0 {
+		filename = queue.pictures[0]
+	}
+	queue.Unlock()
+	if dropped {
+		queueChanged(cfg)
+	}
+	return filename, filename != ""
+}
+
+// popQueue removes and returns the first queued picture that still exists.
+func popQueue(cfg *Config) (string, bool) {
+	filename, ok := peekQueue(cfg)
+	if !ok {
+		return "", false
+	}
+	queue.Lock()
+	queue.pictures = queue.pictures[1:]
+	queue.Unlock()
+	queueChanged(cfg)
+	return filename, true
+}
+
+// queueChanged saves the queue if persist_queue is set, and notifies
+// OnQueueChange.
+func queueChanged(cfg *Config) {
+	queue.Lock()
+	pictures := append([]string(nil), queue.pictures...)
+	queue.Unlock()
+	if cfg.PersistQueue {
+		if err := updatePersistedState(func(st *persistedState) { st.Queue = pictures }); err != nil {
+			log.Printf("Warning: cannot save the queue: %v", err)
+		}
+	}
+	OnQueueChange(len(pictures))
+}
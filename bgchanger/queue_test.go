@@ -0,0 +1,118 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	random := writeTestPictures(t, filepath.Join(dir, "random"), 2)
+	queued := writeTestPictures(t, filepath.Join(dir, "queued"), 3)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, filepath.Join(dir, "random"))
+	var lengths []int
+	old := OnQueueChange
+	OnQueueChange = func(n int) { lengths = append(lengths, n) }
+	t.Cleanup(func() { OnQueueChange = old })
+
+	QueueCurrent(cfg)
+	if QueueLength() != 0 {
+		t.Fatal("queued a picture before any was set")
+	}
+	for _, p := range []string{queued[2], queued[0], queued[1]} {
+		if err := SetBackground(cfg, p); err != nil {
+			t.Fatal(err)
+		}
+		QueueCurrent(cfg)
+	}
+	if QueueLength() != 3 {
+		t.Fatalf("got %d queued pictures, want 3", QueueLength())
+	}
+	if next, err := peekNext(cfg); err != nil || next != queued[2] {
+		t.Errorf("got next %s, %v, want the first queued %s", next, err, queued[2])
+	}
+	setter.sets = nil
+	for i := 0; i < 4; i++ {
+		ChangeBG(cfg)
+	}
+	uris := setter.values("picture-uri")
+	want := []string{"file://" + queued[2], "file://" + queued[0], "file://" + queued[1]}
+	if len(uris) != 4 || strings.Join(uris[:3], " ") != strings.Join(want, " ") {
+		t.Fatalf("got picture-uri %v, want %v then a random pick", uris, want)
+	}
+	if p := strings.TrimPrefix(uris[3], "file://"); p != random[0] && p != random[1] {
+		t.Errorf("got %s after the queue, want a picture of pictures_dir", p)
+	}
+	if QueueLength() != 0 {
+		t.Errorf("got %d queued pictures after applying them", QueueLength())
+	}
+	if got := lengths[len(lengths)-1]; got != 0 {
+		t.Errorf("OnQueueChange got %d last, want 0", got)
+	}
+}
+
+func TestQueueSkipsMissing(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	queued := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	for _, p := range queued {
+		if err := SetBackground(cfg, p); err != nil {
+			t.Fatal(err)
+		}
+		QueueCurrent(cfg)
+	}
+	if err := os.Remove(queued[0]); err != nil {
+		t.Fatal(err)
+	}
+	setter.sets = nil
+	ChangeBG(cfg)
+	if got := setter.get("picture-uri"); got != "file://"+queued[1] {
+		t.Errorf("got picture-uri %s, want the remaining queued %s", got, queued[1])
+	}
+}
+
+func TestClearQueue(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	queued := writeTestPictures(t, dir, 1)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if err := SetBackground(cfg, queued[0]); err != nil {
+		t.Fatal(err)
+	}
+	QueueCurrent(cfg)
+	QueueCurrent(cfg)
+	ClearQueue(cfg)
+	if QueueLength() != 0 {
+		t.Errorf("got %d queued pictures after clearing", QueueLength())
+	}
+}
+
+func TestPersistQueue(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	queued := writeTestPictures(t, dir, 2)
+	for _, persist := range []bool{false, true} {
+		cfg := testConfig(t, `{"pictures_dir": %q, "persist_queue": %v}`, dir, persist)
+		for _, p := range queued {
+			if err := SetBackground(cfg, p); err != nil {
+				t.Fatal(err)
+			}
+			QueueCurrent(cfg)
+		}
+		// a new instance
+		resetState()
+		RestoreQueue(cfg)
+		want := 0
+		if persist {
+			want = 2
+		}
+		if QueueLength() != want {
+			t.Errorf("persist_queue %v: got %d restored pictures, want %d", persist, QueueLength(), want)
+		}
+		ClearQueue(cfg)
+	}
+}
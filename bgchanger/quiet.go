@@ -0,0 +1,138 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// QuietWindow is a daily time range, optionally limited to some weekdays,
+// during which automatic background changes are suppressed. Start and End are
+// in 24-hour "HH:MM" format. A window whose end is before its start crosses
+// midnight, and belongs to the weekday it starts on.
+type QuietWindow struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Weekdays []string `json:"weekdays"`
+
+	start, end int
+	weekdays   map[time.Weekday]bool
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (qw *QuietWindow) parse() error {
+	var err error
+	if qw.start, err = parseClock(qw.Start); err != nil {
+		return err
+	}
+	if qw.end, err = parseClock(qw.End); err != nil {
+		return err
+	}
+	if len(qw.Weekdays) > 0 {
+		qw.weekdays = make(map[time.Weekday]bool)
+		for _, name := range qw.Weekdays {
+			// accept both "mon" and "monday"
+			key := strings.ToLower(name)
+			if len(key) > 3 {
+				key = key[:3]
+			}
+			wd, ok := weekdayNames[key]
+			if !ok {
+				return fmt.Errorf("invalid weekday '%s'", name)
+			}
+			qw.weekdays[wd] = true
+		}
+	}
+	return nil
+}
+
+func (qw *QuietWindow) onDay(wd time.Weekday) bool {
+	return qw.weekdays == nil || qw.weekdays[wd]
+}
+
+// contains reports whether t falls inside the window.
+func (qw *QuietWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if qw.start <= qw.end {
+		return m >= qw.start && m < qw.end && qw.onDay(t.Weekday())
+	}
+	// the window crosses midnight
+	if m >= qw.start {
+		return qw.onDay(t.Weekday())
+	}
+	if m < qw.end {
+		return qw.onDay(t.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
+
+func inQuietHours(windows []QuietWindow, t time.Time) bool {
+	for i := range windows {
+		if windows[i].contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoChangeBG is like ChangeBG, but for changes not explicitly requested by
+// the user, which are skipped during quiet hours, in comfort and focus mode,
+// during a manual_hold and while paused by pause_on_failures.
+func AutoChangeBG(cfg *Config) {
+	scheduleNextChange(cfg)
+	if changeAllowed(cfg) {
+		ChangeBG(cfg)
+	}
+}
+
+// changeAllowed reports whether a change not explicitly requested by the user
+// can happen now, logging why not otherwise.
+func changeAllowed(cfg *Config) bool {
+	if inQuietHours(cfg.QuietHours, time.Now()) {
+		log.Printf("Skipping background change during quiet hours")
+		return false
+	}
+	if ComfortEnabled() {
+		debugf("Skipping background change in comfort mode")
+		return false
+	}
+	if FocusEnabled() {
+		debugf("Skipping background change in focus mode")
+		return false
+	}
+	if until, held := Held(); held {
+		debugf("Skipping background change, holding until %s", until.Format("15:04"))
+		return false
+	}
+	if Paused() {
+		debugf("Skipping background change, paused")
+		return false
+	}
+	if failuresPaused() {
+		debugf("Skipping background change, paused after %d failures", cfg.FailureAlertAfter)
+		return false
+	}
+	if cfg.SkipWhenFullscreen && fullscreenActive() {
+		log.Printf("Skipping background change while a fullscreen window is active")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package bgchanger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	cfg := testConfig(t, `{"pictures_dir": "/p", "quiet_hours": [
+		{"start": "09:00", "end": "10:30", "weekdays": ["mon", "Tuesday"]},
+		{"start": "23:00", "end": "01:00", "weekdays": ["fri"]}
+	]}`)
+	// 2026-10-12 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+	}
+	for _, tc := range []struct {
+		t     time.Time
+		quiet bool
+	}{
+		{at(12, 9, 0), true},
+		{at(13, 10, 29), true},
+		{at(12, 10, 30), false},
+		{at(12, 8, 59), false},
+		{at(14, 9, 30), false},
+		{at(16, 23, 30), true},
+		{at(17, 0, 30), true},
+		{at(17, 1, 0), false},
+		{at(17, 23, 30), false},
+		{at(18, 0, 30), false},
+	} {
+		if got := inQuietHours(cfg.QuietHours, tc.t); got != tc.quiet {
+			t.Errorf("%s: got quiet %v, want %v", tc.t.Format("Mon 15:04"), got, tc.quiet)
+		}
+	}
+	for _, config := range []string{
+		`{"pictures_dir": "/p", "quiet_hours": [{"start": "9", "end": "10:00"}]}`,
+		`{"pictures_dir": "/p", "quiet_hours": [{"start": "09:00", "end": "10:00", "weekdays": ["someday"]}]}`,
+	} {
+		if _, err := ParseConfig([]byte(config)); err == nil {
+			t.Errorf("%s accepted", config)
+		}
+	}
+}
+
+func TestQuietHoursSuppressAutomaticChanges(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	now := time.Now()
+	clock := func(d time.Duration) string {
+		return now.Add(d).Format("15:04")
+	}
+	quiet := testConfig(t, `{"pictures_dir": %q, "quiet_hours": [{"start": %q, "end": %q}]}`,
+		dir, clock(-time.Hour), clock(time.Hour))
+	AutoChangeBG(quiet)
+	if n := len(setter.values("picture-uri")); n != 0 {
+		t.Fatalf("got %d changes during quiet hours, want none", n)
+	}
+	ManualChangeBG(quiet)
+	if n := len(setter.values("picture-uri")); n != 1 {
+		t.Fatalf("got %d manual changes during quiet hours, want 1", n)
+	}
+	resetState()
+	outside := testConfig(t, `{"pictures_dir": %q, "quiet_hours": [{"start": %q, "end": %q}]}`,
+		dir, clock(time.Hour), clock(2*time.Hour))
+	AutoChangeBG(outside)
+	if n := len(setter.values("picture-uri")); n != 2 {
+		t.Errorf("got %d changes outside quiet hours, want 2", n)
+	}
+}
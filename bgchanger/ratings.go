@@ -0,0 +1,126 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/kirsle/configdir"
+)
+
+// maxRating bounds the rating of a picture in both directions. Each rating
+// point doubles (or halves) the chance of a picture being picked.
+const maxRating = 5
+
+// ratings holds the user's rating of each picture, by path. Unrated pictures
+// have a neutral rating of 0.
+var ratings = struct {
+	sync.Mutex
+	loaded bool
+	scores map[string]int
+}{}
+
+func ratingsFile() string {
+	return path.Join(configdir.LocalConfig(Progname), "ratings.json")
+}
+
+// loadRatingsLocked reads the ratings file, if not done already. The ratings
+// lock must be held.
+func loadRatingsLocked() {
+	if ratings.loaded {
+		return
+	}
+	ratings.loaded = true
+	ratings.scores = make(map[string]int)
+	data, err := os.ReadFile(ratingsFile())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: cannot read ratings: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &ratings.scores); err != nil {
+		log.Printf("Warning: cannot parse ratings: %v", err)
+	}
+}
+
+func getRating(filename string) int {
+	ratings.Lock()
+	defer ratings.Unlock()
+	loadRatingsLocked()
+	return ratings.scores[filename]
+}
+
+// ratePicture adds delta to the rating of the given picture, and saves the
+// ratings.
+func ratePicture(filename string, delta int) error {
+	ratings.Lock()
+	defer ratings.Unlock()
+	loadRatingsLocked()
+	score := ratings.scores[filename] + delta
+	if score > maxRating {
+		score = maxRating
+	} else if score < -maxRating {
+		score = -maxRating
+	}
+	ratings.scores[filename] = score
+	data, err := json.MarshalIndent(ratings.scores, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ratings: %w", err)
+	}
+	if err := writeFileAtomic(ratingsFile(), data); err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+	log.Printf("Rated '%s' %+d", filename, score)
+	return nil
+}
+
+// ratingWeight returns the selection weight of a picture according to its
+// rating.
+func ratingWeight(filename string) float64 {
+	return math.Pow(2, float64(getRating(filename)))
+}
+
+// weightedShuffle shuffles the pictures so that the ones with a higher weight
+// are more likely to come first.
+func weightedShuffle(pictures []string, weight func(string) float64) {
+	// Efraimidis-Spirakis: sort by u^(1/w), u uniform in (0, 1)
+	keys := make(map[string]float64, len(pictures))
+	for _, p := range pictures {
+		keys[p] = math.Pow(rand.Float64(), 1/weight(p))
+	}
+	sort.SliceStable(pictures, func(i, j int) bool { return keys[pictures[i]] > keys[pictures[j]] })
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to filename,
+// so that readers never see a partially written file.
+func writeFileAtomic(filename string, data []byte) error {
+	if err := checkWrite(filename); err != nil {
+		return err
+	}
+	fd, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+	if err := os.Rename(fd.Name(), filename); err != nil {
+		os.Remove(fd.Name())
+		return err
+	}
+	return nil
+}
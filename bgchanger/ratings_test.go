@@ -0,0 +1,51 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestRatingsBiasSelection(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 3)
+	liked, disliked := pictures[0], pictures[1]
+	if err := ratePicture(liked, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ratePicture(disliked, -2); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		p, err := pickFrom(cfg, append([]string(nil), pictures...), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[p]++
+	}
+	// the weights are 4, 1 and 0.25
+	if counts[liked] < 2*counts[pictures[2]] || counts[pictures[2]] < 2*counts[disliked] || counts[disliked] == 0 {
+		t.Errorf("got liked %d, unrated %d, disliked %d", counts[liked], counts[pictures[2]], counts[disliked])
+	}
+
+	data, err := os.ReadFile(ratingsFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]int
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved[liked] != 2 || saved[disliked] != -2 {
+		t.Errorf("saved %v", saved)
+	}
+	if err := ratePicture(liked, 10); err != nil {
+		t.Fatal(err)
+	}
+	if r := getRating(liked); r != maxRating {
+		t.Errorf("got rating %d, want it capped at %d", r, maxRating)
+	}
+}
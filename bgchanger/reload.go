@@ -0,0 +1,83 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/editor"
+)
+
+// openEditor opens the given file with the configured editor, returning when
+// the editor exits.
+var openEditor = editor.Open
+
+// EditConfig opens the config file with the editor and, once the editor
+// returns, applies the edited config to cfg. It reports whether the interval
+// changed, in which case the change timer has to be restarted.
+func EditConfig(configFile string, cfg *Config) (bool, error) {
+	if err := openEditor(configFile); err != nil {
+		return false, fmt.Errorf("failed to open config file: %w", err)
+	}
+	newCfg, err := ReloadConfig(configFile)
+	if err != nil {
+		return false, fmt.Errorf("not applying the edited config: %w", err)
+	}
+	intervalChanged := newCfg.Interval != cfg.Interval
+	ApplyConfig(cfg, newCfg)
+	return intervalChanged, nil
+}
+
+// OnConfigApplied is called after ApplyConfig replaced the running
+// configuration, with the previous one, so that the tray menu can be updated.
+var OnConfigApplied = func(previous *Config) {}
+
+// ApplyConfig replaces the running configuration with newCfg, logging which
+// settings changed.
+func ApplyConfig(cfg, newCfg *Config) {
+	oldV, newV := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(newCfg).Elem()
+	for i := 0; i < oldV.NumField(); i++ {
+		field := oldV.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if reflect.DeepEqual(oldV.Field(i).Interface(), newV.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		log.Printf("Config: %s changed from %v to %v", name, oldV.Field(i).Interface(), newV.Field(i).Interface())
+	}
+	if newCfg.Editor != cfg.Editor && newCfg.Editor != "" {
+		editor.Set(newCfg.Editor)
+	}
+	// the gradient mode hides the picture, the spanned picture of per_monitor
+	// covers all the displays, and without picture_options, landscape_options
+	// or portrait_options picture-options is not set from the config anymore
+	if (cfg.Mode == modeGradient && newCfg.Mode != modeGradient) ||
+		(cfg.PerMonitor && !newCfg.PerMonitor) ||
+		(orientationConfigured(cfg) && !orientationConfigured(newCfg)) {
+		if err := restorePictureOptions(newCfg); err != nil {
+			log.Printf("Error: %v", err)
+		}
+	}
+	if newCfg.IntervalClickAction != intervalClickPause {
+		// nothing could resume the changes anymore
+		setPaused(false)
+	}
+	if newCfg.Hotkey != cfg.Hotkey {
+		SetupHotkey(newCfg)
+	}
+	StartWatcher(newCfg)
+	StartNetworkWatcher(newCfg)
+	setCommandTimeout(time.Duration(newCfg.CommandTimeout))
+	setWriteAllowlist(newCfg.WriteAllowlist)
+	setSupportedExtensions(newCfg.SupportedExtensions)
+	setLargeDirectory(newCfg.LargeDirectory)
+	setLogFormat(newCfg.LogFormat)
+	invalidatePreload()
+	previous := *cfg
+	*cfg = *newCfg
+	OnConfigApplied(&previous)
+}
@@ -0,0 +1,113 @@
+package bgchanger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEditConfigAppliesInterval(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(`{"pictures_dir": %q, "interval": "1h"}`, dir)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, cfg, err := LoadConfigFrom(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := openEditor
+	defer func() { openEditor = orig }()
+	openEditor = func(filename string) error {
+		if filename != configFile {
+			t.Errorf("editing %s, want %s", filename, configFile)
+		}
+		return os.WriteFile(filename, []byte(`{"pictures_dir": "pictures", "interval": "10m"}`), 0644)
+	}
+
+	changed, err := EditConfig(configFile, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("the interval change was not reported")
+	}
+	if d := time.Duration(EffectiveInterval(cfg)); d != 10*time.Minute {
+		t.Errorf("got interval %s after editing, want 10m", d)
+	}
+	if want := filepath.Join(dir, "pictures"); cfg.PicturesDir != want {
+		t.Errorf("got pictures_dir %s, want %s", cfg.PicturesDir, want)
+	}
+
+	openEditor = func(filename string) error {
+		return os.WriteFile(filename, []byte(`{"interval": "5m"`), 0644)
+	}
+	if _, err := EditConfig(configFile, cfg); err == nil {
+		t.Error("an invalid edited config was applied")
+	}
+	if d := time.Duration(cfg.Interval); d != 10*time.Minute {
+		t.Errorf("got interval %s after an invalid edit, want 10m", d)
+	}
+}
+
+func TestOnConfigApplied(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "a", "interval": "1h", "icon_path": "old.png",
+		"profiles": {"work": {"pictures_dir": "b", "interval": "5m"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, cfg, err := LoadConfigFrom(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// what the tray menu is refreshed from
+	type menu struct {
+		icon, previousIcon string
+		interval           time.Duration
+		profile            string
+		profiles           int
+		chooseDir          bool
+	}
+	var refreshes []menu
+	old := OnConfigApplied
+	OnConfigApplied = func(previous *Config) {
+		refreshes = append(refreshes, menu{
+			icon:         cfg.IconPath,
+			previousIcon: previous.IconPath,
+			interval:     time.Duration(EffectiveInterval(cfg)),
+			profile:      cfg.Profile,
+			profiles:     len(cfg.Profiles),
+			chooseDir:    len(cfg.Sources) == 0,
+		})
+	}
+	t.Cleanup(func() { OnConfigApplied = old })
+
+	newCfg, err := SwitchProfile(configFile, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyConfig(cfg, newCfg)
+	want := menu{filepath.Join(dir, "old.png"), filepath.Join(dir, "old.png"), 5 * time.Minute, "work", 1, true}
+	if len(refreshes) != 1 || refreshes[0] != want {
+		t.Fatalf("got refreshes %+v, want %+v", refreshes, want)
+	}
+
+	orig := openEditor
+	defer func() { openEditor = orig }()
+	openEditor = func(filename string) error {
+		return os.WriteFile(filename, []byte(`{"icon_path": "new.png", "interval": "10m",
+			"sources": [{"type": "dir", "path": "c"}]}`), 0644)
+	}
+	if _, err := EditConfig(configFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want = menu{filepath.Join(dir, "new.png"), filepath.Join(dir, "old.png"), 10 * time.Minute, "", 0, false}
+	if len(refreshes) != 2 || refreshes[1] != want {
+		t.Errorf("got refreshes %+v, want %+v last", refreshes, want)
+	}
+}
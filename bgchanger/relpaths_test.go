@@ -0,0 +1,43 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelativePaths(t *testing.T) {
+	testEnv(t)
+	root := t.TempDir()
+	pictures := writeTestPictures(t, filepath.Join(root, "usb", "pictures"), 1)
+	absolute := t.TempDir()
+	configFile := filepath.Join(root, "usb", "bgchanger.json")
+	if err := os.WriteFile(configFile, []byte(`{"pictures_dir": "pictures", "blocklist_file": "lists/blocked.txt",
+		"sources": [{"type": "dir", "path": "pictures"}, {"type": "dir", "path": "`+absolute+`"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the current directory does not matter
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	_, cfg, err := LoadConfigFrom(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(configFile)
+	if cfg.PicturesDir != filepath.Join(dir, "pictures") || cfg.BlocklistFile != filepath.Join(dir, "lists", "blocked.txt") {
+		t.Errorf("got pictures_dir %s and blocklist_file %s, want them relative to %s", cfg.PicturesDir, cfg.BlocklistFile, dir)
+	}
+	if cfg.Sources[0].Path != filepath.Join(dir, "pictures") || cfg.Sources[1].Path != absolute {
+		t.Errorf("got source paths %s and %s", cfg.Sources[0].Path, cfg.Sources[1].Path)
+	}
+	if p, err := PickPicture(cfg); err != nil || p != pictures[0] {
+		t.Errorf("picked %s, %v, want %s", p, err, pictures[0])
+	}
+}
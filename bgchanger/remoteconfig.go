@@ -0,0 +1,114 @@
+package bgchanger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+// maxRemoteConfigSize bounds the size of a config file fetched over HTTP.
+const maxRemoteConfigSize = 1 << 20
+
+func isRemoteConfig(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// LoadConfigFrom loads the config from location, which is a local file or an
+// http(s) URL. An empty location means the default config file, as in
+// LoadConfig. It returns the path of the local config file and the parsed
+// config.
+func LoadConfigFrom(location string) (string, *Config, error) {
+	if location == "" {
+		return LoadConfig()
+	}
+	if isRemoteConfig(location) {
+		return loadRemoteConfig(location)
+	}
+	cfg, err := ReloadConfig(location)
+	if err != nil {
+		return location, nil, err
+	}
+	setCommandTimeout(time.Duration(cfg.CommandTimeout))
+	setWriteAllowlist(cfg.WriteAllowlist)
+	setSupportedExtensions(cfg.SupportedExtensions)
+	setLargeDirectory(cfg.LargeDirectory)
+	setLogFormat(cfg.LogFormat)
+	return location, cfg, nil
+}
+
+// isRemoteConfigFile reports whether configFile is a remote config, or the
+// cached copy LoadConfigFrom returns for one, which are not to be edited.
+func isRemoteConfigFile(configFile string) bool {
+	return isRemoteConfig(configFile) || configFile == remoteConfigCache()
+}
+
+// remoteConfigCache returns the path of the cached copy of a remote config.
+func remoteConfigCache() string {
+	return path.Join(configdir.LocalCache(Progname), "remote-config.json")
+}
+
+// loadRemoteConfig fetches the config at the given URL, and keeps a copy in
+// the cache. If the config cannot be fetched, or is invalid, the cached copy
+// is used instead.
+func loadRemoteConfig(u string) (string, *Config, error) {
+	cacheDir := configdir.LocalCache(Progname)
+	if err := makeDirs(cacheDir); err != nil {
+		return "", nil, fmt.Errorf("failed to create cache directory '%s': %w", cacheDir, err)
+	}
+	cached := remoteConfigCache()
+	log.Printf("Fetching config from %s", u)
+	cfg, err := fetchRemoteConfig(u, cached)
+	if err != nil {
+		log.Printf("Warning: cannot fetch config from %s, using the cached copy: %v", u, err)
+		// relative paths are not resolved, as for the fetched config
+		data, rerr := os.ReadFile(cached)
+		if rerr != nil {
+			return cached, nil, fmt.Errorf("no usable cached config: %w", rerr)
+		}
+		if cfg, err = ParseConfig(data); err != nil {
+			return cached, nil, fmt.Errorf("no usable cached config: %w", err)
+		}
+	}
+	setCommandTimeout(time.Duration(cfg.CommandTimeout))
+	setWriteAllowlist(cfg.WriteAllowlist)
+	setSupportedExtensions(cfg.SupportedExtensions)
+	setLargeDirectory(cfg.LargeDirectory)
+	setLogFormat(cfg.LogFormat)
+	return cached, cfg, nil
+}
+
+// fetchRemoteConfig downloads and parses the config at the given URL, and
+// saves it to cached if valid.
+func fetchRemoteConfig(u, cached string) (*Config, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("config is larger than %d bytes", maxRemoteConfigSize)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(cached, data); err != nil {
+		log.Printf("Warning: cannot cache config: %v", err)
+	}
+	return cfg, nil
+}
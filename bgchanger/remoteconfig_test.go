@@ -0,0 +1,58 @@
+package bgchanger
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRemoteConfig(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	var down int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) != 0 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"pictures_dir": %q, "interval": "42m"}`, dir)
+	}))
+	defer srv.Close()
+
+	file, cfg, err := LoadConfigFrom(srv.URL + "/config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PicturesDir != dir || cfg.Interval.String() != "42m0s" {
+		t.Errorf("got pictures_dir %s and interval %v, want the served config", cfg.PicturesDir, cfg.Interval)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("the fetched config is not cached: %v", err)
+	}
+
+	// the cached copy is used while the server is unavailable
+	atomic.StoreInt32(&down, 1)
+	if _, cfg, err = LoadConfigFrom(srv.URL + "/config.json"); err != nil || cfg.PicturesDir != dir {
+		t.Errorf("got %v, %v with the server down, want the cached config", cfg, err)
+	}
+}
+
+func TestRemoteConfigInvalid(t *testing.T) {
+	testEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"interval": "-1h"}`)
+	}))
+	defer srv.Close()
+	// an invalid config is neither applied nor cached
+	file, _, err := LoadConfigFrom(srv.URL)
+	if err == nil {
+		t.Fatal("loaded an invalid remote config")
+	}
+	if _, serr := os.Stat(filepath.Clean(file)); !os.IsNotExist(serr) {
+		t.Errorf("the invalid config was cached: %v", serr)
+	}
+}
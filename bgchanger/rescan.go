@@ -0,0 +1,75 @@
+package bgchanger
+
+import "log"
+
+// candidateListing is the cached listing of a directory: all its pictures,
+// or none if it has more than large_directory, as those are sampled again at
+// each scan rather than kept in memory.
+type candidateListing struct {
+	pictures []string
+	large    bool
+}
+
+// candidateCache caches the pictures of each directory. Adding or removing a
+// file changes the modification time of the directory, so the cache follows
+// most changes on its own, and Rescan covers the rest.
+var candidateCache = newFileCache(func(dirname string) (candidateListing, error) {
+	limit := getLargeDirectory()
+	pictures, seen, err := listCandidates(dirname, limit)
+	if err != nil {
+		return candidateListing{}, err
+	}
+	if seen > limit {
+		return candidateListing{large: true}, nil
+	}
+	return candidateListing{pictures: pictures}, nil
+})
+
+// allCandidates, as the limit of cachedCandidates, lists every picture of a
+// directory however large it is.
+const allCandidates = -1
+
+// cachedCandidates is like ListCandidates, but reuses the previous listing of
+// dirname when possible. Without a limit, directories with more than
+// large_directory pictures are sampled down to that many, unless limit is
+// allCandidates. Sampled listings are never cached, so that each scan gets a
+// fresh sample, and neither are the listings while explaining, so that the
+// files left out of them are recorded.
+func cachedCandidates(dirname string, limit int) ([]string, error) {
+	if limit > 0 || explanation != nil {
+		if limit == 0 {
+			limit = getLargeDirectory()
+		}
+		return ListCandidates(dirname, limit)
+	}
+	listing, err := candidateCache.get(dirname)
+	if err != nil {
+		return nil, err
+	}
+	if listing.large {
+		if limit == allCandidates {
+			return ListCandidates(dirname, 0)
+		}
+		return ListCandidates(dirname, getLargeDirectory())
+	}
+	// callers may reorder the pictures, so don't hand out the cached slice
+	return append([]string(nil), listing.pictures...), nil
+}
+
+// Rescan drops the cached candidates and bucket listings and scans the
+// sources again, without changing the background. It returns the number of
+// candidates found.
+func Rescan(cfg *Config) (int, error) {
+	candidateCache.reset()
+	resetS3Listings()
+	invalidatePreload()
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Rescan found %d pictures", len(pictures))
+	if cfg.AdaptiveInterval.Enabled {
+		setCandidateCount(cfg, len(pictures))
+	}
+	return len(pictures), nil
+}
@@ -0,0 +1,46 @@
+package bgchanger
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRescanFindsNewPictures(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	before, err := ScanCandidates(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := writeTestPicture(t, filepath.Join(dir, "new.png"), 16, 9, color.RGBA{1, 2, 3, 255})
+	// keep the directory mtime, so that the cached listing looks current
+	if err := os.Chtimes(dir, time.Now(), st.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := ScanCandidates(cfg); err != nil || contains(cached, added) {
+		t.Fatalf("got %v, %v, want the cached listing without %s", cached, err, added)
+	}
+
+	n, err := Rescan(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(before)+1 {
+		t.Errorf("rescan found %d pictures, want %d", n, len(before)+1)
+	}
+	if after, err := ScanCandidates(cfg); err != nil || !contains(after, added) {
+		t.Errorf("got %v, %v after the rescan, want %s", after, err, added)
+	}
+	if n := len(setter.values("picture-uri")); n != 0 {
+		t.Errorf("the rescan changed the background %d times", n)
+	}
+}
@@ -0,0 +1,410 @@
+package bgchanger
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+const sourceS3 = "s3"
+
+const defaultS3Region = "us-east-1"
+
+// emptySHA256 is the hash of an empty request body.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Credentials are the credentials used to sign S3 requests. Empty
+// credentials mean anonymous requests, for public buckets.
+type s3Credentials struct {
+	accessKey, secretKey, sessionToken string
+}
+
+// loadS3Credentials gets the credentials from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables, or
+// else from the AWS_PROFILE (or default) profile of ~/.aws/credentials.
+func loadS3Credentials() s3Credentials {
+	if ak, sk := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); ak != "" && sk != "" {
+		return s3Credentials{ak, sk, os.Getenv("AWS_SESSION_TOKEN")}
+	}
+	filename := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return s3Credentials{}
+		}
+		filename = path.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	fd, err := os.Open(filename)
+	if err != nil {
+		return s3Credentials{}
+	}
+	defer fd.Close()
+	var (
+		creds   s3Credentials
+		section string
+	)
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if section != profile || len(kv) != 2 {
+			continue
+		}
+		v := strings.TrimSpace(kv[1])
+		switch strings.TrimSpace(kv[0]) {
+		case "aws_access_key_id":
+			creds.accessKey = v
+		case "aws_secret_access_key":
+			creds.secretKey = v
+		case "aws_session_token":
+			creds.sessionToken = v
+		}
+	}
+	return creds
+}
+
+// s3Client talks to an S3 compatible object storage, like AWS S3, or Google
+// Cloud Storage with HMAC keys.
+type s3Client struct {
+	bucket   string
+	region   string
+	endpoint string
+	creds    s3Credentials
+	client   http.Client
+	now      func() time.Time
+}
+
+func newS3Client(sc *SourceConfig) *s3Client {
+	region := sc.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	return &s3Client{
+		bucket:   sc.Bucket,
+		region:   region,
+		endpoint: strings.TrimSuffix(sc.Endpoint, "/"),
+		creds:    loadS3Credentials(),
+		client:   http.Client{Timeout: time.Minute},
+		now:      time.Now,
+	}
+}
+
+// objectURL returns the URL of the given key, or of the bucket for an empty
+// key. AWS uses virtual-hosted-style URLs, other endpoints path-style URLs.
+func (c *s3Client) objectURL(key string, query url.Values) *url.URL {
+	u := &url.URL{Scheme: "https"}
+	if c.endpoint == "" {
+		u.Host = fmt.Sprintf("%s.s3.%s.amazonaws.com", c.bucket, c.region)
+		u.Path = "/" + key
+	} else {
+		ep, err := url.Parse(c.endpoint)
+		if err == nil && ep.Host != "" {
+			u.Scheme, u.Host = ep.Scheme, ep.Host
+		} else {
+			u.Host = c.endpoint
+		}
+		u.Path = "/" + c.bucket + "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	u.RawQuery = s3CanonicalQuery(query)
+	return u
+}
+
+// s3Escape escapes a string as required by AWS Signature Version 4.
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = s3Escape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign adds an AWS Signature Version 4 to a request with an empty body.
+func (c *s3Client) sign(req *http.Request) {
+	if c.creds.accessKey == "" {
+		return
+	}
+	now := c.now().UTC()
+	amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", emptySHA256)
+	if c.creds.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.creds.sessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(req.Header.Get(k))
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptySHA256,
+	}, "\n")
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crHash[:])
+	key := hmacSHA256([]byte("AWS4"+c.creds.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.creds.accessKey, scope, signedHeaders, signature))
+}
+
+func (c *s3Client) get(key string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, c.objectURL(key, query).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// s3Entry is an object of a bucket listing.
+type s3Entry struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type s3ListResult struct {
+	Contents              []s3Entry `xml:"Contents"`
+	IsTruncated           bool      `xml:"IsTruncated"`
+	NextContinuationToken string    `xml:"NextContinuationToken"`
+}
+
+// list returns the pictures under prefix, following the pagination of the
+// listing.
+func (c *s3Client) list(prefix string) ([]s3Entry, error) {
+	var (
+		entries []s3Entry
+		token   string
+	)
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.get("", query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bucket '%s': %w", c.bucket, err)
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode listing of bucket '%s': %w", c.bucket, err)
+		}
+		for _, obj := range result.Contents {
+			if hasSupportedExtension(obj.Key) {
+				entries = append(entries, obj)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return entries, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// download saves the object at key to filename.
+func (c *s3Client) download(key, filename string) error {
+	resp, err := c.get(key, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") && !strings.HasPrefix(ct, "video/") {
+		return fmt.Errorf("not an image, content type is '%s'", ct)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// s3CacheName returns the name of the cached copy of the object at key. It is
+// a hash of the whole key, as objects under different prefixes can have the
+// same base name, with the extension of the key.
+func s3CacheName(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:]) + strings.ToLower(path.Ext(key))
+}
+
+// s3ListingTTL is how long the listing of a bucket is reused before the
+// bucket is listed again.
+var s3ListingTTL = 10 * time.Minute
+
+// s3Object is an object of a bucket offered as a candidate, at the local
+// path it is downloaded to once picked.
+type s3Object struct {
+	source   SourceConfig
+	key      string
+	modified time.Time
+}
+
+type s3Listing struct {
+	listed   time.Time
+	pictures []string
+}
+
+// s3Listings caches the listings of the buckets, by download directory, and
+// the objects of those listings, by local path.
+var s3Listings = struct {
+	sync.Mutex
+	listings map[string]s3Listing
+	objects  map[string]s3Object
+}{listings: make(map[string]s3Listing), objects: make(map[string]s3Object)}
+
+// resetS3Listings forgets the listings of the buckets.
+func resetS3Listings() {
+	s3Listings.Lock()
+	s3Listings.listings = make(map[string]s3Listing)
+	s3Listings.objects = make(map[string]s3Object)
+	s3Listings.Unlock()
+}
+
+// s3Candidates returns the pictures of the bucket, as the sorted local paths
+// they are downloaded to by fetchRemote once picked. The listing is reused
+// for s3ListingTTL, and when the bucket cannot be listed the previous
+// listing, or else the pictures downloaded before, are returned instead.
+func s3Candidates(cfg *Config, sc *SourceConfig) ([]string, error) {
+	sum := sha1.Sum([]byte(sc.Endpoint + "/" + sc.Bucket + "/" + sc.Prefix))
+	dir := path.Join(configdir.LocalCache(Progname), "s3", hex.EncodeToString(sum[:8]))
+	if err := makeDirs(dir); err != nil {
+		return nil, fmt.Errorf("failed to create download directory '%s': %w", dir, err)
+	}
+	s3Listings.Lock()
+	previous, listed := s3Listings.listings[dir]
+	s3Listings.Unlock()
+	if listed && time.Since(previous.listed) < s3ListingTTL {
+		return append([]string(nil), previous.pictures...), nil
+	}
+	entries, err := newS3Client(sc).list(sc.Prefix)
+	if err != nil {
+		if listed {
+			log.Printf("Warning: using the previous listing of %s: %v", sc.String(), err)
+			return append([]string(nil), previous.pictures...), nil
+		}
+		log.Printf("Warning: using the pictures downloaded before from %s: %v", sc.String(), err)
+		return ListCandidates(dir, cfg.MaxCandidates)
+	}
+	pictures := make([]string, 0, len(entries))
+	s3Listings.Lock()
+	for _, e := range entries {
+		local := path.Join(dir, s3CacheName(e.Key))
+		s3Listings.objects[local] = s3Object{source: *sc, key: e.Key, modified: e.LastModified}
+		pictures = append(pictures, local)
+	}
+	sort.Strings(pictures)
+	s3Listings.listings[dir] = s3Listing{listed: time.Now(), pictures: pictures}
+	s3Listings.Unlock()
+	return append([]string(nil), pictures...), nil
+}
+
+// fetchRemote downloads filename if it is an object of a bucket that is not
+// downloaded yet, with the modification time of the object. Other pictures
+// are left as they are.
+func fetchRemote(filename string) error {
+	s3Listings.Lock()
+	obj, ok := s3Listings.objects[filename]
+	s3Listings.Unlock()
+	if !ok {
+		return nil
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+	if err := newS3Client(&obj.source).download(obj.key, filename); err != nil {
+		return fmt.Errorf("cannot download '%s' from %s: %w", obj.key, obj.source.String(), err)
+	}
+	if !obj.modified.IsZero() {
+		if err := os.Chtimes(filename, obj.modified, obj.modified); err != nil {
+			debugf("cannot set the modification time of '%s': %v", filename, err)
+		}
+	}
+	return nil
+}
+
+// s3ModTime returns the modification time of the bucket object downloaded to
+// filename, if any.
+func s3ModTime(filename string) (time.Time, bool) {
+	s3Listings.Lock()
+	defer s3Listings.Unlock()
+	obj, ok := s3Listings.objects[filename]
+	return obj.modified, ok && !obj.modified.IsZero()
+}
@@ -0,0 +1,262 @@
+package bgchanger
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves a bucket with the given objects, by key.
+func fakeS3(t *testing.T, bucket string, objects map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+bucket+"/" && r.URL.Query().Get("list-type") == "2" {
+			var buf bytes.Buffer
+			buf.WriteString("<ListBucketResult>")
+			for key := range objects {
+				fmt.Fprintf(&buf, "<Contents><Key>%s</Key></Contents>", key)
+			}
+			buf.WriteString("<IsTruncated>false</IsTruncated></ListBucketResult>")
+			w.Write(buf.Bytes())
+			return
+		}
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, "/"+bucket+"/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+}
+
+func TestS3SameBaseName(t *testing.T) {
+	testEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	dir := t.TempDir()
+	objects := make(map[string][]byte)
+	for i, key := range []string{"a/x.png", "b/x.png"} {
+		p := writeTestPicture(t, filepath.Join(dir, fmt.Sprintf("%d.png", i)), 4, 4, color.RGBA{uint8(100 * i), 0, 0, 255})
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects[key] = data
+	}
+	server := fakeS3(t, "pictures", objects)
+	defer server.Close()
+	cfg := testConfig(t, `{"pictures_dir": "/p"}`)
+	sc := &SourceConfig{Type: sourceS3, Bucket: "pictures", Endpoint: server.URL}
+
+	pictures, err := s3Candidates(cfg, sc)
+	if err != nil || len(pictures) != 2 || pictures[0] == pictures[1] {
+		t.Fatalf("got %v, %v, want a cached copy for each key", pictures, err)
+	}
+	var contents [][]byte
+	for _, p := range pictures {
+		if filepath.Ext(p) != ".png" {
+			t.Errorf("got %s, want the extension of the key", p)
+		}
+		if err := fetchRemote(p); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, data)
+	}
+	if bytes.Equal(contents[0], contents[1]) {
+		t.Error("both keys are served from the same cached copy")
+	}
+}
+
+func TestS3ListPagination(t *testing.T) {
+	testEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+	var prefixes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		prefixes = append(prefixes, q.Get("prefix"))
+		switch q.Get("continuation-token") {
+		case "":
+			fmt.Fprint(w, `<ListBucketResult><Contents><Key>walls/a.jpg</Key></Contents><Contents><Key>walls/notes.txt</Key></Contents>`+
+				`<IsTruncated>true</IsTruncated><NextContinuationToken>page 2</NextContinuationToken></ListBucketResult>`)
+		case "page 2":
+			fmt.Fprint(w, `<ListBucketResult><Contents><Key>walls/b.png</Key></Contents><IsTruncated>false</IsTruncated></ListBucketResult>`)
+		default:
+			http.Error(w, "bad token", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	c := newS3Client(&SourceConfig{Type: sourceS3, Bucket: "pictures", Prefix: "walls/", Endpoint: server.URL})
+	entries, err := c.list("walls/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	if strings.Join(keys, ",") != "walls/a.jpg,walls/b.png" {
+		t.Errorf("got keys %v, want the pictures of both pages", keys)
+	}
+	if strings.Join(prefixes, ",") != "walls/,walls/" {
+		t.Errorf("got prefixes %v", prefixes)
+	}
+}
+
+func TestS3OfflineAndContentType(t *testing.T) {
+	testEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+	data, err := os.ReadFile(writeTestPictures(t, t.TempDir(), 1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := fakeS3(t, "pictures", map[string][]byte{"a.png": data})
+	cfg := testConfig(t, `{"pictures_dir": "/p"}`)
+	sc := &SourceConfig{Type: sourceS3, Bucket: "pictures", Endpoint: server.URL}
+	online, err := s3Candidates(cfg, sc)
+	if err != nil || len(online) != 1 {
+		t.Fatalf("got %v, %v, want the picture of the bucket", online, err)
+	}
+	if err := fetchRemote(online[0]); err != nil {
+		t.Fatal(err)
+	}
+	// the bucket cannot be reached, and its listing is forgotten
+	server.Close()
+	resetS3Listings()
+	if offline, err := s3Candidates(cfg, sc); err != nil || len(offline) != 1 || offline[0] != online[0] {
+		t.Errorf("got %v, %v offline, want the cached %v", offline, err, online)
+	}
+
+	html := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html>login</html>")
+	}))
+	defer html.Close()
+	c := newS3Client(&SourceConfig{Type: sourceS3, Bucket: "pictures", Endpoint: html.URL})
+	if err := c.download("a.png", filepath.Join(t.TempDir(), "a.png")); err == nil {
+		t.Error("downloaded a HTML page as a picture")
+	}
+}
+
+func TestS3Credentials(t *testing.T) {
+	credentials := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentials, []byte("[default]\naws_access_key_id = DEFAULT\naws_secret_access_key = x\n"+
+		"[team]\naws_access_key_id = TEAM\naws_secret_access_key = y\naws_session_token = z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentials)
+	t.Setenv("AWS_PROFILE", "team")
+	if creds := loadS3Credentials(); creds != (s3Credentials{"TEAM", "y", "z"}) {
+		t.Errorf("got %+v from the team profile", creds)
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	if creds := loadS3Credentials(); creds != (s3Credentials{"ENV", "secret", ""}) {
+		t.Errorf("got %+v, want the environment credentials first", creds)
+	}
+
+	// requests are signed with the credentials
+	var auth, date string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, date = r.Header.Get("Authorization"), r.Header.Get("x-amz-date")
+		fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated></ListBucketResult>`)
+	}))
+	defer server.Close()
+	c := newS3Client(&SourceConfig{Type: sourceS3, Bucket: "pictures", Region: "eu-west-1", Endpoint: server.URL})
+	c.now = func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) }
+	if _, err := c.list(""); err != nil {
+		t.Fatal(err)
+	}
+	if date != "20240506T070809Z" || !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=ENV/20240506/eu-west-1/s3/aws4_request, SignedHeaders=") {
+		t.Errorf("got Authorization %q and x-amz-date %q", auth, date)
+	}
+}
+
+func TestS3Listing(t *testing.T) {
+	setter := testEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "none"))
+	data, err := os.ReadFile(writeTestPictures(t, t.TempDir(), 1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		listings  int
+		downloads []string
+	)
+	modified := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("list-type") == "2" {
+			listings++
+			fmt.Fprint(w, `<ListBucketResult>`)
+			for _, key := range []string{"a.png", "b.png", "c.png"} {
+				fmt.Fprintf(w, `<Contents><Key>%s</Key><LastModified>%s</LastModified></Contents>`, key, modified.Format(time.RFC3339))
+			}
+			fmt.Fprint(w, `<IsTruncated>false</IsTruncated></ListBucketResult>`)
+			return
+		}
+		downloads = append(downloads, strings.TrimPrefix(r.URL.Path, "/pictures/"))
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := testConfig(t, `{"pictures_dir": %q, "sources": [{"type": "s3", "bucket": "pictures", "endpoint": %q}]}`, dir, server.URL)
+
+	// every object is a candidate, with a stable index, and nothing is
+	// downloaded to list them
+	for i := 0; i < 3; i++ {
+		pictures, err := indexedCandidates(cfg)
+		if err != nil || len(pictures) != 3 || !sort.StringsAreSorted(pictures) {
+			t.Fatalf("got %v, %v, want the 3 objects of the bucket", pictures, err)
+		}
+	}
+	if listings != 1 || len(downloads) != 0 {
+		t.Errorf("got %d listings and downloads %v, want a single listing and no downloads", listings, downloads)
+	}
+
+	// only the picked object is downloaded, with its modification time
+	ChangeBG(cfg)
+	current := CurrentPicture()
+	if len(downloads) != 1 || setter.get("picture-uri") != "file://"+current {
+		t.Fatalf("got downloads %v and picture-uri %s, want only the picked %s", downloads, setter.get("picture-uri"), current)
+	}
+	if st, err := os.Stat(current); err != nil || !st.ModTime().Equal(modified) {
+		t.Errorf("got %v, %v, want the modification time of the object", st, err)
+	}
+
+	// the listing is done again once it expires
+	old := s3ListingTTL
+	s3ListingTTL = 0
+	t.Cleanup(func() { s3ListingTTL = old })
+	if _, err := indexedCandidates(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if listings != 2 {
+		t.Errorf("got %d listings after the TTL, want 2", listings)
+	}
+	// and the previous one is used if the bucket cannot be reached
+	server.Close()
+	if pictures, err := indexedCandidates(cfg); err != nil || len(pictures) != 3 {
+		t.Errorf("got %v, %v offline, want the previous listing", pictures, err)
+	}
+}
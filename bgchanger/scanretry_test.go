@@ -0,0 +1,50 @@
+package bgchanger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScanRetries(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	calls := 0
+	failures := 2
+	sourceCandidates = func(sc *SourceConfig, cfg *Config) ([]string, error) {
+		calls++
+		if calls <= failures {
+			return nil, errors.New("transport endpoint is not connected")
+		}
+		return sc.candidates(cfg)
+	}
+	defer func() { sourceCandidates = (*SourceConfig).candidates }()
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "scan_retries": 2, "scan_retry_delay": "1ms"}`, dir)
+	ChangeBG(cfg)
+	if cur := CurrentPicture(); cur != pictures[0] && cur != pictures[1] {
+		t.Errorf("got %q after two failed scans, want a picture", cur)
+	}
+	if calls != 3 {
+		t.Errorf("scanned %d times, want 3", calls)
+	}
+
+	// giving up after the retries
+	calls, failures = 0, 10
+	if _, err := PickPicture(cfg); err == nil {
+		t.Error("picked a picture from a failing source")
+	}
+	if calls != 3 {
+		t.Errorf("scanned %d times, want 3", calls)
+	}
+
+	// an empty directory is not retried
+	calls, failures = 0, 0
+	cfg = testConfig(t, `{"pictures_dir": %q, "scan_retries": 2, "scan_retry_delay": "1ms"}`, t.TempDir())
+	if _, err := PickPicture(cfg); err == nil {
+		t.Error("picked a picture from an empty directory")
+	}
+	if calls != 1 {
+		t.Errorf("scanned an empty directory %d times, want once", calls)
+	}
+}
@@ -0,0 +1,126 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// colorSchemePollInterval is how often the color scheme is checked for
+// changes when follow_color_scheme is set.
+const colorSchemePollInterval = 5 * time.Second
+
+// colorSchemeDark is the value of org.gnome.desktop.interface color-scheme in
+// dark mode.
+const colorSchemeDark = "prefer-dark"
+
+// darkScheme returns whether the desktop is in dark mode.
+func darkScheme() (bool, error) {
+	out, err := gsettingsGet(interfaceSchema, "color-scheme")
+	if err != nil {
+		return false, fmt.Errorf("failed to get color scheme: %w", err)
+	}
+	return strings.Trim(out, "'") == colorSchemeDark, nil
+}
+
+// colorSchemeChanges is notified when the desktop switches between light and
+// dark mode.
+var colorSchemeChanges = make(chan struct{}, 1)
+
+// ColorSchemeChanges returns a channel that receives a value when the desktop
+// switches between light and dark mode, at which point
+// ApplyColorSchemeChange should be called.
+func ColorSchemeChanges() <-chan struct{} {
+	return colorSchemeChanges
+}
+
+// StartColorSchemeWatcher watches the color scheme if follow_color_scheme is
+// set, so that the background changes when GNOME switches between light and
+// dark mode, including when it does so on a schedule.
+func StartColorSchemeWatcher(cfg *Config) {
+	if !cfg.FollowColorScheme {
+		return
+	}
+	last, err := darkScheme()
+	if err != nil {
+		log.Printf("Warning: not following the color scheme: %v", err)
+		return
+	}
+	log.Printf("Watching the color scheme for follow_color_scheme")
+	go func() {
+		for range time.Tick(colorSchemePollInterval) {
+			dark, err := darkScheme()
+			if err != nil {
+				debugf("cannot check the color scheme: %v", err)
+				continue
+			}
+			if dark == last {
+				continue
+			}
+			last = dark
+			select {
+			case colorSchemeChanges <- struct{}{}:
+			default:
+			}
+		}
+	}()
+}
+
+// flip is the switch between light and dark mode that ApplyColorSchemeChange
+// is changing the background for, with the picture shown before it, to fade
+// from with the fade transition.
+var flip = struct {
+	sync.Mutex
+	active bool
+	dark   bool
+	from   string
+}{}
+
+// flipFade returns whether a switch between light and dark mode is being
+// applied and, if it is to dark mode or not as given, the picture shown
+// before it.
+func flipFade(dark bool) (string, bool) {
+	flip.Lock()
+	defer flip.Unlock()
+	if !flip.active || flip.dark != dark {
+		return "", flip.active
+	}
+	return flip.from, true
+}
+
+// ApplyColorSchemeChange changes the background after a switch between light
+// and dark mode, which also picks a new picture-uri-dark from
+// dark_pictures_dir or with differ_by_theme, so that the picture shown for
+// the new scheme is a fresh one. With the fade transition, it fades from the
+// picture shown before the switch to the new one. Like the automatic changes,
+// it leaves the background as it is when they are not allowed, e.g. during
+// quiet hours or while paused.
+func ApplyColorSchemeChange(cfg *Config) {
+	dark, err := darkScheme()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	// the picture of the scheme the desktop switched from
+	var from string
+	if dark {
+		log.Printf("Color scheme changed to dark")
+		from = gsettingsPicture("picture-uri")
+	} else {
+		log.Printf("Color scheme changed to light")
+		from = gsettingsPicture("picture-uri-dark")
+	}
+	if !changeAllowed(cfg) {
+		return
+	}
+	flip.Lock()
+	flip.active, flip.dark, flip.from = true, dark, from
+	flip.Unlock()
+	defer func() {
+		flip.Lock()
+		flip.active, flip.from = false, ""
+		flip.Unlock()
+	}()
+	ChangeBG(cfg)
+}
@@ -0,0 +1,141 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyColorSchemeChange(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	light := writeTestPictures(t, filepath.Join(dir, "light"), 2)
+	dark := writeTestPictures(t, filepath.Join(dir, "dark"), 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q, "follow_color_scheme": true}`,
+		filepath.Join(dir, "light"), filepath.Join(dir, "dark"))
+	for i, scheme := range []string{"'prefer-dark'", "'default'", "'prefer-dark'"} {
+		setter.preset(interfaceSchema, "color-scheme", scheme)
+		ApplyColorSchemeChange(cfg)
+		uris, darkURIs := setter.values("picture-uri"), setter.values("picture-uri-dark")
+		if len(uris) != i+1 || len(darkURIs) != i+1 {
+			t.Fatalf("%s: got picture-uri %v and picture-uri-dark %v, want a new pick for each switch", scheme, uris, darkURIs)
+		}
+		if p := strings.TrimPrefix(uris[i], "file://"); !contains(light, p) {
+			t.Errorf("%s: got picture-uri %s, want one of pictures_dir", scheme, p)
+		}
+		if p := strings.TrimPrefix(darkURIs[i], "file://"); !contains(dark, p) {
+			t.Errorf("%s: got picture-uri-dark %s, want one of dark_pictures_dir", scheme, p)
+		}
+	}
+}
+
+func TestApplyColorSchemeChangeIsGated(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	setter.preset(interfaceSchema, "color-scheme", "'prefer-dark'")
+	cfg := testConfig(t, `{"pictures_dir": %q, "differ_by_theme": true, "follow_color_scheme": true, "manual_hold": "1h"}`, dir)
+	setPaused(true)
+	ApplyColorSchemeChange(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v while paused", setter.sets)
+	}
+	setPaused(false)
+	startManualHold(cfg)
+	ApplyColorSchemeChange(cfg)
+	if len(setter.sets) != 0 {
+		t.Errorf("got sets %v while held", setter.sets)
+	}
+	if from, active := flipFade(true); active || from != "" {
+		t.Errorf("got flip %q, %v after a skipped switch", from, active)
+	}
+}
+
+func TestFollowColorSchemeValidate(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "follow_color_scheme": true}`)); err == nil {
+		t.Error("expected an error without a dark mode picture option")
+	}
+}
+
+// fadeSteps returns the fade frames set in the key before its last value,
+// failing unless each is brighter than the previous one, or darker.
+func fadeSteps(t *testing.T, setter *testSetter, key string, brighter bool) []string {
+	t.Helper()
+	uris := setter.values(key)
+	var frames []string
+	prev := -1.0
+	for _, u := range uris[:len(uris)-1] {
+		f := strings.TrimPrefix(u, "file://")
+		if !strings.HasPrefix(filepath.Base(f), "fade-") {
+			continue
+		}
+		c, err := averageColor(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := luminance(c)
+		if prev >= 0 && (l > prev) != brighter {
+			t.Errorf("%s: frame %s has luminance %.2f after %.2f", key, f, l, prev)
+		}
+		prev = l
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+func TestColorSchemeFlipFades(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	light := writeTestPicture(t, filepath.Join(dir, "light", "a.png"), 16, 9, color.RGBA{R: 240, G: 240, B: 240, A: 255})
+	dark := writeTestPicture(t, filepath.Join(dir, "dark", "a.png"), 16, 9, color.RGBA{R: 10, G: 10, B: 10, A: 255})
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q, "follow_color_scheme": true,
+		"transition": "fade", "transition_frames": 3, "transition_duration": "40ms"}`,
+		filepath.Join(dir, "light"), filepath.Join(dir, "dark"))
+
+	// the light picture is shown, and the desktop switches to dark mode
+	setter.preset(backgroundSchema, "picture-uri", "'file://"+light+"'")
+	setter.preset(backgroundSchema, "picture-uri-dark", "'file://"+light+"'")
+	setter.preset(interfaceSchema, "color-scheme", "'prefer-dark'")
+	ApplyColorSchemeChange(cfg)
+	darkURIs := setter.values("picture-uri-dark")
+	if len(darkURIs) == 0 || darkURIs[len(darkURIs)-1] != "file://"+dark {
+		t.Fatalf("got picture-uri-dark %v, want it to end with %s", darkURIs, dark)
+	}
+	if frames := fadeSteps(t, setter, "picture-uri-dark", false); len(frames) != 3 {
+		t.Errorf("got %d frames fading to the dark picture, want 3", len(frames))
+	}
+	// the light mode picture doesn't fade, it is not shown
+	if frames := fadeSteps(t, setter, "picture-uri", true); len(frames) != 0 {
+		t.Errorf("got %d frames in picture-uri while switching to dark mode", len(frames))
+	}
+
+	// and back to light mode, from the dark picture
+	setter.sets = nil
+	setter.preset(backgroundSchema, "picture-uri", "'file://"+light+"'")
+	setter.preset(backgroundSchema, "picture-uri-dark", "'file://"+dark+"'")
+	setter.preset(interfaceSchema, "color-scheme", "'default'")
+	ApplyColorSchemeChange(cfg)
+	uris := setter.values("picture-uri")
+	if len(uris) == 0 || uris[len(uris)-1] != "file://"+light {
+		t.Fatalf("got picture-uri %v, want it to end with %s", uris, light)
+	}
+	if frames := fadeSteps(t, setter, "picture-uri", true); len(frames) != 3 {
+		t.Errorf("got %d frames fading to the light picture, want 3", len(frames))
+	}
+}
+
+func TestColorSchemeFlipWithoutFade(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	light := writeTestPicture(t, filepath.Join(dir, "light", "a.png"), 16, 9, color.RGBA{R: 240, G: 240, B: 240, A: 255})
+	writeTestPicture(t, filepath.Join(dir, "dark", "a.png"), 16, 9, color.RGBA{R: 10, G: 10, B: 10, A: 255})
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q, "follow_color_scheme": true}`,
+		filepath.Join(dir, "light"), filepath.Join(dir, "dark"))
+	setter.preset(backgroundSchema, "picture-uri", "'file://"+light+"'")
+	setter.preset(interfaceSchema, "color-scheme", "'prefer-dark'")
+	ApplyColorSchemeChange(cfg)
+	if darkURIs := setter.values("picture-uri-dark"); len(darkURIs) != 1 {
+		t.Errorf("got picture-uri-dark %v, want a single switch without transition", darkURIs)
+	}
+}
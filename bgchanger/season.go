@@ -0,0 +1,98 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const modeSeasonal = "seasonal"
+
+// hemispheres, for the seasonal mode
+const (
+	hemisphereNorth = "north"
+	hemisphereSouth = "south"
+)
+
+func validateHemisphere(h string) error {
+	switch h {
+	case "", hemisphereNorth, hemisphereSouth:
+		return nil
+	}
+	return fmt.Errorf("unknown hemisphere '%s', want north or south", h)
+}
+
+// season returns the meteorological season of t in the given hemisphere:
+// spring, summer, autumn or winter, each starting on the first of March,
+// June, September and December in the northern hemisphere.
+func season(t time.Time, hemisphere string) string {
+	seasons := []string{"winter", "spring", "summer", "autumn"}
+	i := int(t.Month()) % 12 / 3
+	if hemisphere == hemisphereSouth {
+		i = (i + 2) % 4
+	}
+	return seasons[i]
+}
+
+// inSeason reports whether the picture is in a directory named after the
+// season, or has the season among its tags. "fall" is the same as autumn.
+func inSeason(filename, s string) bool {
+	if dir := strings.ToLower(filepath.Base(filepath.Dir(filename))); dir == s || (s == "autumn" && dir == "fall") {
+		return true
+	}
+	for _, tag := range readSidecar(filename).Tags {
+		if tag == s || (s == "autumn" && tag == "fall") {
+			return true
+		}
+	}
+	return false
+}
+
+// seasonalCandidates returns the pictures in the subdirectory of a directory
+// source named after the current season, if there is one, "fall" being the
+// same as autumn like in inSeason.
+func seasonalCandidates(cfg *Config, src *SourceConfig, now time.Time) []string {
+	if src.Type != "" && src.Type != sourceDir {
+		return nil
+	}
+	s := season(now, cfg.Hemisphere)
+	names := []string{s}
+	if s == "autumn" {
+		names = append(names, "fall")
+	}
+	for _, name := range names {
+		for _, name := range []string{name, strings.ToUpper(name[:1]) + name[1:]} {
+			dir := filepath.Join(src.Path, name)
+			if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+				continue
+			}
+			pictures, err := cachedCandidates(dir, cfg.MaxCandidates)
+			if err != nil {
+				log.Printf("Warning: cannot read the %s pictures: %v", s, err)
+				return nil
+			}
+			return pictures
+		}
+	}
+	return nil
+}
+
+// filterBySeason returns the pictures for the current season, or all of them
+// if there are none.
+func filterBySeason(cfg *Config, pictures []string, now time.Time) []string {
+	s := season(now, cfg.Hemisphere)
+	var matching []string
+	for _, p := range pictures {
+		if inSeason(p, s) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		log.Printf("No pictures for %s, using any picture", s)
+		return pictures
+	}
+	return matching
+}
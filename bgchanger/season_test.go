@@ -0,0 +1,106 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeason(t *testing.T) {
+	for _, tc := range []struct {
+		month time.Month
+		north string
+		south string
+	}{
+		{time.January, "winter", "summer"},
+		{time.March, "spring", "autumn"},
+		{time.May, "spring", "autumn"},
+		{time.June, "summer", "winter"},
+		{time.September, "autumn", "spring"},
+		{time.November, "autumn", "spring"},
+		{time.December, "winter", "summer"},
+	} {
+		d := time.Date(2024, tc.month, 15, 12, 0, 0, 0, time.Local)
+		if got := season(d, hemisphereNorth); got != tc.north {
+			t.Errorf("%s in the north: got %s, want %s", tc.month, got, tc.north)
+		}
+		if got := season(d, ""); got != tc.north {
+			t.Errorf("%s by default: got %s, want %s", tc.month, got, tc.north)
+		}
+		if got := season(d, hemisphereSouth); got != tc.south {
+			t.Errorf("%s in the south: got %s, want %s", tc.month, got, tc.south)
+		}
+	}
+}
+
+func TestSeasonalCandidates(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	any := writeTestPictures(t, dir, 1)
+	summer := writeTestPictures(t, filepath.Join(dir, "summer"), 1)
+	fall := writeTestPictures(t, filepath.Join(dir, "Fall"), 1)
+	// tagged in a sidecar rather than in a subdirectory
+	winter := filepath.Join(dir, "snow.png")
+	if err := os.Rename(writeTestPictures(t, filepath.Join(t.TempDir()), 1)[0], winter); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(winter+sidecarSuffix, []byte("snow, Winter\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	summerDir := filepath.Join(t.TempDir(), "Summer")
+	summerOnly := writeTestPictures(t, summerDir, 1)
+
+	for _, tc := range []struct {
+		hemisphere string
+		month      time.Month
+		want       []string
+	}{
+		{"north", time.July, summer},
+		{"south", time.January, summer},
+		{"north", time.October, fall},
+		{"south", time.April, fall},
+		{"north", time.February, []string{winter}},
+		{"south", time.August, []string{winter}},
+		// no spring pictures, anything goes
+		{"north", time.April, append([]string{winter}, any...)},
+	} {
+		now := time.Date(2024, tc.month, 10, 12, 0, 0, 0, time.Local)
+		cfg := testConfig(t, `{"pictures_dir": %q, "mode": "seasonal", "hemisphere": %q}`, dir, tc.hemisphere)
+		src := configuredSources(cfg)[0]
+		// what scanSource and pickFrom do, on the given date
+		pictures, err := cachedCandidates(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pictures = append(pictures, seasonalCandidates(cfg, &src, now)...)
+		pictures = filterCandidates(cfg, pictures, nil, now)
+		sort.Strings(pictures)
+		want := append([]string(nil), tc.want...)
+		sort.Strings(want)
+		if strings.Join(pictures, " ") != strings.Join(want, " ") {
+			t.Errorf("%s in %s in the %s: got %v, want %v", season(now, tc.hemisphere), tc.month, tc.hemisphere, pictures, want)
+		}
+	}
+
+	// only the subdirectory of the current season is scanned
+	cfg := testConfig(t, `{"pictures_dir": %q, "mode": "seasonal"}`, filepath.Dir(summerDir))
+	src := configuredSources(cfg)[0]
+	if got := seasonalCandidates(cfg, &src, time.Date(2024, time.July, 1, 0, 0, 0, 0, time.Local)); len(got) != 1 || got[0] != summerOnly[0] {
+		t.Errorf("got %v, want the pictures of the Summer subdirectory", got)
+	}
+	if got := seasonalCandidates(cfg, &src, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.Local)); len(got) != 0 {
+		t.Errorf("got %v in winter", got)
+	}
+}
+
+func TestSeasonalValidate(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "mode": "seasonal", "hemisphere": "east"}`)); err == nil {
+		t.Error("expected an error for an unknown hemisphere")
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "mode": "seasonal", "hemisphere": "south"}`)); err != nil {
+		t.Error(err)
+	}
+}
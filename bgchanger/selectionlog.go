@@ -0,0 +1,82 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSelectionLogSize is the size after which the selection log is rotated
+// to a .1 file, overwriting the previous one.
+const maxSelectionLogSize = 1 << 20
+
+// selectionLogQueue holds the entries waiting to be written, so that changes
+// never wait for the disk.
+var (
+	selectionLogQueue = make(chan selectionLogEntry, 64)
+	selectionLogOnce  sync.Once
+)
+
+type selectionLogEntry struct {
+	filename string
+	line     string
+}
+
+// logSelection queues an entry for the selection log, if configured. If the
+// queue is full, the entry is dropped.
+func logSelection(cfg *Config, picture string, candidates int, src *SourceConfig) {
+	if cfg.SelectionLog == "" {
+		return
+	}
+	selectionLogOnce.Do(func() { go writeSelectionLog() })
+	mode := cfg.Mode
+	if mode == "" {
+		mode = modeRandom
+	}
+	filters := []string{"mode=" + mode}
+	if cfg.Dedup {
+		filters = append(filters, "dedup")
+	}
+	if cfg.MaxCandidates > 0 {
+		filters = append(filters, fmt.Sprintf("max_candidates=%d", cfg.MaxCandidates))
+	}
+	line := fmt.Sprintf("%s\t%s\tcandidates=%d\tsource=%s\t%s\n",
+		time.Now().Format(time.RFC3339), picture, candidates, src.String(), strings.Join(filters, ","))
+	select {
+	case selectionLogQueue <- selectionLogEntry{filename: cfg.SelectionLog, line: line}:
+	default:
+		debugf("selection log queue is full, dropping entry")
+	}
+}
+
+// writeSelectionLog writes the queued selection log entries.
+func writeSelectionLog() {
+	for e := range selectionLogQueue {
+		if err := appendSelectionLog(e.filename, e.line); err != nil {
+			log.Printf("Warning: cannot write selection log: %v", err)
+		}
+	}
+}
+
+func appendSelectionLog(filename, line string) error {
+	if err := checkWrite(filename); err != nil {
+		return err
+	}
+	if st, err := os.Stat(filename); err == nil && st.Size() >= maxSelectionLogSize {
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			return fmt.Errorf("failed to rotate: %w", err)
+		}
+	}
+	fd, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.WriteString(line); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
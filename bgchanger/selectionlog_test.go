@@ -0,0 +1,55 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectionLog(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	logFile := filepath.Join(t.TempDir(), "selection.log")
+	cfg := testConfig(t, `{"pictures_dir": %q, "selection_log": %q, "dedup": true}`, dir, logFile)
+	ChangeBG(cfg)
+
+	// entries are written asynchronously
+	var data []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, _ = os.ReadFile(logFile); len(data) > 0 {
+			break
+		}
+	}
+	entry := regexp.MustCompile(`^(\S+)\t(\S+)\tcandidates=3\tsource=` + regexp.QuoteMeta(dir) + `\tmode=random,dedup\n$`)
+	m := entry.FindStringSubmatch(string(data))
+	if m == nil {
+		t.Fatalf("got selection log %q, want one entry", data)
+	}
+	if _, err := time.Parse(time.RFC3339, m[1]); err != nil {
+		t.Errorf("invalid timestamp: %v", err)
+	}
+	if current := getStatus().Current; m[2] != current {
+		t.Errorf("logged %s, want the applied %s", m[2], current)
+	}
+}
+
+func TestSelectionLogRotation(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "selection.log")
+	full := strings.Repeat("x", maxSelectionLogSize)
+	if err := os.WriteFile(logFile, []byte(full), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendSelectionLog(logFile, "entry\n"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(logFile); err != nil || string(data) != "entry\n" {
+		t.Errorf("got %q, %v after rotation, want only the new entry", data, err)
+	}
+	if st, err := os.Stat(logFile + ".1"); err != nil || st.Size() != int64(len(full)) {
+		t.Errorf("the full log was not rotated: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package bgchanger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kirsle/configdir"
+)
+
+// recordingSetter is a BackgroundSetter that only records the settings, for
+// the self-test.
+type recordingSetter struct {
+	sync.Mutex
+	settings map[string]string
+}
+
+// Set implements BackgroundSetter.
+func (rs *recordingSetter) Set(schema, key, value string) error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.settings[schema+" "+key] = value
+	return nil
+}
+
+// Get implements BackgroundSetter, returning the recorded settings.
+func (rs *recordingSetter) Get(schema, key string) (string, error) {
+	rs.Lock()
+	defer rs.Unlock()
+	value, ok := rs.settings[schema+" "+key]
+	if !ok {
+		return "", fmt.Errorf("%s %s was not set during the self-test", schema, key)
+	}
+	return value, nil
+}
+
+func (rs *recordingSetter) get(schema, key string) string {
+	rs.Lock()
+	defer rs.Unlock()
+	return rs.settings[schema+" "+key]
+}
+
+// errNoDesktop is returned by the external commands during the self-test.
+var errNoDesktop = errors.New("no desktop during the self-test")
+
+// SelfTest runs the scan, filter, pick and set steps on generated pictures in
+// a temporary directory, without a desktop: the settings are recorded rather
+// than applied, the external commands fail, and the config and cache
+// directories are temporary too. It returns an error describing the first
+// step that didn't do what it should.
+func SelfTest() error {
+	tmp, err := os.MkdirTemp("", Progname+"-selftest")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(tmp, "cache"))
+	configdir.Refresh()
+	setter := &recordingSetter{settings: make(map[string]string)}
+	Setter = setter
+	runCommand = func(name string, args ...string) error { return errNoDesktop }
+	commandOutput = func(name string, args ...string) ([]byte, error) { return nil, errNoDesktop }
+
+	dir := filepath.Join(tmp, "pictures")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create pictures directory: %w", err)
+	}
+	var want []string
+	for i, c := range []color.RGBA{{R: 200, A: 255}, {G: 200, A: 255}, {B: 200, A: 255}} {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 36))
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = c.R, c.G, c.B, c.A
+		}
+		p := filepath.Join(dir, fmt.Sprintf("picture%d.png", i))
+		if err := writePNG(p, img); err != nil {
+			return fmt.Errorf("failed to write test picture: %w", err)
+		}
+		want = append(want, p)
+	}
+	// files that must not become candidates
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a picture"), 0o644); err != nil {
+		return fmt.Errorf("failed to write test file: %w", err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "missing.jpg"), filepath.Join(dir, "broken.jpg")); err != nil {
+		return fmt.Errorf("failed to write test symlink: %w", err)
+	}
+	fmt.Printf("Generated %d pictures in %s\n", len(want), dir)
+
+	cfg, err := ParseConfig([]byte(fmt.Sprintf(`{"pictures_dir": %q}`, dir)))
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	pictures, err := ScanCandidates(cfg)
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+	if strings.Join(pictures, "\n") != strings.Join(want, "\n") {
+		return fmt.Errorf("scan: got candidates %v, want %v", pictures, want)
+	}
+	fmt.Printf("Scan: %d candidates\n", len(pictures))
+
+	picked, _, err := pickPicture(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("pick: %w", err)
+	}
+	if !contains(want, picked) {
+		return fmt.Errorf("pick: got '%s', which is not a candidate", picked)
+	}
+	fmt.Printf("Pick: %s\n", picked)
+
+	if err := changeBackground(cfg); err != nil {
+		return fmt.Errorf("set: %w", err)
+	}
+	uri := setter.get(backgroundSchema, "picture-uri")
+	if !strings.HasPrefix(uri, "file://") {
+		return fmt.Errorf("set: picture-uri is '%s', want a file URI", uri)
+	}
+	if _, err := loadImage(strings.TrimPrefix(uri, "file://")); err != nil {
+		return fmt.Errorf("set: the applied file is not a valid picture: %w", err)
+	}
+	if current := CurrentPicture(); !contains(want, current) {
+		return fmt.Errorf("set: current picture is '%s', which is not a candidate", current)
+	}
+	fmt.Printf("Set: %s\n", uri)
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package bgchanger
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	testEnv(t)
+	// SelfTest moves the config and cache directories to its own temporary
+	// directory, t.Setenv restores them
+	t.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	t.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	var err error
+	out := captureStdout(t, func() { err = SelfTest() })
+	if err != nil {
+		t.Fatalf("self-test failed: %v\n%s", err, out)
+	}
+	for _, step := range []string{"Scan: 3 candidates", "Pick: ", "Set: file://"} {
+		if !strings.Contains(out, step) {
+			t.Errorf("the output has no %q:\n%s", step, out)
+		}
+	}
+}
+
+func TestSelfTestFails(t *testing.T) {
+	testEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	t.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	// the test pictures cannot be written
+	setWriteAllowlist([]string{t.TempDir()})
+	var err error
+	captureStdout(t, func() { err = SelfTest() })
+	if err == nil || !strings.Contains(err.Error(), "test picture") {
+		t.Errorf("got %v, want an error writing the test pictures", err)
+	}
+}
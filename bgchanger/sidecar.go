@@ -0,0 +1,54 @@
+package bgchanger
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// sidecarSuffix is appended to the name of a picture to get its sidecar
+// file. The sidecar lists tags separated by spaces, commas or newlines, and
+// "name: value" lines for the settings of the picture.
+const sidecarSuffix = ".tags"
+
+// sidecar is the metadata of a picture from its sidecar file.
+type sidecar struct {
+	// Tags are lowercase.
+	Tags []string
+	// Duration is how long the picture stays before the next automatic
+	// change, 0 meaning the interval.
+	Duration time.Duration
+}
+
+// readSidecar reads the sidecar file of the picture, which is empty if there
+// is none.
+func readSidecar(filename string) sidecar {
+	var sc sidecar
+	data, err := os.ReadFile(filename + sidecarSuffix)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			debugf("cannot read sidecar of '%s': %v", filename, err)
+		}
+		return sc
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "duration":
+				d, err := time.ParseDuration(strings.TrimSpace(value))
+				if err != nil || d < 0 {
+					debugf("invalid duration in sidecar of '%s': '%s'", filename, strings.TrimSpace(value))
+					continue
+				}
+				sc.Duration = d
+			default:
+				debugf("unknown setting '%s' in sidecar of '%s'", strings.TrimSpace(name), filename)
+			}
+			continue
+		}
+		sc.Tags = append(sc.Tags, strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\r'
+		})...)
+	}
+	return sc
+}
@@ -0,0 +1,92 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+func TestReadSidecar(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.png")
+	if sc := readSidecar(p); len(sc.Tags) != 0 || sc.Duration != 0 {
+		t.Errorf("got %+v without a sidecar", sc)
+	}
+	if err := os.WriteFile(p+sidecarSuffix, []byte("Beach, Summer\nduration: 90s\nnope: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc := readSidecar(p)
+	if len(sc.Tags) != 2 || sc.Tags[0] != "beach" || sc.Tags[1] != "summer" || sc.Duration != 90*time.Second {
+		t.Errorf("got %+v, want tags beach and summer and 90s", sc)
+	}
+	if err := os.WriteFile(p+sidecarSuffix, []byte("duration: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if sc := readSidecar(p); sc.Duration != 0 {
+		t.Errorf("got duration %s from an invalid one", sc.Duration)
+	}
+}
+
+func TestSidecarDuration(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	if err := os.WriteFile(pictures[0]+sidecarSuffix, []byte("duration: 50ms\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1h"}`, dir)
+	drain(intervalChanges)
+
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := EffectiveInterval(cfg); got != xjson.Duration(50*time.Millisecond) {
+		t.Errorf("got interval %s, want the 50ms of the sidecar", got)
+	}
+	select {
+	case <-intervalChanges:
+	default:
+		t.Fatal("the duration of the picture did not reschedule the next change")
+	}
+	// the main loop then restarts the ticker
+	timer, stop := ChangeTicker(cfg)
+	defer stop()
+	select {
+	case <-timer:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the next change did not come after the duration of the picture")
+	}
+	if next := getStatus().NextChange; next == nil || next.After(time.Now().Add(time.Minute)) {
+		t.Errorf("got next change at %v, want it within the duration", next)
+	}
+
+	if err := SetBackground(cfg, pictures[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := EffectiveInterval(cfg); got != xjson.Duration(time.Hour) {
+		t.Errorf("got interval %s without a sidecar, want the interval", got)
+	}
+	select {
+	case <-intervalChanges:
+	default:
+		t.Error("going back to the interval did not reschedule the next change")
+	}
+}
+
+func TestSidecarDurationAccessibility(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 1)
+	if err := os.WriteFile(pictures[0]+sidecarSuffix, []byte("duration: 1m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "2h", "accessibility": {"enabled": true, "min_interval": "30m"}}`, dir)
+	if err := SetBackground(cfg, pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got := EffectiveInterval(cfg); got != xjson.Duration(30*time.Minute) {
+		t.Errorf("got interval %s, want the 30m min_interval", got)
+	}
+}
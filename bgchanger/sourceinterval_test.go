@@ -0,0 +1,44 @@
+package bgchanger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourceInterval(t *testing.T) {
+	testEnv(t)
+	quick, curated := t.TempDir(), t.TempDir()
+	writeTestPictures(t, quick, 2)
+	writeTestPictures(t, curated, 2)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "interval": "1h", "sources": [
+		{"type": "dir", "path": %q, "interval": "5m"},
+		{"type": "dir", "path": %q}
+	]}`, quick, curated)
+
+	switches := 0
+	last := ""
+	for i := 0; i < 50; i++ {
+		drain(intervalChanges)
+		ChangeBG(cfg)
+		dir := filepath.Dir(getStatus().Current)
+		want := time.Hour
+		if dir == quick {
+			want = 5 * time.Minute
+		}
+		if got := time.Duration(EffectiveInterval(cfg)); got != want {
+			t.Fatalf("got interval %s with a picture from %s, want %s", got, dir, want)
+		}
+		notified := len(intervalChanges) > 0
+		if last != "" && (dir != last) != notified {
+			t.Fatalf("interval change notified: %v, switching from %s to %s", notified, last, dir)
+		}
+		if last != "" && dir != last {
+			switches++
+		}
+		last = dir
+	}
+	if switches == 0 {
+		t.Error("the active source never changed")
+	}
+}
@@ -0,0 +1,210 @@
+package bgchanger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+	"github.com/kirsle/configdir"
+)
+
+// source types
+const (
+	sourceDir  = "dir"
+	sourceURLs = "urls"
+)
+
+// SourceConfig is a place pictures are picked from. A "dir" source is a local
+// directory, an "archive" source is a .zip or .tar.gz file of pictures, a
+// "urls" source is a list of pictures downloaded from the web, and an "s3"
+// source is a bucket of an S3 compatible object storage. Sources are picked
+// randomly, proportionally to their weight.
+type SourceConfig struct {
+	Type     string         `json:"type"`
+	Path     string         `json:"path"`
+	URLs     []string       `json:"urls"`
+	Weight   float64        `json:"weight"`
+	Interval xjson.Duration `json:"interval"`
+	Bucket   string         `json:"bucket"`
+	Prefix   string         `json:"prefix"`
+	Region   string         `json:"region"`
+	Endpoint string         `json:"endpoint"`
+}
+
+func (sc *SourceConfig) validate() error {
+	switch sc.Type {
+	case sourceDir:
+		if sc.Path == "" {
+			return fmt.Errorf("path cannot be empty for a dir source")
+		}
+	case sourceArchive:
+		if !isArchive(sc.Path) {
+			return fmt.Errorf("path must be a .zip, .tar.gz or .tgz file for an archive source")
+		}
+	case sourceURLs:
+		if len(sc.URLs) == 0 {
+			return fmt.Errorf("urls cannot be empty for a urls source")
+		}
+		for _, u := range sc.URLs {
+			if pu, err := url.Parse(u); err != nil || (pu.Scheme != "http" && pu.Scheme != "https") {
+				return fmt.Errorf("invalid URL '%s'", u)
+			}
+		}
+	case sourceS3:
+		if sc.Bucket == "" {
+			return fmt.Errorf("bucket cannot be empty for an s3 source")
+		}
+	default:
+		return fmt.Errorf("unknown source type '%s'", sc.Type)
+	}
+	if sc.Weight < 0 {
+		return fmt.Errorf("weight cannot be negative")
+	}
+	if sc.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	return nil
+}
+
+// String returns a human-readable description of the source.
+func (sc *SourceConfig) String() string {
+	switch sc.Type {
+	case sourceURLs:
+		return fmt.Sprintf("%d URLs", len(sc.URLs))
+	case sourceS3:
+		return "s3://" + path.Join(sc.Bucket, sc.Prefix)
+	}
+	return sc.Path
+}
+
+// configuredSources returns the directory for today in calendar_sources if
+// any, otherwise the directory for the current network in network_sources if
+// any, otherwise the sources for the current time in time_sources if any,
+// otherwise the configured sources, or a single directory source for
+// pictures_dir if there are none.
+func configuredSources(cfg *Config) []SourceConfig {
+	now := time.Now()
+	if dir, ok := calendarDir(cfg.CalendarSources, now); ok {
+		return []SourceConfig{{Type: sourceDir, Path: dir, Weight: 1}}
+	}
+	if r := networkRule(cfg.NetworkSources); r != nil && r.Path != "" {
+		return []SourceConfig{{Type: sourceDir, Path: r.Path, Weight: 1}}
+	}
+	if sources, ok := timeSources(cfg.TimeSources, now); ok {
+		return sources
+	}
+	if len(cfg.Sources) > 0 {
+		return cfg.Sources
+	}
+	if isArchive(cfg.PicturesDir) {
+		return []SourceConfig{{Type: sourceArchive, Path: cfg.PicturesDir, Weight: 1}}
+	}
+	return []SourceConfig{{Type: sourceDir, Path: cfg.PicturesDir, Weight: 1}}
+}
+
+// orderSources returns the sources in random order, with the ones with a
+// higher weight more likely to come first.
+func orderSources(sources []SourceConfig) []SourceConfig {
+	ordered := make([]SourceConfig, len(sources))
+	copy(ordered, sources)
+	keys := make([]float64, len(ordered))
+	for i, s := range ordered {
+		w := s.Weight
+		if w == 0 {
+			w = 1
+		}
+		keys[i] = math.Pow(rand.Float64(), 1/w)
+	}
+	sort.Sort(byKey{ordered, keys})
+	return ordered
+}
+
+type byKey struct {
+	sources []SourceConfig
+	keys    []float64
+}
+
+func (b byKey) Len() int           { return len(b.sources) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] > b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.sources[i], b.sources[j] = b.sources[j], b.sources[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// candidates returns the local pictures offered by the source.
+func (sc *SourceConfig) candidates(cfg *Config) ([]string, error) {
+	switch sc.Type {
+	case sourceURLs:
+		return downloadURLs(sc.URLs)
+	case sourceS3:
+		return s3Candidates(cfg, sc)
+	case sourceArchive:
+		dir, err := extractArchive(sc.Path)
+		if err != nil {
+			return nil, err
+		}
+		return cachedCandidates(dir, cfg.MaxCandidates)
+	default:
+		return cachedCandidates(sc.Path, cfg.MaxCandidates)
+	}
+}
+
+// downloadURLs downloads the pictures at the given URLs to the cache, unless
+// already there, and returns their sorted local paths. Pictures that cannot
+// be downloaded are skipped.
+func downloadURLs(urls []string) ([]string, error) {
+	dir := path.Join(configdir.LocalCache(Progname), "downloads")
+	if err := makeDirs(dir); err != nil {
+		return nil, fmt.Errorf("failed to create download directory '%s': %w", dir, err)
+	}
+	var pictures []string
+	for _, u := range urls {
+		sum := sha1.Sum([]byte(u))
+		ext := path.Ext(strings.SplitN(u, "?", 2)[0])
+		if ext == "" {
+			ext = ".jpg"
+		}
+		local := path.Join(dir, hex.EncodeToString(sum[:])+strings.ToLower(ext))
+		if _, err := os.Stat(local); err != nil {
+			if err := download(u, local); err != nil {
+				log.Printf("Warning: cannot download '%s': %v", u, err)
+				continue
+			}
+		}
+		pictures = append(pictures, local)
+	}
+	sort.Strings(pictures)
+	return pictures, nil
+}
+
+func download(u, filename string) error {
+	client := http.Client{Timeout: time.Minute}
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "image/") {
+		return fmt.Errorf("not an image, content type is '%s'", ct)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
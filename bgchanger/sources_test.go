@@ -0,0 +1,56 @@
+package bgchanger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPickFromMultipleSources(t *testing.T) {
+	testEnv(t)
+	first, second := t.TempDir(), t.TempDir()
+	writeTestPictures(t, first, 2)
+	writeTestPictures(t, second, 2)
+	served := writeTestPictures(t, t.TempDir(), 1)[0]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, served)
+	}))
+	defer server.Close()
+	cfg := testConfig(t, `{"pictures_dir": "/p", "scan_retries": 0, "sources": [
+		{"type": "dir", "path": %q},
+		{"type": "dir", "path": %q, "weight": 2},
+		{"type": "urls", "urls": [%q]},
+		{"type": "dir", "path": "/nonexistent", "weight": 100}
+	]}`, first, second, server.URL+"/picture.png")
+
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		p, src, err := pickPicture(cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src.Type == sourceDir && filepath.Dir(p) != src.Path {
+			t.Fatalf("picked %s from %s", p, src.String())
+		}
+		counts[src.String()]++
+	}
+	if counts["/nonexistent"] != 0 {
+		t.Errorf("picked %d pictures from a missing directory", counts["/nonexistent"])
+	}
+	// the weights are 1, 2 and 1
+	if counts[first] == 0 || counts["1 URLs"] == 0 || counts[second] < counts[first] {
+		t.Errorf("got %v, want pictures from every source, more from %s", counts, second)
+	}
+
+	if err := os.RemoveAll(second); err != nil {
+		t.Fatal(err)
+	}
+	candidateCache.reset()
+	for i := 0; i < 20; i++ {
+		if _, src, err := pickPicture(cfg, nil); err != nil || src.Path == second {
+			t.Fatalf("got %v, %v with %s removed, want the other sources", src, err, second)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package bgchanger
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStartupDelay(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1h", "change_on_start": true, "startup_delay": "300ms"}`, dir)
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		RunLoop(cfg, nil, quit)
+		close(done)
+	}()
+	defer func() {
+		quit <- os.Interrupt
+		<-done
+	}()
+	for len(setter.values("picture-uri")) == 0 {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("no change after the startup delay")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Errorf("changed after %s, before the startup delay", d)
+	}
+
+	if d := time.Duration(*testConfig(t, `{"pictures_dir": "/p"}`).StartupDelay); d != defaultStartupDelay {
+		t.Errorf("got default startup_delay %s, want %s", d, defaultStartupDelay)
+	}
+}
@@ -0,0 +1,154 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// state is the runtime state of this instance.
+var state = struct {
+	sync.Mutex
+	current      string
+	previous     string
+	currentSince time.Time
+	// sourceInterval is the interval of the source of the current picture
+	sourceInterval xjson.Duration
+	// pictureDuration is the duration in the sidecar of the current picture
+	pictureDuration xjson.Duration
+	// candidateCount is the number of candidates, for adaptive_interval
+	candidateCount int
+	nextChange     time.Time
+	configFile     string
+	lastError      error
+}{}
+
+// OnLastErrorChange is called when the outcome of the last change differs
+// from the previous one, with the error or nil on success.
+var OnLastErrorChange = func(err error) {}
+
+// OnUndoAvailableChange is called with whether there is a change to undo,
+// every time that is recomputed.
+var OnUndoAvailableChange = func(available bool) {}
+
+// Status is the state of a running instance, as reported by -status.
+type Status struct {
+	Current string `json:"current"`
+	// Paused is whether the automatic changes are paused, by the user or by
+	// pause_on_failures
+	Paused     bool       `json:"paused"`
+	NextChange *time.Time `json:"next_change,omitempty"`
+	ConfigFile string     `json:"config_file"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+func setCurrent(cfg *Config, filename string) {
+	SaveDisplayTime()
+	state.Lock()
+	if state.current != filename {
+		state.previous = state.current
+	}
+	state.current = filename
+	state.currentSince = time.Now()
+	undoable := state.previous != ""
+	state.Unlock()
+	saveLastApplied(filename)
+	if err := recordShown(cfg, filename, time.Now()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := recordSeen(cfg, filename); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	setPictureDuration(cfg, filename)
+	OnUndoAvailableChange(undoable)
+}
+
+// CurrentPicture returns the picture most recently applied by this instance.
+func CurrentPicture() string {
+	state.Lock()
+	defer state.Unlock()
+	return state.current
+}
+
+// SetConfigFile records the config file used by this instance, for the
+// status and for reloading it when the network changes.
+func SetConfigFile(configFile string) {
+	state.Lock()
+	state.configFile = configFile
+	state.Unlock()
+}
+
+// setLastError records the outcome of the last change, nil meaning success.
+func setLastError(err error) {
+	state.Lock()
+	changed := (err == nil) != (state.lastError == nil) ||
+		(err != nil && err.Error() != state.lastError.Error())
+	state.lastError = err
+	state.Unlock()
+	if changed {
+		OnLastErrorChange(err)
+	}
+}
+
+// scheduleNextChange records when the next automatic change is due.
+func scheduleNextChange(cfg *Config) {
+	state.Lock()
+	defer state.Unlock()
+	interval := effectiveIntervalLocked(cfg)
+	if interval <= 0 {
+		state.nextChange = time.Time{}
+		return
+	}
+	state.nextChange = time.Now().Add(time.Duration(interval))
+}
+
+func getStatus() Status {
+	paused := Paused() || failuresPaused()
+	state.Lock()
+	defer state.Unlock()
+	st := Status{
+		Current:    state.current,
+		Paused:     paused,
+		ConfigFile: state.configFile,
+	}
+	if state.lastError != nil {
+		st.LastError = state.lastError.Error()
+	}
+	if !state.nextChange.IsZero() {
+		next := state.nextChange
+		st.NextChange = &next
+	}
+	return st
+}
+
+// PrintStatus queries the running instance via its control server and prints
+// its status.
+func PrintStatus(cfg *Config) error {
+	if cfg.ControlAddr == "" {
+		return fmt.Errorf("control_addr is not configured, cannot reach the running instance")
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + cfg.ControlAddr + "/status")
+	if err != nil {
+		return fmt.Errorf("no running instance found at %s: %w", cfg.ControlAddr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from %s: %s", cfg.ControlAddr, resp.Status)
+	}
+	var st Status
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return fmt.Errorf("failed to decode status: %w", err)
+	}
+	data, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
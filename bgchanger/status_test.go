@@ -0,0 +1,88 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusJSON(t *testing.T) {
+	next := time.Date(2026, 10, 14, 12, 30, 0, 0, time.UTC)
+	st := Status{
+		Current:    "/pictures/a.jpg",
+		Paused:     true,
+		NextChange: &next,
+		ConfigFile: "/config/config.json",
+		LastError:  "boom",
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"current":"/pictures/a.jpg","paused":true,"next_change":"2026-10-14T12:30:00Z","config_file":"/config/config.json","last_error":"boom"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	var decoded Status
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, st) {
+		t.Errorf("got %+v after a round trip, want %+v", decoded, st)
+	}
+
+	data, err = json.Marshal(Status{Current: "/pictures/a.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"current":"/pictures/a.jpg","paused":false,"config_file":""}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestGetStatusPaused(t *testing.T) {
+	testEnv(t)
+	if getStatus().Paused {
+		t.Fatal("paused in a new environment")
+	}
+	setPaused(true)
+	if !getStatus().Paused {
+		t.Error("not paused after pausing")
+	}
+	setPaused(false)
+	failures.Lock()
+	failures.paused = true
+	failures.Unlock()
+	if !getStatus().Paused {
+		t.Error("not paused by pause_on_failures")
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	testEnv(t)
+	SetConfigFile("/config/config.json")
+	cfg := testConfig(t, `{"pictures_dir": "/p"}`)
+	server := httptest.NewServer(controlHandler(cfg))
+	defer server.Close()
+	cfg.ControlAddr = strings.TrimPrefix(server.URL, "http://")
+	var err error
+	out := captureStdout(t, func() { err = PrintStatus(cfg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st Status
+	if err := json.Unmarshal([]byte(out), &st); err != nil {
+		t.Fatalf("invalid output %q: %v", out, err)
+	}
+	if st.ConfigFile != "/config/config.json" {
+		t.Errorf("got %+v", st)
+	}
+
+	server.Close()
+	if err := PrintStatus(cfg); err == nil {
+		t.Error("no error without a running instance")
+	}
+}
@@ -0,0 +1,114 @@
+package bgchanger
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fallbackResolution is the size SVG pictures are rasterized to if the
+// resolution of the display is unknown.
+var fallbackResolution = [2]int{1920, 1080}
+
+// rasterize converts SVG pictures to a PNG at the resolution of the display,
+// and ICO pictures to a PNG of their largest image, and returns the path of
+// the cached result. Other pictures are returned as they are.
+func rasterize(filename string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".svg" && ext != ".ico" {
+		return filename, nil
+	}
+	variant := "largest"
+	w, h := 0, 0
+	if ext == ".svg" {
+		var err error
+		if w, h, err = displayResolution(); err != nil {
+			log.Printf("Warning: cannot get display resolution, rasterizing '%s' to %dx%d: %v", filename, fallbackResolution[0], fallbackResolution[1], err)
+			w, h = fallbackResolution[0], fallbackResolution[1]
+		}
+		variant = fmt.Sprintf("%dx%d", w, h)
+	}
+	cached, ok, err := cachedFile("rasterized", filename, variant, ".png")
+	if err != nil || ok {
+		return cached, err
+	}
+	var img image.Image
+	if ext == ".svg" {
+		img, err = renderSVG(filename, w, h)
+	} else {
+		img, err = loadImage(filename)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := writePNG(cached, img); err != nil {
+		return "", fmt.Errorf("failed to write rasterized picture: %w", err)
+	}
+	return cached, nil
+}
+
+// rasterizedSVG returns the rasterized copy of an SVG picture, which can be
+// decoded like any other picture, or the given picture if not an SVG.
+func rasterizedSVG(filename string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(filename), ".svg") {
+		return filename, nil
+	}
+	return rasterize(filename)
+}
+
+// lookPath is exec.LookPath, a variable so that tests can pretend that tools
+// are installed or not.
+var lookPath = exec.LookPath
+
+// svgSupport records whether rsvg-convert, which rasterizes the SVG
+// pictures, is installed. It is checked once.
+var svgSupport struct {
+	once      sync.Once
+	available bool
+}
+
+func svgAvailable() bool {
+	svgSupport.once.Do(func() {
+		_, err := lookPath("rsvg-convert")
+		svgSupport.available = err == nil
+		if err != nil {
+			log.Printf("Warning: rsvg-convert (from librsvg) is not installed, SVG pictures cannot be used: %v", err)
+		}
+	})
+	return svgSupport.available
+}
+
+// renderSVG rasterizes an SVG file with rsvg-convert, to fit in w x h pixels
+// keeping its aspect ratio.
+func renderSVG(filename string, w, h int) (image.Image, error) {
+	if !svgAvailable() {
+		return nil, fmt.Errorf("cannot rasterize '%s', rsvg-convert is not installed", filename)
+	}
+	out, err := commandOutput("rsvg-convert", "--keep-aspect-ratio",
+		"--width", strconv.Itoa(w), "--height", strconv.Itoa(h),
+		"--format", "png", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rasterize '%s' with rsvg-convert: %w", filename, err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rasterized '%s': %w", filename, err)
+	}
+	return img, nil
+}
+
+// writePNG is writeJPEG, for PNG files.
+func writePNG(filename string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
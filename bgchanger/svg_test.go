@@ -0,0 +1,211 @@
+package bgchanger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// decodeSize returns the size of the picture in filename.
+func decodeSize(t *testing.T, filename string) (int, int) {
+	t.Helper()
+	fd, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	c, _, err := image.DecodeConfig(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Width, c.Height
+}
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="16" height="9"><rect width="16" height="9" fill="#336699"/></svg>`
+
+// fakeLookPath pretends that only the given tools are installed.
+func fakeLookPath(t *testing.T, installed ...string) {
+	old := lookPath
+	lookPath = func(file string) (string, error) {
+		if contains(installed, file) {
+			return "/usr/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	t.Cleanup(func() { lookPath = old })
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	testEnv(t)
+	fakeLookPath(t, "rsvg-convert")
+	renders := 0
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		switch name {
+		case "xrandr":
+			return []byte("DP-1 connected primary 64x36+0+0 (normal)\n"), nil
+		case "rsvg-convert":
+			renders++
+			var w, h int
+			for i := 0; i+1 < len(args); i++ {
+				switch args[i] {
+				case "--width":
+					w, _ = strconv.Atoi(args[i+1])
+				case "--height":
+					h, _ = strconv.Atoi(args[i+1])
+				}
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		return nil, errNoDesktop
+	}
+	svg := filepath.Join(t.TempDir(), "vector.svg")
+	if err := os.WriteFile(svg, []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rasterized, err := rasterize(svg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Ext(rasterized) != ".png" {
+		t.Errorf("got %s, want a PNG", rasterized)
+	}
+	if w, h := decodeSize(t, rasterized); w != 64 || h != 36 {
+		t.Errorf("got %dx%d, want the display resolution 64x36", w, h)
+	}
+	// the result is cached
+	if again, err := rasterize(svg); err != nil || again != rasterized || renders != 1 {
+		t.Errorf("got %s, %v after %d renders, want the cached %s", again, err, renders, rasterized)
+	}
+}
+
+func TestRasterizeSVGWithRsvg(t *testing.T) {
+	testEnv(t)
+	if _, err := exec.LookPath("rsvg-convert"); err != nil {
+		t.Skip("rsvg-convert is not installed")
+	}
+	commandOutput = func(name string, args ...string) ([]byte, error) {
+		if name == "xrandr" {
+			return []byte("DP-1 connected primary 160x90+0+0 (normal)\n"), nil
+		}
+		return exec.Command(name, args...).Output()
+	}
+	svg := filepath.Join(t.TempDir(), "vector.svg")
+	if err := os.WriteFile(svg, []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rasterized, err := rasterize(svg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, rasterized); w != 160 || h != 90 {
+		t.Errorf("got %dx%d, want 160x90", w, h)
+	}
+}
+
+// writeTestICO writes an ICO file with a PNG image of each of the given
+// sizes.
+func writeTestICO(t *testing.T, filename string, sizes ...int) {
+	t.Helper()
+	var frames [][]byte
+	for _, size := range sizes {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(size)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, buf.Bytes())
+	}
+	var out bytes.Buffer
+	out.Write([]byte{0, 0, 1, 0})
+	binary.Write(&out, binary.LittleEndian, uint16(len(sizes)))
+	offset := 6 + 16*len(sizes)
+	for i, size := range sizes {
+		out.Write([]byte{uint8(size), uint8(size), 0, 0, 1, 0, 32, 0})
+		binary.Write(&out, binary.LittleEndian, uint32(len(frames[i])))
+		binary.Write(&out, binary.LittleEndian, uint32(offset))
+		offset += len(frames[i])
+	}
+	for _, f := range frames {
+		out.Write(f)
+	}
+	if err := os.WriteFile(filename, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRasterizeICO(t *testing.T) {
+	testEnv(t)
+	ico := filepath.Join(t.TempDir(), "icon.ico")
+	writeTestICO(t, ico, 16, 48, 32)
+	rasterized, err := rasterize(ico)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decodeSize(t, rasterized); w != 48 || h != 48 {
+		t.Errorf("got %dx%d, want the largest image 48x48", w, h)
+	}
+	img, err := loadImage(rasterized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); c.R != 48 {
+		t.Errorf("got color %v, want the one of the 48x48 image", c)
+	}
+}
+
+func TestVectorExtensionsAreOptIn(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 1)
+	writeTestICO(t, filepath.Join(dir, "icon.ico"), 16)
+	if err := os.WriteFile(filepath.Join(dir, "vector.svg"), []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ListCandidates(dir, 0); err != nil || len(got) != 1 {
+		t.Errorf("got %v, %v, want only the PNG by default", got, err)
+	}
+	setSupportedExtensions([]string{"png", "svg", "ico"})
+	if got, err := ListCandidates(dir, 0); err != nil || len(got) != 3 {
+		t.Errorf("got %v, %v, want the SVG and ICO too", got, err)
+	}
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "supported_extensions": ["bmp"]}`)); err == nil {
+		t.Error("got no error for an unsupported extension")
+	}
+}
+
+func TestSVGWithoutRsvg(t *testing.T) {
+	testEnv(t)
+	fakeLookPath(t)
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	// the warning comes as soon as svg is supported, once
+	setSupportedExtensions([]string{"png", "svg"})
+	setSupportedExtensions([]string{"svg"})
+	if n := strings.Count(buf.String(), "rsvg-convert (from librsvg) is not installed"); n != 1 {
+		t.Errorf("got %q, want a single warning about rsvg-convert", buf.String())
+	}
+	svg := filepath.Join(t.TempDir(), "vector.svg")
+	if err := os.WriteFile(svg, []byte(testSVG), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rasterize(svg); err == nil || !strings.Contains(err.Error(), "rsvg-convert is not installed") {
+		t.Errorf("got %v, want an error about rsvg-convert", err)
+	}
+}
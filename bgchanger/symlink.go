@@ -0,0 +1,58 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// updateCurrentSymlink points current_symlink, if set, to the applied
+// picture. The link is replaced atomically, so that other programs never
+// find it missing.
+func updateCurrentSymlink(cfg *Config, applied string) {
+	if cfg.CurrentSymlink == "" {
+		return
+	}
+	if err := replaceSymlink(applied, cfg.CurrentSymlink); err != nil {
+		log.Printf("Warning: cannot update current_symlink: %v", err)
+	}
+}
+
+func replaceSymlink(target, link string) error {
+	if err := checkWrite(link); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", link, os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// RemoveCurrentSymlink removes current_symlink on exit, if
+// remove_symlink_on_exit is set.
+func RemoveCurrentSymlink(cfg *Config) {
+	if cfg.CurrentSymlink == "" || !cfg.RemoveSymlinkOnExit {
+		return
+	}
+	if err := checkWrite(cfg.CurrentSymlink); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if st, err := os.Lstat(cfg.CurrentSymlink); err != nil || st.Mode()&os.ModeSymlink == 0 {
+		// never remove something that isn't our symlink
+		return
+	}
+	if err := os.Remove(cfg.CurrentSymlink); err != nil {
+		log.Printf("Warning: cannot remove current_symlink: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListCandidatesSymlinks(t *testing.T) {
+	testEnv(t)
+	dir, targets := t.TempDir(), t.TempDir()
+	pictures := writeTestPictures(t, targets, 1)
+	valid := filepath.Join(dir, "valid.png")
+	dangling := filepath.Join(dir, "dangling.png")
+	if err := os.Symlink(pictures[0], valid); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(targets, "unmounted.png"), dangling); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ListCandidates(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != valid {
+		t.Errorf("got candidates %v, want only the valid symlink %s", got, valid)
+	}
+}
+
+func TestPickSkipsDeadCachedSymlinks(t *testing.T) {
+	setter := testEnv(t)
+	dir, targets := t.TempDir(), t.TempDir()
+	pictures := writeTestPictures(t, targets, 2)
+	for i, p := range pictures {
+		if err := os.Symlink(p, filepath.Join(dir, filepath.Base(pictures[i]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	if _, err := cachedCandidates(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	// the target goes away without changing the directory of the symlinks,
+	// so the cached listing still has it
+	if err := os.Remove(pictures[0]); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != filepath.Join(dir, "1.png") {
+			t.Fatalf("got %s, want the symlink that still resolves", p)
+		}
+	}
+	if err := os.Remove(pictures[1]); err != nil {
+		t.Fatal(err)
+	}
+	ChangeBG(cfg)
+	if len(setter.values("picture-uri")) != 0 {
+		t.Errorf("got picture-uri %v, want no change without readable pictures", setter.values("picture-uri"))
+	}
+}
+
+func TestCurrentSymlink(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 3)
+	link := filepath.Join(t.TempDir(), "sub", "current")
+	cfg := testConfig(t, `{"pictures_dir": %q, "current_symlink": %q}`, dir, link)
+	for i := 0; i < 3; i++ {
+		ChangeBG(cfg)
+		target, err := os.Readlink(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uri := setter.get("picture-uri"); "file://"+target != uri {
+			t.Errorf("got current_symlink to %s, want the applied %s", target, uri)
+		}
+	}
+	if matches, _ := filepath.Glob(link + ".*"); len(matches) != 0 {
+		t.Errorf("got leftover files %v", matches)
+	}
+
+	// kept on exit unless remove_symlink_on_exit is set
+	RemoveCurrentSymlink(cfg)
+	if _, err := os.Lstat(link); err != nil {
+		t.Errorf("the symlink was removed: %v", err)
+	}
+	cfg = testConfig(t, `{"pictures_dir": %q, "current_symlink": %q, "remove_symlink_on_exit": true}`, dir, link)
+	RemoveCurrentSymlink(cfg)
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("the symlink was not removed on exit: %v", err)
+	}
+}
+
+func TestRemoveCurrentSymlinkKeepsFiles(t *testing.T) {
+	testEnv(t)
+	file := filepath.Join(t.TempDir(), "current")
+	if err := os.WriteFile(file, []byte("not a symlink"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	RemoveCurrentSymlink(testConfig(t, `{"pictures_dir": "/p", "current_symlink": %q, "remove_symlink_on_exit": true}`, file))
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("removed a file that is not a symlink: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package bgchanger
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimePeriod is a daily time range, with the same format as quiet_hours, and
+// the sources to pick from during it. The sources are mixed by weight like
+// the top-level ones, so that e.g. the mornings can be mostly, but not only,
+// nature pictures.
+type TimePeriod struct {
+	QuietWindow
+	Sources []SourceConfig `json:"sources"`
+}
+
+func (tp *TimePeriod) validate() error {
+	if err := tp.parse(); err != nil {
+		return err
+	}
+	for i := range tp.Sources {
+		if err := tp.Sources[i].validate(); err != nil {
+			return fmt.Errorf("invalid sources entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// timeSources returns the sources of the first period containing t that has
+// any.
+func timeSources(periods []TimePeriod, t time.Time) ([]SourceConfig, bool) {
+	for i := range periods {
+		if p := &periods[i]; len(p.Sources) > 0 && p.contains(t) {
+			return p.Sources, true
+		}
+	}
+	return nil, false
+}
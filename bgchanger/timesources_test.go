@@ -0,0 +1,79 @@
+package bgchanger
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeSourcesWeights(t *testing.T) {
+	testEnv(t)
+	nature, abstract, global := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestPictures(t, nature, 2)
+	writeTestPictures(t, abstract, 2)
+	writeTestPictures(t, global, 2)
+	// a period around now, and its complement
+	now := time.Now()
+	start, end := now.Add(-2*time.Hour).Format("15:04"), now.Add(2*time.Hour).Format("15:04")
+	cfg := testConfig(t, `{"pictures_dir": %q, "time_sources": [
+		{"start": %q, "end": %q, "sources": [
+			{"type": "dir", "path": %q, "weight": 4},
+			{"type": "dir", "path": %q, "weight": 1}]},
+		{"start": %q, "end": %q, "sources": [{"type": "dir", "path": %q}]}]}`,
+		global, start, end, nature, abstract, end, start, abstract)
+
+	const n = 2000
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[filepath.Dir(p)]++
+	}
+	if counts[global] != 0 {
+		t.Errorf("got %d pictures from pictures_dir during a period with sources", counts[global])
+	}
+	// the expected ratio nature:abstract is 4:1
+	if ratio := float64(counts[nature]) / n; ratio < 0.75 || ratio > 0.85 {
+		t.Errorf("got %d of %d pictures from the nature folder, want about 80%%", counts[nature], n)
+	}
+	if counts[abstract] == 0 {
+		t.Error("never picked from the abstract folder")
+	}
+}
+
+func TestTimeSourcesFallback(t *testing.T) {
+	testEnv(t)
+	global := t.TempDir()
+	writeTestPictures(t, global, 2)
+	now := time.Now()
+	// the current period has no sources, and the other one is not now
+	cfg := testConfig(t, `{"pictures_dir": %q, "time_sources": [
+		{"start": %q, "end": %q, "sources": []},
+		{"start": %q, "end": %q, "sources": [{"type": "dir", "path": "/elsewhere"}]}]}`,
+		global,
+		now.Add(-2*time.Hour).Format("15:04"), now.Add(2*time.Hour).Format("15:04"),
+		now.Add(3*time.Hour).Format("15:04"), now.Add(4*time.Hour).Format("15:04"))
+	for i := 0; i < 10; i++ {
+		p, err := PickPicture(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(p, global) {
+			t.Fatalf("got %s, want a picture from pictures_dir", p)
+		}
+	}
+}
+
+func TestTimeSourcesValidate(t *testing.T) {
+	for _, tc := range []string{
+		`{"pictures_dir": "/p", "time_sources": [{"start": "6:00am", "end": "12:00", "sources": []}]}`,
+		`{"pictures_dir": "/p", "time_sources": [{"start": "06:00", "end": "12:00", "sources": [{"type": "bogus"}]}]}`,
+	} {
+		if _, err := ParseConfig([]byte(tc)); err == nil {
+			t.Errorf("%s: got no error, want an invalid time_sources error", tc)
+		}
+	}
+}
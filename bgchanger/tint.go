@@ -0,0 +1,99 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"time"
+)
+
+// defaultTintCurve warms the pictures in the evening and at night.
+var defaultTintCurve = map[string]float64{
+	"0":  0.5,
+	"6":  0.2,
+	"8":  0,
+	"18": 0.2,
+	"20": 0.4,
+	"22": 0.5,
+}
+
+// TintConfig configures the tint step, which warms the pictures depending on
+// the time of day, like a night light.
+type TintConfig struct {
+	Enabled bool `json:"enabled"`
+	// Curve maps hours, from "0" to "23", to a tint strength between 0 and 1.
+	// Each strength applies until the next listed hour.
+	Curve map[string]float64 `json:"curve"`
+}
+
+func (tc *TintConfig) validate() error {
+	for k, v := range tc.Curve {
+		h, err := strconv.Atoi(k)
+		if err != nil || h < 0 || h > 23 {
+			return fmt.Errorf("invalid tint curve hour '%s', want 0 to 23", k)
+		}
+		if v < 0 || v > 1 {
+			return fmt.Errorf("invalid tint curve strength %v for hour %s, want 0 to 1", v, k)
+		}
+	}
+	return nil
+}
+
+// strength returns the tint strength for the given time, which is the one of
+// the closest listed hour before it, wrapping around midnight.
+func (tc *TintConfig) strength(now time.Time) float64 {
+	curve := tc.Curve
+	if len(curve) == 0 {
+		curve = defaultTintCurve
+	}
+	for i := 0; i < 24; i++ {
+		h := (now.Hour() - i + 24) % 24
+		if v, ok := curve[strconv.Itoa(h)]; ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// warmImage shifts the color temperature of img towards warmer tones, more so
+// the higher the strength.
+func warmImage(img image.Image, strength float64) *image.RGBA {
+	src := toRGBA(img)
+	b := src.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	scale := func(v uint8, f float64) uint8 {
+		return uint8(math.Min(255, float64(v)*f+0.5))
+	}
+	fr, fg, fb := 1+0.15*strength, 1-0.05*strength, 1-0.4*strength
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := src.RGBAAt(b.Min.X+x, b.Min.Y+y)
+			out.SetRGBA(x, y, color.RGBA{R: scale(c.R, fr), G: scale(c.G, fg), B: scale(c.B, fb), A: c.A})
+		}
+	}
+	return out
+}
+
+// tint warms the picture according to the time of day, and returns the path
+// of the cached result. Pictures are returned unchanged when the strength is
+// 0.
+func tint(cfg *Config, filename string, now time.Time) (string, error) {
+	strength := cfg.Tint.strength(now)
+	if strength == 0 {
+		return filename, nil
+	}
+	cached, ok, err := cachedVariant("tinted", filename, fmt.Sprintf("tint%03.0f", strength*100))
+	if err != nil || ok {
+		return cached, err
+	}
+	img, err := loadImage(filename)
+	if err != nil {
+		return "", err
+	}
+	if err := writeJPEG(cached, warmImage(img, strength)); err != nil {
+		return "", fmt.Errorf("failed to write tinted picture: %w", err)
+	}
+	return cached, nil
+}
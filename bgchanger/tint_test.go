@@ -0,0 +1,56 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTintStrength(t *testing.T) {
+	tc := TintConfig{Curve: map[string]float64{"8": 0, "20": 0.5}}
+	for hour, want := range map[int]float64{8: 0, 12: 0, 19: 0, 20: 0.5, 23: 0.5, 3: 0.5} {
+		now := time.Date(2024, 1, 1, hour, 30, 0, 0, time.Local)
+		if got := tc.strength(now); got != want {
+			t.Errorf("got strength %v at %d:30, want %v", got, hour, want)
+		}
+	}
+	if err := (&TintConfig{Curve: map[string]float64{"24": 0.5}}).validate(); err == nil {
+		t.Error("accepted hour 24")
+	}
+	if err := (&TintConfig{Curve: map[string]float64{"20": 2}}).validate(); err == nil {
+		t.Error("accepted strength 2")
+	}
+}
+
+func TestTintIsWarmerInTheEvening(t *testing.T) {
+	testEnv(t)
+	src := color.RGBA{128, 128, 128, 255}
+	picture := writeTestPicture(t, filepath.Join(t.TempDir(), "gray.png"), 16, 16, src)
+	cfg := testConfig(t, `{"pictures_dir": "/p", "tint": {"enabled": true}}`)
+
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	if got, err := tint(cfg, picture, day); err != nil || got != picture {
+		t.Errorf("got %s, %v during the day, want the untinted picture", got, err)
+	}
+	evening := time.Date(2024, 1, 1, 21, 0, 0, 0, time.Local)
+	tinted, err := tint(cfg, picture, evening)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tinted == picture {
+		t.Fatal("the picture was not tinted in the evening")
+	}
+	img, err := loadImage(tinted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, b, _ := img.At(8, 8).RGBA()
+	if r>>8 <= uint32(src.R) || b>>8 >= uint32(src.B) {
+		t.Errorf("got red %d and blue %d in the evening, want warmer than %v", r>>8, b>>8, src)
+	}
+	// the tinted picture is cached for the same strength
+	if again, err := tint(cfg, picture, evening.Add(30*time.Minute)); err != nil || again != tinted {
+		t.Errorf("got %s, %v, want the cached %s", again, err, tinted)
+	}
+}
@@ -0,0 +1,105 @@
+package bgchanger
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/kirsle/configdir"
+)
+
+const (
+	transitionFade = "fade"
+
+	defaultTransitionDuration = time.Second
+	defaultTransitionFrames   = 5
+)
+
+// blendImages returns the image at t (between 0 and 1) of a crossfade from a
+// to b, with the size of b. a is stretched to the size of b if needed.
+func blendImages(a, b image.Image, t float64) *image.RGBA {
+	ab, bb := a.Bounds(), b.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bb.Dx(), bb.Dy()))
+	for y := 0; y < bb.Dy(); y++ {
+		ay := ab.Min.Y + y*ab.Dy()/bb.Dy()
+		for x := 0; x < bb.Dx(); x++ {
+			ax := ab.Min.X + x*ab.Dx()/bb.Dx()
+			ar, ag, abl, _ := a.At(ax, ay).RGBA()
+			br, bg, bbl, _ := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			out.SetRGBA(x, y, color.RGBA{
+				R: uint8((float64(ar)*(1-t) + float64(br)*t) / 0x101),
+				G: uint8((float64(ag)*(1-t) + float64(bg)*t) / 0x101),
+				B: uint8((float64(abl)*(1-t) + float64(bbl)*t) / 0x101),
+				A: 0xff,
+			})
+		}
+	}
+	return out
+}
+
+// fadeFrames writes the intermediate frames of a crossfade from the picture
+// at from to the one at to into dir, and returns their paths. It gives up if
+// it takes longer than the given budget.
+func fadeFrames(from, to, dir string, frames int, budget time.Duration) ([]string, error) {
+	start := time.Now()
+	a, err := loadImage(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadImage(to)
+	if err != nil {
+		return nil, err
+	}
+	// remove the frames of the previous transition. New frames get new names,
+	// otherwise GNOME may keep showing a cached one.
+	old, _ := filepath.Glob(path.Join(dir, "fade-*.jpg"))
+	for _, f := range old {
+		if checkWrite(f) == nil {
+			os.Remove(f)
+		}
+	}
+	var paths []string
+	for i := 1; i <= frames; i++ {
+		if time.Since(start) > budget {
+			return nil, fmt.Errorf("generating frames takes longer than %s", budget)
+		}
+		frame := blendImages(a, b, float64(i)/float64(frames+1))
+		p := path.Join(dir, fmt.Sprintf("fade-%d-%d.jpg", start.UnixNano(), i))
+		if err := writeJPEG(p, frame); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// playFade shows a quick crossfade from the picture at from to the one at to,
+// in the given key of the background settings, picture-uri or
+// picture-uri-dark. It is best-effort: on any error the change just happens
+// without a fade.
+func playFade(cfg *Config, key, from, to string) {
+	dir := configdir.LocalCache(Progname)
+	if err := makeDirs(dir); err != nil {
+		log.Printf("Warning: skipping fade, cannot create cache directory: %v", err)
+		return
+	}
+	duration := time.Duration(cfg.TransitionDuration)
+	frames, err := fadeFrames(from, to, dir, cfg.TransitionFrames, duration)
+	if err != nil {
+		log.Printf("Warning: skipping fade: %v", err)
+		return
+	}
+	delay := duration / time.Duration(len(frames)+1)
+	for _, f := range frames {
+		if err := gsettingsSet(backgroundSchema, key, "file://"+f); err != nil {
+			log.Printf("Warning: fade interrupted: %v", err)
+			return
+		}
+		time.Sleep(delay)
+	}
+}
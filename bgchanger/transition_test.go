@@ -0,0 +1,71 @@
+package bgchanger
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBlendImages(t *testing.T) {
+	dir := t.TempDir()
+	black := writeTestPicture(t, filepath.Join(dir, "black.png"), 8, 8, color.RGBA{0, 0, 0, 255})
+	orange := writeTestPicture(t, filepath.Join(dir, "orange.png"), 4, 4, color.RGBA{200, 100, 50, 255})
+	a, err := loadImage(black)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loadImage(orange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mid := blendImages(a, b, 0.5)
+	if s := mid.Bounds().Size(); s.X != 4 || s.Y != 4 {
+		t.Errorf("got size %v, want the size of the target", s)
+	}
+	if c := mid.RGBAAt(2, 2); c != (color.RGBA{100, 50, 25, 255}) {
+		t.Errorf("got %v halfway, want {100 50 25 255}", c)
+	}
+	if c := blendImages(a, b, 1).RGBAAt(0, 0); c != (color.RGBA{200, 100, 50, 255}) {
+		t.Errorf("got %v at the end, want the target", c)
+	}
+}
+
+func TestFadeFrames(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	from := writeTestPicture(t, filepath.Join(dir, "from.png"), 8, 8, color.RGBA{0, 0, 0, 255})
+	to := writeTestPicture(t, filepath.Join(dir, "to.png"), 8, 8, color.RGBA{240, 240, 240, 255})
+	out := t.TempDir()
+	frames, err := fadeFrames(from, to, out, 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	// each frame is brighter than the previous one, and in between
+	prev := 0.0
+	for _, f := range frames {
+		c, err := averageColor(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := luminance(c)
+		if l <= prev || l >= 0.95 {
+			t.Errorf("frame %s has luminance %.2f, after %.2f", f, l, prev)
+		}
+		prev = l
+	}
+	// the frames of the previous transition are removed
+	again, err := fadeFrames(from, to, out, 2, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := filepath.Glob(filepath.Join(out, "fade-*.jpg")); len(left) != len(again) {
+		t.Errorf("got %d frames in the cache, want %d", len(left), len(again))
+	}
+	if _, err := fadeFrames(from, to, out, 3, 0); err == nil {
+		t.Error("no error with no time budget")
+	}
+}
@@ -0,0 +1,48 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// actions of tray_click_action, run when the tray icon is clicked
+const (
+	trayClickNone    = "none"
+	trayClickChange  = "change"
+	trayClickPause   = "pause"
+	trayClickOpenDir = "open_dir"
+)
+
+func validateTrayClickAction(action string) error {
+	switch action {
+	case "", trayClickNone, trayClickChange, trayClickPause, trayClickOpenDir:
+		return nil
+	}
+	return fmt.Errorf("unknown tray_click_action '%s', want change, pause, open_dir or none", action)
+}
+
+// picturesFolder returns the folder to open for pictures_dir, which is the
+// folder containing it for an archive.
+func picturesFolder(cfg *Config) string {
+	if isArchive(cfg.PicturesDir) {
+		return filepath.Dir(cfg.PicturesDir)
+	}
+	return cfg.PicturesDir
+}
+
+// TrayClicked runs the tray_click_action. The tray library doesn't report the
+// clicks on the icon itself, so this is run by the /click request of the
+// control server, that e.g. a panel extension can send.
+func TrayClicked(cfg *Config) {
+	switch cfg.TrayClickAction {
+	case trayClickChange:
+		ManualChangeBG(cfg)
+	case trayClickPause:
+		setPaused(!Paused())
+	case trayClickOpenDir:
+		if err := runCommand("xdg-open", picturesFolder(cfg)); err != nil {
+			log.Printf("Error: cannot open the pictures folder: %v", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package bgchanger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTrayClicked(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	var commands [][]string
+	runCommand = func(name string, args ...string) error {
+		commands = append(commands, append([]string{name}, args...))
+		return nil
+	}
+
+	// none, the default, does nothing
+	for _, action := range []string{"", "none"} {
+		TrayClicked(testConfig(t, `{"pictures_dir": %q, "tray_click_action": %q}`, dir, action))
+	}
+	if len(setter.sets) != 0 || len(commands) != 0 || Paused() {
+		t.Errorf("got sets %v and commands %v without an action", setter.sets, commands)
+	}
+
+	TrayClicked(testConfig(t, `{"pictures_dir": %q, "tray_click_action": "change"}`, dir))
+	if len(setter.values("picture-uri")) != 1 {
+		t.Errorf("got picture-uri %v, want a change", setter.values("picture-uri"))
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "tray_click_action": "pause"}`, dir)
+	TrayClicked(cfg)
+	if !Paused() {
+		t.Error("not paused after a click")
+	}
+	TrayClicked(cfg)
+	if Paused() {
+		t.Error("still paused after a second click")
+	}
+
+	TrayClicked(testConfig(t, `{"pictures_dir": %q, "tray_click_action": "open_dir"}`, dir))
+	if want := [][]string{{"xdg-open", dir}}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("got commands %v, want %v", commands, want)
+	}
+}
+
+func TestTrayClickActionValidate(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "tray_click_action": "double"}`)); err == nil {
+		t.Error("got no error for an unknown tray_click_action")
+	}
+}
+
+func TestControlClick(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q, "interval": "1h", "startup_delay": "0s", "tray_click_action": "change"}`, dir)
+	quit := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		RunLoop(cfg, nil, quit)
+		close(done)
+	}()
+	defer func() {
+		quit <- os.Interrupt
+		<-done
+	}()
+	server := httptest.NewServer(controlHandler(cfg))
+	defer server.Close()
+
+	before := len(setter.values("picture-uri"))
+	resp, err := http.Post(server.URL+"/click", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got %s, want OK", resp.Status)
+	}
+	if n := len(setter.values("picture-uri")); n != before+1 {
+		t.Errorf("got %d changes after the click, want %d", n, before+1)
+	}
+
+	resp, err = http.Get(server.URL + "/click")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("got %s for a GET, want method not allowed", resp.Status)
+	}
+}
@@ -0,0 +1,42 @@
+package bgchanger
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+	"os"
+)
+
+// icoMagic starts every ICO file.
+var icoMagic = []byte{0, 0, 1, 0}
+
+// readIcon reads the icon at filename, checking that it is a PNG, JPEG or
+// ICO image.
+func readIcon(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, icoMagic) {
+		return data, nil
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("'%s' is not a PNG or ICO image: %w", filename, err)
+	}
+	return data, nil
+}
+
+// TrayIcon returns the contents of the icon configured with icon_path, or
+// fallback if there is none or it cannot be used.
+func TrayIcon(cfg *Config, fallback []byte) []byte {
+	if cfg.IconPath == "" {
+		return fallback
+	}
+	data, err := readIcon(cfg.IconPath)
+	if err != nil {
+		log.Printf("Warning: cannot use icon_path, using the default icon: %v", err)
+		return fallback
+	}
+	return data
+}
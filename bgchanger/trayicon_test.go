@@ -0,0 +1,42 @@
+package bgchanger
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrayIcon(t *testing.T) {
+	dir := t.TempDir()
+	fallback := []byte("default icon")
+	png := writeTestPicture(t, filepath.Join(dir, "icon.png"), 22, 22, color.RGBA{0, 0, 0, 255})
+	want, err := os.ReadFile(png)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ico := filepath.Join(dir, "icon.ico")
+	icoData := append(append([]byte{}, icoMagic...), 1, 0, 16, 16)
+	bogus := filepath.Join(dir, "icon.txt")
+	for filename, data := range map[string][]byte{ico: icoData, bogus: []byte("not an image")} {
+		if err := os.WriteFile(filename, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		iconPath string
+		want     []byte
+	}{
+		{"", fallback},
+		{png, want},
+		{ico, icoData},
+		{bogus, fallback},
+		{filepath.Join(dir, "missing.png"), fallback},
+	} {
+		if got := TrayIcon(&Config{IconPath: tc.iconPath}, fallback); !bytes.Equal(got, tc.want) {
+			t.Errorf("icon_path %q: got %d bytes, want %d", tc.iconPath, len(got), len(tc.want))
+		}
+	}
+}
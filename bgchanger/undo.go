@@ -0,0 +1,27 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+)
+
+// Undo applies again the picture that was the background before the most
+// recent change. Only one change can be undone.
+func Undo(cfg *Config) error {
+	state.Lock()
+	previous := state.previous
+	state.Unlock()
+	if previous == "" {
+		return fmt.Errorf("no change to undo")
+	}
+	if err := SetBackground(cfg, previous); err != nil {
+		return fmt.Errorf("failed to undo: %w", err)
+	}
+	state.Lock()
+	state.previous = ""
+	state.Unlock()
+	OnUndoAvailableChange(false)
+	log.Printf("Undid the last change")
+	startManualHold(cfg)
+	return nil
+}
@@ -0,0 +1,40 @@
+package bgchanger
+
+import "testing"
+
+func TestUndo(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	pictures := writeTestPictures(t, dir, 2)
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	var available []bool
+	oldHook := OnUndoAvailableChange
+	OnUndoAvailableChange = func(a bool) { available = append(available, a) }
+	defer func() { OnUndoAvailableChange = oldHook }()
+
+	if err := Undo(cfg); err == nil {
+		t.Error("undid a change before any change")
+	}
+	for _, p := range pictures {
+		if err := SetBackground(cfg, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Undo(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if uri := setter.schemaValues(backgroundSchema, "picture-uri"); uri[len(uri)-1] != "file://"+pictures[0] {
+		t.Errorf("got picture-uri %v after undo, want %s", uri, pictures[0])
+	}
+	if cur := getStatus().Current; cur != pictures[0] {
+		t.Errorf("got current %s after undo, want %s", cur, pictures[0])
+	}
+	// only one change can be undone
+	if err := Undo(cfg); err == nil {
+		t.Error("undid the undo")
+	}
+	// the item is disabled until there is a change to undo, and after undoing
+	if len(available) < 3 || available[0] || !available[1] || available[len(available)-1] {
+		t.Errorf("got undo availability %v, want false, true, ..., false", available)
+	}
+}
@@ -0,0 +1,130 @@
+package bgchanger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version of bgchanger, set at build time with
+// -ldflags "-X github.com/insomniacslk/gnome-background-changer/bgchanger.Version=v1.2.3".
+var Version = "dev"
+
+const updateCheckCooldown = 24 * time.Hour
+
+// releasesURL is where the latest release is looked up.
+var releasesURL = "https://api.github.com/repos/insomniacslk/gnome-background-changer/releases/latest"
+
+// release is the part of a GitHub release that matters here.
+type release struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// parseVersion parses a version like "v1.2.3", ignoring any pre-release or
+// build suffix.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// newerVersion reports whether version a is newer than version b. Versions
+// that cannot be parsed are never newer, nor older.
+func newerVersion(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// updateCheckDue reports whether the last update check is old enough for a
+// new one.
+func updateCheckDue(last, now time.Time) bool {
+	return now.Sub(last) >= updateCheckCooldown || now.Before(last)
+}
+
+func latestRelease() (*release, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(releasesURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var r release
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &r, nil
+}
+
+// CheckUpdates looks for a newer release at most once a day, if check_updates
+// is set, and shows a notification the first time one is found. Nothing is
+// installed. Failures, e.g. when offline, are only logged in debug mode.
+func CheckUpdates(cfg *Config) {
+	if !cfg.CheckUpdates {
+		return
+	}
+	if _, ok := parseVersion(Version); !ok {
+		debugf("not checking for updates of a %s build", Version)
+		return
+	}
+	st := loadPersistedState()
+	if !updateCheckDue(st.UpdateCheck, time.Now()) {
+		return
+	}
+	r, err := latestRelease()
+	if err != nil {
+		debugf("cannot check for updates: %v", err)
+		return
+	}
+	notify := newerVersion(r.TagName, Version) && r.TagName != st.NotifiedVersion
+	err = updatePersistedState(func(st *persistedState) {
+		st.UpdateCheck = time.Now()
+		if notify {
+			st.NotifiedVersion = r.TagName
+		}
+	})
+	if err != nil {
+		debugf("cannot save the update check: %v", err)
+	}
+	if !notify {
+		return
+	}
+	msg := fmt.Sprintf(Tr("update_available"), r.TagName, r.HTMLURL)
+	log.Print(msg)
+	if err := runCommand("notify-send", "--app-name="+Progname, Progname, msg); err != nil {
+		debugf("cannot show the update notification: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package bgchanger
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewerVersion(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.4", "v1.2.3", true},
+		{"v1.10.0", "v1.9.9", true},
+		{"v2.0", "v1.99.99", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2", "v1.2.0", false},
+		{"v1.2.1", "v1.2", true},
+		{"1.2.3", "v1.2.2", true},
+		{"v1.2.3-rc1", "v1.2.2", true},
+		{"v1.2.3", "v1.2.3+build", false},
+		{"v1.2.2", "v1.2.3", false},
+		{"latest", "v1.0.0", false},
+		{"v1.0.0", "dev", false},
+	} {
+		if got := newerVersion(tc.a, tc.b); got != tc.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestUpdateCheckDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		last time.Time
+		want bool
+	}{
+		{time.Time{}, true},
+		{now.Add(-time.Hour), false},
+		{now.Add(-updateCheckCooldown), true},
+		// a clock set back
+		{now.Add(time.Hour), true},
+	} {
+		if got := updateCheckDue(tc.last, now); got != tc.want {
+			t.Errorf("updateCheckDue(%v) = %v, want %v", tc.last, got, tc.want)
+		}
+	}
+}
+
+func TestCheckUpdates(t *testing.T) {
+	testEnv(t)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"tag_name": "v1.3.0", "html_url": "https://example.com/v1.3.0"}`)
+	}))
+	defer srv.Close()
+	oldURL, oldVersion := releasesURL, Version
+	releasesURL, Version = srv.URL, "v1.2.0"
+	t.Cleanup(func() { releasesURL, Version = oldURL, oldVersion })
+	var notifications []string
+	runCommand = func(name string, args ...string) error {
+		if name == "notify-send" {
+			notifications = append(notifications, strings.Join(args, " "))
+		}
+		return nil
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": "/p", "check_updates": true}`)
+	CheckUpdates(cfg)
+	if requests != 1 || len(notifications) != 1 {
+		t.Fatalf("got %d requests and notifications %v, want one of each", requests, notifications)
+	}
+	if !strings.Contains(notifications[0], "v1.3.0") || !strings.Contains(notifications[0], "https://example.com/v1.3.0") {
+		t.Errorf("got notification %q, want the version and the link", notifications[0])
+	}
+
+	// at most one check a day
+	CheckUpdates(cfg)
+	if requests != 1 {
+		t.Errorf("got %d requests, want no check within the cooldown", requests)
+	}
+
+	// and a version is only notified once
+	if err := updatePersistedState(func(st *persistedState) { st.UpdateCheck = time.Time{} }); err != nil {
+		t.Fatal(err)
+	}
+	CheckUpdates(cfg)
+	if requests != 2 || len(notifications) != 1 {
+		t.Errorf("got %d requests and notifications %v, want a check without a new notification", requests, notifications)
+	}
+}
+
+func TestCheckUpdatesOffline(t *testing.T) {
+	testEnv(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	oldURL, oldVersion := releasesURL, Version
+	releasesURL, Version = srv.URL, "v1.2.0"
+	t.Cleanup(func() { releasesURL, Version = oldURL, oldVersion })
+	runCommand = func(name string, args ...string) error {
+		t.Errorf("got command %s while offline", name)
+		return nil
+	}
+	CheckUpdates(testConfig(t, `{"pictures_dir": "/p", "check_updates": true}`))
+	// a failed check is retried at the next start
+	if st := loadPersistedState(); !st.UpdateCheck.IsZero() {
+		t.Errorf("got update check at %v, want none recorded while offline", st.UpdateCheck)
+	}
+}
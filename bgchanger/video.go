@@ -0,0 +1,104 @@
+package bgchanger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var videoExtensions = []string{"mp4", "webm"}
+
+// videoStopTimeout is how long a video player has to exit after SIGTERM
+// before being killed.
+const videoStopTimeout = 2 * time.Second
+
+// video is the player launched for the current video background, if any.
+var video = struct {
+	sync.Mutex
+	cmd  *exec.Cmd
+	done chan struct{}
+}{}
+
+func isVideo(filename string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(filename)), ".")
+	for _, v := range videoExtensions {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
+// filterVideos removes the videos from the candidates.
+func filterVideos(pictures []string) []string {
+	var kept []string
+	for _, p := range pictures {
+		if !isVideo(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// videoCommand returns the command running the video backend, a shell
+// command which gets the video as its first argument and in
+// $BGCHANGER_IMAGE, like the hooks.
+func videoCommand(backend, filename string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", backend+` "$@"`, Progname, filename)
+	cmd.Env = append(os.Environ(), "BGCHANGER_IMAGE="+filename)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// run in its own process group, so that stopping it also stops the
+	// player started by the shell
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// playVideo stops the previous video player, if any, and starts the video
+// backend for the given video.
+func playVideo(cfg *Config, filename string) error {
+	if cfg.VideoBackend == "" {
+		return fmt.Errorf("cannot play '%s', video_backend is not configured", filename)
+	}
+	StopVideo()
+	cmd := videoCommand(cfg.VideoBackend, filename)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start video backend: %w", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			debugf("video backend exited: %v", err)
+		}
+		close(done)
+	}()
+	video.Lock()
+	video.cmd, video.done = cmd, done
+	video.Unlock()
+	return nil
+}
+
+// StopVideo stops the player of the current video background, if any.
+func StopVideo() {
+	video.Lock()
+	cmd, done := video.cmd, video.done
+	video.cmd, video.done = nil, nil
+	video.Unlock()
+	if cmd == nil {
+		return
+	}
+	pgid := -cmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(videoStopTimeout):
+		log.Printf("Warning: video backend did not exit, killing it")
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-done
+	}
+}
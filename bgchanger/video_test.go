@@ -0,0 +1,109 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVideoCommand(t *testing.T) {
+	cmd := videoCommand("mpvpaper -o loop '*'", "/videos/a b.mp4")
+	want := []string{"sh", "-c", `mpvpaper -o loop '*' "$@"`, Progname, "/videos/a b.mp4"}
+	if strings.Join(cmd.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("got command %q, want %q", cmd.Args, want)
+	}
+	found := false
+	for _, e := range cmd.Env {
+		found = found || e == "BGCHANGER_IMAGE=/videos/a b.mp4"
+	}
+	if !found {
+		t.Error("BGCHANGER_IMAGE is not set")
+	}
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		t.Error("the backend does not run in its own process group")
+	}
+}
+
+func TestVideoBackend(t *testing.T) {
+	setter := testEnv(t)
+	dir := t.TempDir()
+	var videos []string
+	for _, name := range []string{"a.mp4", "b.webm"} {
+		videos = append(videos, filepath.Join(dir, name))
+		if err := os.WriteFile(videos[len(videos)-1], []byte("video"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	picture := writeTestPictures(t, dir, 1)[0]
+	played := filepath.Join(t.TempDir(), "played")
+	cfg := testConfig(t, `{"pictures_dir": %q, "video_backend": %q}`, dir, `echo "$BGCHANGER_IMAGE" >> `+played+`; exec sleep 60 #`)
+	defer StopVideo()
+	waitPlayed := func(n int) string {
+		t.Helper()
+		var data []byte
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if data, _ = os.ReadFile(played); strings.Count(string(data), "\n") >= n {
+				break
+			}
+		}
+		return string(data)
+	}
+
+	if err := SetBackground(cfg, videos[0]); err != nil {
+		t.Fatal(err)
+	}
+	waitPlayed(1)
+	video.Lock()
+	first := video.done
+	video.Unlock()
+	if err := SetBackground(cfg, videos[1]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-first:
+	default:
+		t.Error("the previous video player is still running")
+	}
+	if len(setter.values("picture-uri")) != 0 {
+		t.Errorf("got picture-uri %v for videos", setter.values("picture-uri"))
+	}
+	if data := waitPlayed(2); data != videos[0]+"\n"+videos[1]+"\n" {
+		t.Errorf("the backend played %q, want both videos", data)
+	}
+
+	// an image stops the video
+	video.Lock()
+	second := video.done
+	video.Unlock()
+	if err := SetBackground(cfg, picture); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-second:
+	default:
+		t.Error("the video player is still running after setting an image")
+	}
+	if uri := setter.get("picture-uri"); uri != "file://"+picture {
+		t.Errorf("got picture-uri %s, want %s", uri, picture)
+	}
+}
+
+func TestVideosNeedBackend(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.mp4"), []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	picture := writeTestPictures(t, dir, 1)[0]
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	for i := 0; i < 10; i++ {
+		if p, err := PickPicture(cfg); err != nil || p != picture {
+			t.Fatalf("picked %s, %v without video_backend, want %s", p, err, picture)
+		}
+	}
+	if err := SetBackground(cfg, filepath.Join(dir, "a.mp4")); err == nil {
+		t.Error("played a video without video_backend")
+	}
+}
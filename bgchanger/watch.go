@@ -0,0 +1,169 @@
+package bgchanger
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// watchPollInterval is how often the watcher checks the directories. It is
+// a variable so that tests can make it shorter.
+var watchPollInterval = 2 * time.Second
+
+// defaultWatchGrace is how long the watcher waits for changes to settle
+// before rescanning.
+const defaultWatchGrace = 2 * time.Second
+
+// debouncer calls fn once after trigger stops being called for window, so
+// that a burst of events results in a single call.
+type debouncer struct {
+	sync.Mutex
+	window time.Duration
+	fn     func()
+	timer  *time.Timer
+}
+
+func (d *debouncer) trigger() {
+	d.Lock()
+	defer d.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.window, d.fn)
+}
+
+// cancel drops the pending call, if any.
+func (d *debouncer) cancel() {
+	d.Lock()
+	defer d.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// watchedDirs returns the local pictures directories that the watcher
+// checks.
+func watchedDirs(cfg *Config) []string {
+	dirs := localDirs(cfg)
+	if cfg.DarkPicturesDir != "" {
+		dirs = append(dirs, cfg.DarkPicturesDir)
+	}
+	return dirs
+}
+
+// dirModTimes returns the modification times of the given directories, which
+// change when files are added, removed or renamed.
+func dirModTimes(dirs []string) map[string]time.Time {
+	mtimes := make(map[string]time.Time)
+	for _, dir := range dirs {
+		if st, err := os.Stat(dir); err == nil {
+			mtimes[dir] = st.ModTime()
+		}
+	}
+	return mtimes
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for dir, t := range a {
+		if !b[dir].Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// directoryChanges is notified when the watched directories changed and
+// settled.
+var directoryChanges = make(chan struct{}, 1)
+
+// DirectoryChanges returns a channel that receives a value when the watched
+// pictures directories changed, at which point ApplyDirectoryChange should
+// be called.
+func DirectoryChanges() <-chan struct{} {
+	return directoryChanges
+}
+
+// dirWatcher is the running directory watcher, if any, with the directories
+// and grace it watches with.
+var dirWatcher = struct {
+	sync.Mutex
+	dirs  []string
+	grace time.Duration
+	stop  chan struct{}
+}{}
+
+// StartWatcher watches the local pictures directories if watch is set, and
+// notifies DirectoryChanges once changes settle for watch_grace, so that
+// copying many files results in a single rescan. ApplyConfig calls it again,
+// so that the watcher follows the directories of the new config.
+func StartWatcher(cfg *Config) {
+	dirs, grace := watchedDirs(cfg), time.Duration(cfg.WatchGrace)
+	dirWatcher.Lock()
+	defer dirWatcher.Unlock()
+	if dirWatcher.stop != nil && cfg.Watch && grace == dirWatcher.grace && reflect.DeepEqual(dirs, dirWatcher.dirs) {
+		return
+	}
+	stopWatcherLocked()
+	if !cfg.Watch {
+		return
+	}
+	log.Printf("Watching the pictures directories for changes")
+	stop := make(chan struct{})
+	dirWatcher.dirs, dirWatcher.grace, dirWatcher.stop = dirs, grace, stop
+	go watchDirs(dirs, grace, stop)
+}
+
+// stopWatcherLocked stops the directory watcher, if running. The dirWatcher
+// lock must be held.
+func stopWatcherLocked() {
+	if dirWatcher.stop != nil {
+		close(dirWatcher.stop)
+	}
+	dirWatcher.dirs, dirWatcher.grace, dirWatcher.stop = nil, 0, nil
+}
+
+func watchDirs(dirs []string, grace time.Duration, stop chan struct{}) {
+	d := &debouncer{
+		window: grace,
+		fn: func() {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			select {
+			case directoryChanges <- struct{}{}:
+			default:
+			}
+		},
+	}
+	defer d.cancel()
+	t := time.NewTicker(watchPollInterval)
+	defer t.Stop()
+	last := dirModTimes(dirs)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		if now := dirModTimes(dirs); !sameModTimes(last, now) {
+			debugf("pictures directories changed")
+			last = now
+			d.trigger()
+		}
+	}
+}
+
+// ApplyDirectoryChange rescans the pictures after the watched directories
+// changed.
+func ApplyDirectoryChange(cfg *Config) {
+	if _, err := Rescan(cfg); err != nil {
+		log.Printf("Error: rescan failed: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package bgchanger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDebouncerCoalescesBursts(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	done := make(chan struct{}, 10)
+	d := &debouncer{
+		window: 200 * time.Millisecond,
+		fn: func() {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			done <- struct{}{}
+		},
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+
+	// a burst of events within the window
+	for i := 0; i < 20; i++ {
+		d.trigger()
+		time.Sleep(time.Millisecond)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("got %d rescans during the burst, want none", n)
+	}
+	<-done
+	time.Sleep(300 * time.Millisecond)
+	if n := count(); n != 1 {
+		t.Errorf("got %d rescans after the burst, want exactly one", n)
+	}
+
+	// a later event rescans again
+	d.trigger()
+	<-done
+	if n := count(); n != 2 {
+		t.Errorf("got %d rescans after a second event, want 2", n)
+	}
+}
+
+func TestDirModTimes(t *testing.T) {
+	testEnv(t)
+	dir, darkDir := t.TempDir(), t.TempDir()
+	cfg := testConfig(t, `{"pictures_dir": %q, "dark_pictures_dir": %q}`, dir, darkDir)
+	dirs := watchedDirs(cfg)
+	before := dirModTimes(dirs)
+	if len(before) != 2 {
+		t.Fatalf("got %v, want both directories", before)
+	}
+	if !sameModTimes(before, dirModTimes(dirs)) {
+		t.Error("got changes without any")
+	}
+	// directory mtimes can have a coarse resolution
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(darkDir, past, past); err != nil {
+		t.Fatal(err)
+	}
+	before = dirModTimes(dirs)
+	writeTestPictures(t, darkDir, 1)
+	if sameModTimes(before, dirModTimes(dirs)) {
+		t.Errorf("adding %s went unnoticed", filepath.Join(darkDir, "0.png"))
+	}
+}
+
+func TestWatchGraceDefault(t *testing.T) {
+	cfg := testConfig(t, `{"pictures_dir": "/p", "watch": true}`)
+	if time.Duration(cfg.WatchGrace) != defaultWatchGrace {
+		t.Errorf("got watch_grace %s, want the default %s", time.Duration(cfg.WatchGrace), defaultWatchGrace)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	testEnv(t)
+	old := watchPollInterval
+	watchPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchPollInterval = old })
+	dir, other := t.TempDir(), t.TempDir()
+	// directory mtimes can have a coarse resolution
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(other, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	// not watching without watch, until a reload sets it
+	cfg := testConfig(t, `{"pictures_dir": %q}`, dir)
+	StartWatcher(cfg)
+	if dirWatcher.stop != nil {
+		t.Fatal("watching the directories without watch")
+	}
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q, "watch": true, "watch_grace": "10ms"}`, dir))
+	dirWatcher.Lock()
+	stop := dirWatcher.stop
+	dirWatcher.Unlock()
+	if stop == nil {
+		t.Fatal("setting watch did not start the watcher")
+	}
+
+	// a reload changing the directories restarts the watcher on them
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q, "watch": true, "watch_grace": "10ms"}`, other))
+	dirWatcher.Lock()
+	restarted := dirWatcher.stop != stop
+	dirs := dirWatcher.dirs
+	dirWatcher.Unlock()
+	if !restarted || len(dirs) != 1 || dirs[0] != other {
+		t.Fatalf("watching %v after changing pictures_dir, want %s", dirs, other)
+	}
+
+	// let the watcher see the initial directories first
+	time.Sleep(10 * watchPollInterval)
+	writeTestPictures(t, other, 1)
+	select {
+	case <-directoryChanges:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the directory change was not notified")
+	}
+	// the main loop rescans
+	ApplyDirectoryChange(cfg)
+	if listing, ok := cachedListing(other); !ok || len(listing.pictures) != 1 {
+		t.Errorf("got listing %+v after the rescan, want the new picture", listing)
+	}
+
+	ApplyConfig(cfg, testConfig(t, `{"pictures_dir": %q}`, other))
+	dirWatcher.Lock()
+	stopped := dirWatcher.stop == nil
+	dirWatcher.Unlock()
+	if !stopped {
+		t.Error("removing watch did not stop the watcher")
+	}
+}
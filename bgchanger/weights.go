@@ -0,0 +1,42 @@
+package bgchanger
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// extensionKey normalizes a file extension for extension_weights, so that
+// "JPG", ".jpg" and "jpg" are the same.
+func extensionKey(ext string) string {
+	return strings.TrimPrefix(strings.ToLower(ext), ".")
+}
+
+func validateExtensionWeights(weights map[string]float64) error {
+	for ext, w := range weights {
+		if w <= 0 {
+			return fmt.Errorf("extension_weights for '%s' must be positive", ext)
+		}
+	}
+	return nil
+}
+
+// pictureWeight returns the function giving the selection weight of a
+// picture, which is the product of the weight of its rating and of its
+// extension. Unlisted extensions have a weight of 1.
+func pictureWeight(cfg *Config) func(string) float64 {
+	if len(cfg.ExtensionWeights) == 0 {
+		return ratingWeight
+	}
+	weights := make(map[string]float64, len(cfg.ExtensionWeights))
+	for ext, w := range cfg.ExtensionWeights {
+		weights[extensionKey(ext)] = w
+	}
+	return func(filename string) float64 {
+		w := ratingWeight(filename)
+		if ew, ok := weights[extensionKey(path.Ext(filename))]; ok {
+			w *= ew
+		}
+		return w
+	}
+}
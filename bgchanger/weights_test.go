@@ -0,0 +1,50 @@
+package bgchanger
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtensionWeights(t *testing.T) {
+	testEnv(t)
+	dir := t.TempDir()
+	png := writeTestPictures(t, dir, 1)[0]
+	jpg := filepath.Join(dir, "photo.JPG")
+	if err := writeJPEG(jpg, image.NewGray(image.Rect(0, 0, 16, 9))); err != nil {
+		t.Fatal(err)
+	}
+	counts := func(cfg *Config) map[string]int {
+		c := make(map[string]int)
+		for i := 0; i < 2000; i++ {
+			p, err := PickPicture(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c[p]++
+		}
+		return c
+	}
+
+	cfg := testConfig(t, `{"pictures_dir": %q, "extension_weights": {".PNG": 4}}`, dir)
+	c := counts(cfg)
+	// the expected ratio is 4
+	if c[png] < 3*c[jpg] {
+		t.Errorf("picked the PNG %d times and the JPEG %d times, want the PNG about 4 times as often", c[png], c[jpg])
+	}
+
+	// the weights multiply with the ratings
+	if err := ratePicture(jpg, 2); err != nil {
+		t.Fatal(err)
+	}
+	cfg = testConfig(t, `{"pictures_dir": %q, "extension_weights": {"png": 4, "jpg": 0.5}}`, dir)
+	c = counts(cfg)
+	// the expected ratio is 2
+	if c[png] < c[jpg]*3/2 || c[png] > c[jpg]*3 {
+		t.Errorf("picked the PNG %d times and the liked JPEG %d times, want the PNG about twice as often", c[png], c[jpg])
+	}
+
+	if _, err := ParseConfig([]byte(`{"pictures_dir": "/p", "extension_weights": {"jpg": 0}}`)); err == nil {
+		t.Error("accepted a zero extension weight")
+	}
+}
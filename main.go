@@ -2,186 +2,715 @@ package main
 
 import (
 	_ "embed"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
-	"os/exec"
-	"path"
-	"strings"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/getlantern/systray"
 	"github.com/insomniacslk/editor"
-	"github.com/insomniacslk/xjson"
-	"github.com/kirsle/configdir"
+	"github.com/insomniacslk/gnome-background-changer/bgchanger"
 )
 
-const progname = "bgchanger"
-
-var supportedExtensions = []string{"png", "jpg"}
-
 //go:embed config.json.example
 var exampleConfig []byte
 
-func main() {
-	rand.Seed(time.Now().UnixNano())
-	configFile, cfg, err := loadConfig()
-	if err != nil {
-		log.Fatalf("Failed to open config file: %v", err)
+// instanceLock prevents several instances from changing the background at
+// the same time.
+var instanceLock *os.File
+
+// blockConfirmation and clearBlocklistConfirmation gate the destructive menu
+// items.
+var blockConfirmation, clearBlocklistConfirmation bgchanger.Confirmation
+
+var (
+	flagList               = flag.Bool("list", false, "Print the candidate pictures one per line and exit")
+	flagJSON               = flag.Bool("json", false, "Used with -list, print the candidates as a JSON array")
+	flagExplain            = flag.Bool("explain", false, "Print every picture found in the sources and why it is or isn't a candidate, then exit")
+	flagContactSheet       = flag.String("contactsheet", "", "Write a PNG grid of the thumbnails of the candidates to this file and exit")
+	flagContactColumns     = flag.Int("contactsheet-columns", bgchanger.DefaultContactSheetColumns, "Used with -contactsheet, the number of columns of the grid")
+	flagContactSize        = flag.Int("contactsheet-size", bgchanger.DefaultContactSheetSize, "Used with -contactsheet, the size in pixels of the thumbnails")
+	flagApply              = &applyFlag{index: -1}
+	flagSet                = flag.String("set", "", "Apply the candidate with this file name, or the picture at this absolute path, and exit")
+	flagInstallAutostart   = flag.Bool("install-autostart", false, "Start bgchanger on login and exit")
+	flagUninstallAutostart = flag.Bool("uninstall-autostart", false, "Stop starting bgchanger on login and exit")
+	flagChange             = flag.Bool("change", false, "Ask the running instance to change background and exit")
+	flagStatus             = flag.Bool("status", false, "Print the state of the running instance as JSON and exit")
+	flagConfig             = flag.String("config", "", "Config file path or http(s) URL, instead of the default config file")
+	flagPrintConfig        = flag.Bool("print-config", false, "Print the config with the defaults filled in and the paths resolved, as JSON, and exit")
+	flagExport             = flag.String("export", "", "Save the config and state files to this zip file and exit")
+	flagExportSecrets      = flag.Bool("export-secrets", false, "Used with -export, don't redact credentials in the config")
+	flagImport             = flag.String("import", "", "Restore the config and state files from this zip file and exit")
+	flagDebug              = flag.Bool("debug", false, "Enable debug logging")
+	flagVersion            = flag.Bool("version", false, "Print the version and exit")
+	flagSelfTest           = flag.Bool("selftest", false, "Run the whole pipeline on generated pictures without a desktop, report whether it works and exit")
+	flagHeadless           = flag.Bool("headless", false, "Run without a tray icon, even if a tray is available")
+	flagOrganize           = flag.Bool("organize", false, "Print the blocked and lowest rated pictures that would be moved to rejects_dir and exit, use with -apply to move them")
+)
+
+func init() {
+	flag.Var(flagApply, "apply", "Apply the picture at this index in the -list output and exit, or used with -organize, move the pictures")
+}
+
+// applyFlag is -apply, which takes an index on its own, and no value when
+// used with -organize.
+type applyFlag struct {
+	set   bool
+	index int
+}
+
+func (f *applyFlag) String() string {
+	if f == nil || f.index < 0 {
+		return ""
 	}
-	systray.Run(
-		func() { onReady(configFile, cfg) },
-		onExit,
-	)
+	return strconv.Itoa(f.index)
 }
 
-// Config contains the program's configuration.
-type Config struct {
-	PicturesDir   string         `json:"pictures_dir"`
-	Interval      xjson.Duration `json:"interval"`
-	Editor        string         `json:"editor"`
-	ChangeOnStart bool           `json:"change_on_start"`
+func (f *applyFlag) Set(s string) error {
+	f.set = true
+	if s == "true" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid index '%s'", s)
+	}
+	f.index = n
+	return nil
 }
 
-func getRandomPicture(dirname string) (string, error) {
-	files, err := ioutil.ReadDir(dirname)
-	if err != nil {
-		return "", fmt.Errorf("failed to read directory '%s': %w", dirname, err)
-	}
-	var pictures []string
-	for _, f := range files {
-		for _, ext := range supportedExtensions {
-			if strings.HasSuffix(strings.ToLower(f.Name()), ext) {
-				pictures = append(pictures, f.Name())
-				break
+// IsBoolFlag lets -apply be used without a value. In that case parseFlags
+// takes the index from the next argument, if any, so that `-apply 3` keeps
+// working.
+func (f *applyFlag) IsBoolFlag() bool { return true }
+
+func parseFlags() {
+	flag.Parse()
+	if flagApply.set && flagApply.index < 0 && flag.NArg() > 0 {
+		if n, err := strconv.Atoi(flag.Arg(0)); err == nil && n >= 0 {
+			flagApply.index = n
+			if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+				os.Exit(2)
 			}
 		}
 	}
-	if len(pictures) == 0 {
-		return "", fmt.Errorf("no pictures found")
+	if flagApply.set && flagApply.index < 0 && !*flagOrganize {
+		fmt.Fprintln(flag.CommandLine.Output(), "-apply needs an index, unless used with -organize")
+		flag.Usage()
+		os.Exit(2)
 	}
-	rand.Shuffle(len(pictures), func(i, j int) { pictures[i], pictures[j] = pictures[j], pictures[i] })
-	return path.Join(dirname, pictures[0]), nil
 }
 
-func loadConfig() (string, *Config, error) {
-	cfg := Config{}
-
-	configPath := configdir.LocalConfig(progname)
-	configFile := path.Join(configPath, "config.json")
-	log.Printf("Trying to load config file %s", configFile)
-	if err := configdir.MakePath(configPath); err != nil {
-		if os.IsNotExist(err) {
-			return configFile, &cfg, nil
+func main() {
+	parseFlags()
+	bgchanger.Debug = *flagDebug
+	rand.Seed(time.Now().UnixNano())
+	if *flagVersion {
+		fmt.Println(bgchanger.Version)
+		return
+	}
+	if *flagSelfTest {
+		if err := bgchanger.SelfTest(); err != nil {
+			fmt.Printf("FAIL: %v\n", err)
+			os.Exit(1)
 		}
-		return configFile, nil, fmt.Errorf("failed to create config path '%s': %w", configPath, err)
+		fmt.Println("PASS")
+		return
 	}
-	data, err := os.ReadFile(configFile)
+	// the actions below may write files before the config is loaded
+	if err := bgchanger.LoadWriteAllowlist(*flagConfig); err != nil {
+		log.Fatalf("Failed to load write_allowlist: %v", err)
+	}
+	if *flagInstallAutostart {
+		if err := bgchanger.InstallAutostart(); err != nil {
+			log.Fatalf("Failed to install autostart: %v", err)
+		}
+		return
+	}
+	if *flagUninstallAutostart {
+		if err := bgchanger.UninstallAutostart(); err != nil {
+			log.Fatalf("Failed to uninstall autostart: %v", err)
+		}
+		return
+	}
+	if *flagImport != "" {
+		if err := bgchanger.ImportBundle(*flagConfig, *flagImport); err != nil {
+			log.Fatalf("Failed to import: %v", err)
+		}
+		return
+	}
+	if *flagChange {
+		if err := bgchanger.SignalRunning(); err != nil {
+			log.Fatalf("Failed to change background: %v", err)
+		}
+		return
+	}
+	configFile, cfg, err := bgchanger.LoadConfigFrom(*flagConfig)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// create a template file and open it with the default editor
-			if err := editor.Open(configFile); err != nil {
-				return configFile, &cfg, fmt.Errorf("failed to create config file: %w", err)
-			}
-			// re-read the config file
-			data, err = os.ReadFile(configFile)
-			if err != nil {
-				return configFile, &cfg, fmt.Errorf("failed to read config file: %w", err)
-			}
-			// after this point, the newly created config file will be parsed by
-			// the rest of this function.
-		} else {
-			return configFile, nil, fmt.Errorf("failed to read config file: %w", err)
+		log.Fatalf("Failed to open config file: %v", err)
+	}
+	if *flagExport != "" {
+		if err := bgchanger.ExportBundle(configFile, cfg, *flagExport, *flagExportSecrets); err != nil {
+			log.Fatalf("Failed to export: %v", err)
 		}
+		return
 	}
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return configFile, nil, fmt.Errorf("failed to unmarshal config file: %w", err)
+	if *flagPrintConfig {
+		if err := bgchanger.PrintConfig(configFile, cfg); err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		return
 	}
-
-	// sanity checks
-	if cfg.PicturesDir == "" {
-		return configFile, nil, fmt.Errorf("pictures_dir cannot be empty")
+	if *flagStatus {
+		if err := bgchanger.PrintStatus(cfg); err != nil {
+			log.Fatalf("Failed to get status: %v", err)
+		}
+		return
 	}
-
-	return configFile, &cfg, nil
+	if *flagList {
+		if err := bgchanger.PrintCandidates(cfg, *flagJSON); err != nil {
+			log.Fatalf("Failed to list candidates: %v", err)
+		}
+		return
+	}
+	if *flagExplain {
+		if err := bgchanger.ExplainCandidates(cfg); err != nil {
+			log.Fatalf("Failed to explain candidates: %v", err)
+		}
+		return
+	}
+	if *flagContactSheet != "" {
+		if err := bgchanger.ContactSheet(cfg, *flagContactSheet, *flagContactColumns, *flagContactSize); err != nil {
+			log.Fatalf("Failed to make contact sheet: %v", err)
+		}
+		return
+	}
+	if *flagOrganize {
+		if err := organize(cfg, flagApply.set); err != nil {
+			log.Fatalf("Failed to organize pictures: %v", err)
+		}
+		return
+	}
+	if err := bgchanger.CheckBackend(); err != nil {
+		log.Fatalf("Cannot change the background: %v", err)
+	}
+	if flagApply.index >= 0 {
+		if err := bgchanger.ApplyIndex(cfg, flagApply.index); err != nil {
+			log.Fatalf("Failed to apply picture: %v", err)
+		}
+		bgchanger.WaitForHooks()
+		return
+	}
+	if *flagSet != "" {
+		if err := bgchanger.ApplyName(cfg, *flagSet); err != nil {
+			log.Fatalf("Failed to apply picture: %v", err)
+		}
+		bgchanger.WaitForHooks()
+		return
+	}
+	lock, err := bgchanger.AcquireLock(bgchanger.LockFile())
+	if err != nil {
+		if err == bgchanger.ErrAlreadyRunning {
+			log.Fatalf("Another instance of %s is already running, use -change to change its background", bgchanger.Progname)
+		}
+		log.Fatalf("Failed to acquire instance lock: %v", err)
+	}
+	instanceLock = lock
+	bgchanger.SetConfigFile(configFile)
+	if cfg.ControlAddr != "" {
+		bgchanger.StartControlServer(cfg)
+	}
+	go bgchanger.CheckUpdates(cfg)
+	bgchanger.StartWatcher(cfg)
+	bgchanger.StartNetworkWatcher(cfg)
+	bgchanger.StartColorSchemeWatcher(cfg)
+	bgchanger.RestoreQueue(cfg)
+	bgchanger.SetupHotkey(cfg)
+	if !*flagHeadless && !bgchanger.TrayAvailable() {
+		log.Printf("No system tray available, running in headless mode")
+		*flagHeadless = true
+	}
+	if *flagHeadless {
+		bgchanger.RunHeadless(cfg)
+		shutdown(cfg)
+		return
+	}
+	systray.Run(
+		func() { onReady(configFile, cfg) },
+		func() { shutdown(cfg) },
+	)
 }
 
-func changeBG(cfg *Config) {
-	filename, err := getRandomPicture(cfg.PicturesDir)
+// organize prints the pictures that -organize moves, or would move unless
+// apply is true.
+func organize(cfg *bgchanger.Config, apply bool) error {
+	moves, err := bgchanger.Organize(cfg, apply)
+	for _, m := range moves {
+		fmt.Printf("%s -> %s\n", m.From, m.To)
+	}
 	if err != nil {
-		log.Printf("Error: cannot get random picture: %v", err)
-		return
+		return err
 	}
-	cmd := exec.Command("gsettings", "set", "org.gnome.desktop.background", "picture-uri", "file://"+filename)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	if err := cmd.Run(); err != nil {
-		log.Printf("Error when changing background: %v", err)
-	} else {
-		log.Printf("Background changed to '%s'", filename)
+	switch {
+	case len(moves) == 0:
+		fmt.Println("Nothing to move")
+	case apply:
+		fmt.Printf("Moved %d pictures\n", len(moves))
+	default:
+		fmt.Printf("Would move %d pictures, run again with -apply to move them\n", len(moves))
 	}
+	return nil
 }
 
-func onReady(configFile string, cfg *Config) {
-	systray.SetIcon(Icon)
+func onReady(configFile string, cfg *bgchanger.Config) {
+	systray.SetIcon(bgchanger.TrayIcon(cfg, Icon))
 	//systray.SetTitle("RandBG")
-	systray.SetTooltip("Change background randomly")
-	mChange := systray.AddMenuItem("Change background now", "Change background with a randomly picked one from your configured directory")
-	var mInterval *systray.MenuItem
-	if cfg.Interval == 0 {
-	} else {
-		mInterval = systray.AddMenuItem(fmt.Sprintf("Background will change every %s", cfg.Interval), "The background will automatically change at the configured interval")
-		mInterval.Disable()
+	systray.SetTooltip(bgchanger.Tr("tooltip"))
+	mChange := systray.AddMenuItem(bgchanger.Tr("change"), bgchanger.Tr("change_tooltip"))
+	mUndo := systray.AddMenuItem(bgchanger.Tr("undo"), bgchanger.Tr("undo_tooltip"))
+	mUndo.Disable()
+	bgchanger.OnUndoAvailableChange = func(available bool) {
+		if available {
+			mUndo.Enable()
+		} else {
+			mUndo.Disable()
+		}
+	}
+	mError := systray.AddMenuItem("", bgchanger.Tr("last_error_tooltip"))
+	mError.Disable()
+	mError.Hide()
+	bgchanger.OnLastErrorChange = func(err error) {
+		if err == nil {
+			systray.SetTooltip(bgchanger.Tr("tooltip"))
+			mError.Hide()
+			return
+		}
+		systray.SetTooltip(fmt.Sprintf(bgchanger.Tr("error_tooltip"), err))
+		mError.SetTitle(fmt.Sprintf(bgchanger.Tr("last_error"), err))
+		mError.Show()
 	}
-	mEdit := systray.AddMenuItem("Edit config", "Open configuration file for editing")
-	mQuit := systray.AddMenuItem("Quit", "Quit the whole app")
+	bgchanger.OnFailureAlert = func(count int, err error) {
+		msg := fmt.Sprintf(bgchanger.Tr("changes_failing"), count, err)
+		systray.SetTooltip(msg)
+		mError.SetTitle(msg)
+		mError.Show()
+	}
+	mInterval := systray.AddMenuItem("", bgchanger.Tr("interval_tooltip"))
+	updateIntervalItem(mInterval, cfg)
+	bgchanger.OnHoldChange = func() { updateIntervalItem(mInterval, cfg) }
+	bgchanger.OnPauseChange = func(bool) { updateIntervalItem(mInterval, cfg) }
+	mBoost := systray.AddMenuItemCheckbox(bgchanger.Tr("boost"), bgchanger.Tr("boost_tooltip"), false)
+	bgchanger.OnBoostChange = func(boosted bool) {
+		if boosted {
+			mBoost.Check()
+		} else {
+			mBoost.Uncheck()
+		}
+	}
+	mLike := systray.AddMenuItem(bgchanger.Tr("like"), bgchanger.Tr("like_tooltip"))
+	mDislike := systray.AddMenuItem(bgchanger.Tr("dislike"), bgchanger.Tr("dislike_tooltip"))
+	mComfort := systray.AddMenuItemCheckbox(bgchanger.Tr("comfort"), bgchanger.Tr("comfort_tooltip"), bgchanger.ComfortEnabled())
+	mFocus := systray.AddMenuItemCheckbox(bgchanger.Tr("focus"), bgchanger.Tr("focus_tooltip"), bgchanger.FocusEnabled())
+	mBlock := systray.AddMenuItem(bgchanger.Tr("block"), bgchanger.Tr("block_tooltip"))
+	blocked := newBlocklistMenu()
+	blocked.refresh(cfg)
+	bgchanger.OnBlocklistChange = func() { blocked.refresh(cfg) }
+	mRescan := systray.AddMenuItem(bgchanger.Tr("rescan"), bgchanger.Tr("rescan_tooltip"))
+	bgchanger.OnPicturesAvailableChange = func(available bool) {
+		if available {
+			mChange.Enable()
+		} else {
+			mChange.Disable()
+		}
+	}
+	mPreview := systray.AddMenuItem(bgchanger.Tr("preview_next"), bgchanger.Tr("preview_next_tooltip"))
+	mQueue := systray.AddMenuItem(bgchanger.Tr("queue_add"), bgchanger.Tr("queue_add_tooltip"))
+	mClearQueue := systray.AddMenuItem("", bgchanger.Tr("queue_clear_tooltip"))
+	bgchanger.OnQueueChange = func(length int) { updateQueueItem(mClearQueue, length) }
+	updateQueueItem(mClearQueue, bgchanger.QueueLength())
+	profileClicks := make(chan string)
+	profileItems := make(map[string]*systray.MenuItem)
+	if names := bgchanger.ProfileNames(cfg); len(names) > 0 {
+		mProfile := systray.AddMenuItem(bgchanger.Tr("profile"), bgchanger.Tr("profile_tooltip"))
+		for _, name := range names {
+			item := mProfile.AddSubMenuItemCheckbox(name, "", name == cfg.Profile)
+			profileItems[name] = item
+			go func(name string) {
+				for range item.ClickedCh {
+					profileClicks <- name
+				}
+			}(name)
+		}
+	}
+	mAutostart := systray.AddMenuItemCheckbox(bgchanger.Tr("autostart"), bgchanger.Tr("autostart_tooltip"), bgchanger.IsAutostartInstalled())
+	mChooseDir := systray.AddMenuItem(bgchanger.Tr("choose_folder"), bgchanger.Tr("choose_folder_tooltip"))
+	updateChooseDirItem(mChooseDir, cfg)
+	mEdit := systray.AddMenuItem(bgchanger.Tr("edit"), bgchanger.Tr("edit_tooltip"))
+	mQuit := systray.AddMenuItem(bgchanger.Tr("quit"), bgchanger.Tr("quit_tooltip"))
 
 	// Sets the icon of a menu item. Only available on Mac and Windows.
 	mQuit.SetIcon(Icon)
 
+	// systray cannot remove menu items, so after a config change the ones
+	// depending on it are relabelled, checked, enabled or hidden in place
+	bgchanger.OnConfigApplied = func(previous *bgchanger.Config) {
+		if cfg.IconPath != previous.IconPath {
+			systray.SetIcon(bgchanger.TrayIcon(cfg, Icon))
+		}
+		updateIntervalItem(mInterval, cfg)
+		updateProfileItems(profileItems, cfg)
+		updateChooseDirItem(mChooseDir, cfg)
+		blocked.refresh(cfg)
+	}
+
 	// sets the editor
 	if cfg.Editor != "" {
 		editor.Set(cfg.Editor)
 	}
-	if cfg.ChangeOnStart {
-		changeBG(cfg)
+	bgchanger.OnIntervalChange = func() { updateIntervalItem(mInterval, cfg) }
+	// the menu actions are run by the main loop, which owns cfg
+	events := make(chan bgchanger.TrayEvent)
+	editConfig := func() bool {
+		intervalChanged, err := bgchanger.EditConfig(configFile, cfg)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return false
+		}
+		return intervalChanged
 	}
-
 	go func() {
-		var (
-			timer       *time.Ticker
-			ignoreTimer = false
-		)
-		if cfg.Interval > 0 {
-			timer = time.NewTicker(time.Duration(cfg.Interval))
-			log.Printf("Changing background picture every %s", cfg.Interval)
-		} else {
-			// a non-positive interval means "don't change background". This
-			// creates a ticker with a valid time, but it will be ignored
-			timer = time.NewTicker(time.Hour)
-			ignoreTimer = true
-		}
 		for {
+			var event bgchanger.TrayEvent
 			select {
 			case <-mQuit.ClickedCh:
 				systray.Quit()
+				continue
 			case <-mEdit.ClickedCh:
-				if err := editor.Open(configFile); err != nil {
-					log.Printf("Error opening config file: %v", err)
+				event = editConfig
+			case <-mChooseDir.ClickedCh:
+				event = func() bool {
+					newCfg, err := bgchanger.ChoosePicturesDir(configFile, cfg)
+					if errors.Is(err, bgchanger.ErrNoFolderPicker) {
+						log.Printf("Warning: %v, opening the config file instead", err)
+						return editConfig()
+					}
+					if err != nil {
+						log.Printf("Error: cannot set the pictures folder: %v", err)
+						return false
+					}
+					if newCfg == nil {
+						return false
+					}
+					bgchanger.ApplyConfig(cfg, newCfg)
+					if _, err := bgchanger.Rescan(cfg); err != nil {
+						log.Printf("Error: rescan failed: %v", err)
+					}
+					return false
 				}
 			case <-mChange.ClickedCh:
-				changeBG(cfg)
-			case <-timer.C:
-				if !ignoreTimer {
-					changeBG(cfg)
+				event = func() bool {
+					bgchanger.ManualChangeBG(cfg)
+					return false
+				}
+			case <-mInterval.ClickedCh:
+				event = func() bool {
+					bgchanger.IntervalClicked(cfg)
+					return false
+				}
+			case <-mUndo.ClickedCh:
+				event = func() bool {
+					if err := bgchanger.Undo(cfg); err != nil {
+						log.Printf("Error: %v", err)
+					}
+					return false
+				}
+			case <-mPreview.ClickedCh:
+				event = func() bool {
+					if err := bgchanger.PreviewNext(cfg); err != nil {
+						log.Printf("Error: %v", err)
+					}
+					return false
+				}
+			case <-mQueue.ClickedCh:
+				event = func() bool {
+					bgchanger.QueueCurrent(cfg)
+					return false
+				}
+			case <-mClearQueue.ClickedCh:
+				event = func() bool {
+					bgchanger.ClearQueue(cfg)
+					return false
+				}
+			case <-mBoost.ClickedCh:
+				event = func() bool {
+					if mBoost.Checked() {
+						bgchanger.CancelBoost(cfg)
+					} else {
+						bgchanger.Boost(cfg)
+					}
+					return false
+				}
+			case <-mComfort.ClickedCh:
+				event = func() bool {
+					if err := bgchanger.SetComfort(cfg, !mComfort.Checked()); err != nil {
+						log.Printf("Error: %v", err)
+					}
+					if bgchanger.ComfortEnabled() {
+						mComfort.Check()
+					} else {
+						mComfort.Uncheck()
+					}
+					return false
+				}
+			case <-mFocus.ClickedCh:
+				event = func() bool {
+					if err := bgchanger.SetFocus(cfg, !mFocus.Checked()); err != nil {
+						log.Printf("Error: %v", err)
+					}
+					if bgchanger.FocusEnabled() {
+						mFocus.Check()
+					} else {
+						mFocus.Uncheck()
+					}
+					return false
+				}
+			case <-mBlock.ClickedCh:
+				event = func() bool {
+					if confirmed(&blockConfirmation, mBlock, "block", cfg) {
+						bgchanger.BlockCurrent(cfg)
+					}
+					return false
+				}
+			case name := <-profileClicks:
+				event = func() bool {
+					newCfg, err := bgchanger.SwitchProfile(configFile, name)
+					if err != nil {
+						log.Printf("Error: cannot switch profile: %v", err)
+						return false
+					}
+					bgchanger.ApplyConfig(cfg, newCfg)
+					bgchanger.ChangeBG(cfg)
+					return true
+				}
+			case i := <-blocked.clicks:
+				event = func() bool {
+					if hash := blocked.hash(i); hash != "" {
+						if err := bgchanger.Unblock(cfg, hash); err != nil {
+							log.Printf("Error: %v", err)
+						}
+					}
+					return false
+				}
+			case <-blocked.clear.ClickedCh:
+				event = func() bool {
+					if confirmed(&clearBlocklistConfirmation, blocked.clear, "clear_blocklist", cfg) {
+						if err := bgchanger.ClearBlocklist(cfg); err != nil {
+							log.Printf("Error: %v", err)
+						}
+					}
+					return false
+				}
+			case <-mRescan.ClickedCh:
+				event = func() bool {
+					if _, err := bgchanger.Rescan(cfg); err != nil {
+						log.Printf("Error: rescan failed: %v", err)
+					}
+					return false
+				}
+			case <-mAutostart.ClickedCh:
+				event = func() bool {
+					if mAutostart.Checked() {
+						if err := bgchanger.UninstallAutostart(); err != nil {
+							log.Printf("Error: %v", err)
+						}
+					} else {
+						if err := bgchanger.InstallAutostart(); err != nil {
+							log.Printf("Error: %v", err)
+						}
+					}
+					if bgchanger.IsAutostartInstalled() {
+						mAutostart.Check()
+					} else {
+						mAutostart.Uncheck()
+					}
+					return false
+				}
+			case <-mLike.ClickedCh:
+				event = func() bool {
+					bgchanger.RateCurrent(1)
+					return false
+				}
+			case <-mDislike.ClickedCh:
+				event = func() bool {
+					bgchanger.RateCurrent(-1)
+					return false
 				}
 			}
+			events <- event
 		}
 	}()
+	go bgchanger.RunLoop(cfg, events, nil)
+}
+
+// blocklistMenu is the submenu listing the blocked pictures. Menu items
+// cannot be removed, so the entries are shown in a pool of items that only
+// grows, hiding the unused ones.
+type blocklistMenu struct {
+	parent *systray.MenuItem
+	clear  *systray.MenuItem
+	mu     sync.Mutex
+	items  []*systray.MenuItem
+	hashes []string
+	// clicks receives the index of the clicked entry
+	clicks chan int
+}
+
+func newBlocklistMenu() *blocklistMenu {
+	m := &blocklistMenu{
+		parent: systray.AddMenuItem(bgchanger.Tr("blocked"), bgchanger.Tr("blocked_tooltip")),
+		clicks: make(chan int),
+	}
+	m.clear = m.parent.AddSubMenuItem(bgchanger.Tr("clear_blocklist"), bgchanger.Tr("clear_blocklist_tooltip"))
+	return m
+}
+
+// refresh shows the current entries of the blocklist.
+func (m *blocklistMenu) refresh(cfg *bgchanger.Config) {
+	entries, err := bgchanger.Blocklist(cfg)
+	if err != nil {
+		log.Printf("Error: %v", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.items) < len(entries) {
+		item := m.parent.AddSubMenuItem("", bgchanger.Tr("unblock_tooltip"))
+		m.items = append(m.items, item)
+		go func(i int) {
+			for range item.ClickedCh {
+				m.clicks <- i
+			}
+		}(len(m.items) - 1)
+	}
+	m.hashes = m.hashes[:0]
+	for i, item := range m.items {
+		if i >= len(entries) {
+			item.Hide()
+			continue
+		}
+		title := entries[i].Name
+		if title == "" {
+			title = entries[i].Hash[:12]
+		}
+		item.SetTitle(title)
+		item.Show()
+		m.hashes = append(m.hashes, entries[i].Hash)
+	}
+	if len(entries) == 0 {
+		m.clear.Disable()
+	} else {
+		m.clear.Enable()
+	}
+}
+
+// hash returns the hash of the entry at index i, if still there.
+func (m *blocklistMenu) hash(i int) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i >= len(m.hashes) {
+		return ""
+	}
+	return m.hashes[i]
+}
+
+// confirmed reports whether the destructive action of the item can go ahead.
+// If it must be confirmed first, the item asks for a second click, and goes
+// back to its title labelKey when that does not come in time.
+func confirmed(c *bgchanger.Confirmation, item *systray.MenuItem, labelKey string, cfg *bgchanger.Config) bool {
+	if !bgchanger.NeedsConfirmation(cfg) || c.Confirm(time.Now()) {
+		item.SetTitle(bgchanger.Tr(labelKey))
+		return true
+	}
+	item.SetTitle(bgchanger.Tr("confirm"))
+	time.AfterFunc(c.Window(), func() { item.SetTitle(bgchanger.Tr(labelKey)) })
+	return false
+}
+
+// updateIntervalItem shows the interval, or why the background is not
+// changing. The item is only enabled with interval_click_action, and hidden
+// when there is neither an interval nor an action.
+func updateIntervalItem(item *systray.MenuItem, cfg *bgchanger.Config) {
+	interval := bgchanger.EffectiveInterval(cfg)
+	if cfg.IntervalClickAction == "" {
+		item.Disable()
+	} else {
+		item.Enable()
+	}
+	if interval == 0 {
+		if cfg.IntervalClickAction == "" {
+			item.Hide()
+			return
+		}
+		item.SetTitle(bgchanger.Tr("interval_off"))
+	} else if bgchanger.Paused() {
+		item.SetTitle(bgchanger.Tr("interval_paused"))
+	} else if until, held := bgchanger.Held(); held {
+		item.SetTitle(fmt.Sprintf(bgchanger.Tr("interval_held"), until.Format("15:04")))
+	} else if until, boosted := bgchanger.Boosted(); boosted {
+		item.SetTitle(fmt.Sprintf(bgchanger.Tr("interval_boosted"), interval, until.Format("15:04")))
+	} else {
+		item.SetTitle(fmt.Sprintf(bgchanger.Tr("interval"), interval))
+	}
+	item.Show()
+}
+
+// updateProfileItems checks the item of the active profile, and hides the
+// items of the profiles that are not in the config anymore. Profiles added
+// after startup only get an item after a restart.
+func updateProfileItems(items map[string]*systray.MenuItem, cfg *bgchanger.Config) {
+	for name, item := range items {
+		if _, ok := cfg.Profiles[name]; !ok {
+			item.Hide()
+			continue
+		}
+		item.Show()
+		if name == cfg.Profile {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
+
+// updateChooseDirItem disables the item choosing the pictures folder when the
+// pictures come from sources, which don't use pictures_dir.
+func updateChooseDirItem(item *systray.MenuItem, cfg *bgchanger.Config) {
+	if len(cfg.Sources) > 0 {
+		item.Disable()
+	} else {
+		item.Enable()
+	}
+}
+
+// updateQueueItem shows the number of queued pictures in the item clearing
+// the queue, which is disabled when the queue is empty.
+func updateQueueItem(item *systray.MenuItem, length int) {
+	item.SetTitle(fmt.Sprintf(bgchanger.Tr("queue_clear"), length))
+	if length > 0 {
+		item.Enable()
+	} else {
+		item.Disable()
+	}
 }
 
-func onExit() {
-	// clean up here
+// shutdown cleans up when quitting, from the tray or from headless mode.
+func shutdown(cfg *bgchanger.Config) {
+	bgchanger.StopVideo()
+	bgchanger.SaveDisplayTime()
+	bgchanger.RemoveCurrentSymlink(cfg)
+	bgchanger.ReleaseLock(instanceLock)
 }